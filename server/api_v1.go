@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// apiV1 serves /api/v1 REST resources of the world request is scoped to, see static/api/openapi.v1.json
+type apiV1 struct{}
+
+// apiV1Error is the body of every failed /api/v1 request: {"error": {"code": ..., "message": ...}}
+type apiV1Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// /api/v1 error codes
+const (
+	apiV1BadRequest   = "bad_request"
+	apiV1NotFound     = "not_found"
+	apiV1PeerFailed   = "peer_failed"
+	apiV1NoSuchRoute  = "no_such_route"
+	apiV1Internal     = "internal_error"
+	apiV1Conflict     = "conflict"
+	apiV1Unauthorized = "unauthorized"
+	apiV1Forbidden    = "forbidden"
+)
+
+// apiV1MaxLayoutPeers limits peers created by one layout request
+const apiV1MaxLayoutPeers = 1000
+
+// apiV1Link is a directed link: From sees To as its peer
+type apiV1Link struct {
+	From string
+	To   string
+}
+
+func newAPIv1() *apiV1 {
+	return &apiV1{}
+}
+
+// register adds /api/v1 routes to r, which scopes them to a world
+func (api *apiV1) register(r *gin.RouterGroup, au *authenticator) {
+	g := r.Group("/api/v1")
+	g.GET("/openapi.json", func(c *gin.Context) {
+		c.File("./static/api/openapi.v1.json")
+	})
+	g.GET("/actors", au.require(roleViewer), api.listActors)
+	g.POST("/actors", au.require(roleOperator), api.createActor)
+	g.GET("/actors/:id", au.require(roleViewer), api.getActor)
+	g.PATCH("/actors/:id", au.require(roleOperator), api.patchActor)
+	g.DELETE("/actors/:id", au.require(roleOperator), api.deleteActor)
+	g.GET("/actors/:id/neighbours", au.require(roleViewer), api.getNeighbours)
+	g.GET("/links", au.require(roleViewer), api.listLinks)
+	g.POST("/layouts", au.require(roleOperator), api.createLayout)
+	g.GET("/params", au.require(roleViewer), api.getParams)
+	g.PATCH("/params", au.require(roleAdmin), api.patchParams)
+}
+
+// apiV1NoRoute answers unknown /api/v1 routes with JSON error
+func apiV1NoRoute(c *gin.Context) {
+	if strings.Contains(c.Request.URL.Path, "/api/v1/") {
+		apiV1Fail(c, http.StatusNotFound, apiV1NoSuchRoute, "no such route")
+	}
+}
+
+func apiV1Fail(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": apiV1Error{code, message}})
+}
+
+// parseBBox parses "minLat,minLon,maxLat,maxLon"
+func parseBBox(s string) ([4]float64, error) {
+	ret := [4]float64{}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return ret, fmt.Errorf("bbox must be minLat,minLon,maxLat,maxLon")
+	}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return ret, fmt.Errorf("bad bbox value %q", p)
+		}
+		ret[i] = v
+	}
+	if ret[0] > ret[2] || ret[1] > ret[3] {
+		return ret, fmt.Errorf("bbox minimum is greater than maximum")
+	}
+	return ret, nil
+}
+
+func sortedActors(actors map[string]meshsim.ActorInfo) []meshsim.ActorInfo {
+	ret := []meshsim.ActorInfo{}
+	for _, a := range actors {
+		ret = append(ret, a)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret
+}
+
+func (api *apiV1) listActors(c *gin.Context) {
+	w := worldOf(c)
+	actors := sortedActors(w.sim.GetOverview().Actors)
+	if b, ok := c.GetQuery("bbox"); ok {
+		bbox, err := parseBBox(b)
+		if err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+			return
+		}
+		inside := []meshsim.ActorInfo{}
+		for _, a := range actors {
+			if a.Coord[0] >= bbox[0] && a.Coord[1] >= bbox[1] && a.Coord[0] <= bbox[2] && a.Coord[1] <= bbox[3] {
+				inside = append(inside, a)
+			}
+		}
+		actors = inside
+	}
+	c.JSON(http.StatusOK, gin.H{"Actors": actors})
+}
+
+func (api *apiV1) createActor(c *gin.Context) {
+	w := worldOf(c)
+	type createRequest struct {
+		Coord  *[2]float64
+		Exact  bool
+		Type   string
+		Script string
+		Config json.RawMessage
+		Meta   map[string]interface{}
+	}
+	req := &createRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	coord := w.sim.Params().DefaultCoord
+	if req.Coord != nil {
+		coord = *req.Coord
+	}
+	if req.Meta == nil {
+		req.Meta = map[string]interface{}{}
+	}
+	id, err := w.createPeer(peerSpec{coord, req.Exact, req.Meta, req.Type, req.Script, req.Config, ownerOf(c)})
+	if err != nil {
+		status, code := errorStatus(err, http.StatusUnprocessableEntity, apiV1PeerFailed)
+		apiV1Fail(c, status, code, err.Error())
+		return
+	}
+	info, ok := w.sim.GetActor(id)
+	if !ok {
+		apiV1Fail(c, http.StatusGone, apiV1NotFound, "actor left simulation right after creation")
+		return
+	}
+	c.Header("Location", "/api/v1/actors/"+string(id))
+	c.JSON(http.StatusCreated, info)
+}
+
+func (api *apiV1) getActor(c *gin.Context) {
+	w := worldOf(c)
+	info, ok := w.sim.GetActor(meshpeer.NetworkID(c.Param("id")))
+	if !ok {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "actor not found")
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+func (api *apiV1) patchActor(c *gin.Context) {
+	w := worldOf(c)
+	type velocity struct {
+		Speed   float64
+		Heading float64
+	}
+	type patchRequest struct {
+		// Coord teleports actor, Anchor sets the point it jitters around
+		Coord    *[2]float64
+		Anchor   *[2]float64
+		Velocity *velocity
+		Frozen   *bool
+		// Meta is merged into actor meta, null values remove keys
+		Meta map[string]interface{}
+	}
+	id := meshpeer.NetworkID(c.Param("id"))
+	req := &patchRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	info, ok := w.sim.GetActor(id)
+	if !ok {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "actor not found")
+		return
+	}
+	if !authorizeActor(c, w, id) {
+		return
+	}
+	if req.Velocity != nil && (req.Velocity.Speed < 0 || math.IsNaN(req.Velocity.Speed) || math.IsInf(req.Velocity.Speed, 0)) {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, "speed must be a non-negative number")
+		return
+	}
+	for _, coord := range []*[2]float64{req.Coord, req.Anchor} {
+		if coord == nil {
+			continue
+		}
+		if err := validateCoord(*coord); err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+			return
+		}
+	}
+	if req.Meta != nil {
+		// meta is checked as it will be after merge, so repeated patches cannot grow it past the limit
+		merged := map[string]interface{}{}
+		for k, v := range info.Meta {
+			merged[k] = v
+		}
+		for k, v := range req.Meta {
+			if v == nil {
+				delete(merged, k)
+			} else {
+				merged[k] = v
+			}
+		}
+		if err := w.validateMeta(merged); err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+			return
+		}
+	}
+	// the actor may leave simulation in between, so every step reports not found
+	steps := []func() error{}
+	if req.Frozen != nil {
+		steps = append(steps, func() error { return w.sim.FreezeActor(id, *req.Frozen) })
+	}
+	if req.Anchor != nil {
+		steps = append(steps, func() error { return w.sim.SetActorAnchor(id, *req.Anchor) })
+	}
+	if req.Coord != nil {
+		steps = append(steps, func() error { return w.sim.TeleportActor(id, *req.Coord) })
+	}
+	if req.Velocity != nil {
+		steps = append(steps, func() error { return w.sim.SetActorVelocity(id, req.Velocity.Speed, req.Velocity.Heading) })
+	}
+	if req.Meta != nil {
+		steps = append(steps, func() error { return w.sim.UpdateActorMeta(id, req.Meta) })
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			apiV1Fail(c, http.StatusNotFound, apiV1NotFound, err.Error())
+			return
+		}
+	}
+	api.getActor(c)
+}
+
+// deleteActor removes any actor user may control: peers created through the API are stopped
+// together with their runtime, remote ones get disconnected
+func (api *apiV1) deleteActor(c *gin.Context) {
+	w := worldOf(c)
+	id := meshpeer.NetworkID(c.Param("id"))
+	if _, ok := w.sim.GetActor(id); !ok {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "actor not found")
+		return
+	}
+	if !authorizeActor(c, w, id) {
+		return
+	}
+	if err := w.deletePeer(id); err != nil {
+		w.sim.RemoveActor(id)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (api *apiV1) getNeighbours(c *gin.Context) {
+	w := worldOf(c)
+	overview := w.sim.GetOverview()
+	a, ok := overview.Actors[c.Param("id")]
+	if !ok {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "actor not found")
+		return
+	}
+	neighbours := []meshsim.ActorInfo{}
+	for _, p := range a.Peers {
+		if n, ok := overview.Actors[p]; ok {
+			neighbours = append(neighbours, n)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"Actors": neighbours})
+}
+
+func (api *apiV1) listLinks(c *gin.Context) {
+	w := worldOf(c)
+	links := []apiV1Link{}
+	for _, a := range sortedActors(w.sim.GetOverview().Actors) {
+		for _, p := range a.Peers {
+			links = append(links, apiV1Link{a.ID, p})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"Links": links})
+}
+
+func (api *apiV1) getParams(c *gin.Context) {
+	w := worldOf(c)
+	c.JSON(http.StatusOK, w.sim.Params())
+}
+
+// patchParams changes simulation parameters of running world, omitted ones are kept.
+// Unknown fields are rejected, so a typo does not pass silently
+func (api *apiV1) patchParams(c *gin.Context) {
+	w := worldOf(c)
+	params := w.sim.Params()
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&params); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	if err := validateParams(params); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	if err := w.sim.SetParams(params); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	w.logger.Info("Simulation params changed", "user", userOf(c).Name, "params", params)
+	c.JSON(http.StatusOK, w.sim.Params())
+}
+
+// createLayout creates peers of the same kind placed exactly along a line, on a grid, around a ring
+// or randomly inside a polygon. Either all of them are created or none
+func (api *apiV1) createLayout(c *gin.Context) {
+	w := worldOf(c)
+	type layoutRequest struct {
+		// Shape is one of line, grid, ring, polygon
+		Shape string
+		// Origin is line start, grid north-west corner or ring center
+		Origin  *[2]float64
+		Count   int
+		Rows    int
+		Cols    int
+		Spacing float64
+		Heading float64
+		Polygon [][2]float64
+		Type    string
+		Script  string
+		Config  json.RawMessage
+		Meta    map[string]interface{}
+	}
+	req := &layoutRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	origin := w.sim.Params().DefaultCoord
+	if req.Origin != nil {
+		origin = *req.Origin
+	}
+	if req.Shape == "grid" {
+		// rows and cols are bounded before multiplying, so the product cannot overflow
+		if req.Rows < 1 || req.Cols < 1 || req.Rows > apiV1MaxLayoutPeers || req.Cols > apiV1MaxLayoutPeers || req.Rows > apiV1MaxLayoutPeers/req.Cols {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, fmt.Sprintf("grid must have at least 1 row and col and at most %v peers", apiV1MaxLayoutPeers))
+			return
+		}
+		req.Count = req.Rows * req.Cols
+	}
+	if req.Count <= 0 || req.Count > apiV1MaxLayoutPeers {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, fmt.Sprintf("peer count must be from 1 to %v", apiV1MaxLayoutPeers))
+		return
+	}
+	if req.Spacing <= 0 {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, "spacing must be positive")
+		return
+	}
+	for _, coord := range append([][2]float64{origin}, req.Polygon...) {
+		if err := validateCoord(coord); err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+			return
+		}
+	}
+	if err := w.checkCapacity(ownerOf(c), req.Count); err != nil {
+		status, code := errorStatus(err, http.StatusBadRequest, apiV1BadRequest)
+		apiV1Fail(c, status, code, err.Error())
+		return
+	}
+
+	var coords [][2]float64
+	switch req.Shape {
+	case "line":
+		coords = meshsim.LayoutLine(origin, req.Count, req.Spacing, req.Heading)
+	case "grid":
+		coords = meshsim.LayoutGrid(origin, req.Rows, req.Cols, req.Spacing)
+	case "ring":
+		coords = meshsim.LayoutRing(origin, req.Count, req.Spacing)
+	case "polygon":
+		var err error
+		coords, err = meshsim.LayoutPolygon(req.Polygon, req.Count, req.Spacing, rand.New(rand.NewSource(rand.Int63())))
+		if err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+			return
+		}
+	default:
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, fmt.Sprintf("unknown shape %q", req.Shape))
+		return
+	}
+
+	ids := []meshpeer.NetworkID{}
+	for _, coord := range coords {
+		meta := map[string]interface{}{}
+		for k, v := range req.Meta {
+			meta[k] = v
+		}
+		id, err := w.createPeer(peerSpec{coord, true, meta, req.Type, req.Script, req.Config, ownerOf(c)})
+		if err != nil {
+			for _, id := range ids {
+				w.deletePeer(id)
+			}
+			status, code := errorStatus(err, http.StatusUnprocessableEntity, apiV1PeerFailed)
+			apiV1Fail(c, status, code, err.Error())
+			return
+		}
+		ids = append(ids, id)
+	}
+	actors := []meshsim.ActorInfo{}
+	for _, id := range ids {
+		if info, ok := w.sim.GetActor(id); ok {
+			actors = append(actors, info)
+		}
+	}
+	c.JSON(http.StatusCreated, gin.H{"Actors": actors})
+}
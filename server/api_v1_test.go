@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// do sends request with JSON body to test server and decodes JSON response into out, if set
+func (ts *testServer) do(method string, path string, body interface{}, out interface{}) int {
+	return ts.doAs("", method, path, body, out)
+}
+
+// doAs is do with API token, empty token makes anonymous request
+func (ts *testServer) doAs(token string, method string, path string, body interface{}, out interface{}) int {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			ts.t.Fatalf("marshal request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, ts.http.URL+path, bytes.NewReader(b))
+	if err != nil {
+		ts.t.Fatalf("new request: %v", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		ts.t.Fatalf("%v %v: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			ts.t.Fatalf("decode %v %v response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestCreateLayoutValidation(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+
+	tests := []struct {
+		name       string
+		req        map[string]interface{}
+		wantStatus int
+		wantPeers  int
+	}{
+		{"line", map[string]interface{}{"Shape": "line", "Count": 3, "Spacing": 10}, http.StatusCreated, 3},
+		{"grid", map[string]interface{}{"Shape": "grid", "Rows": 2, "Cols": 3, "Spacing": 10}, http.StatusCreated, 6},
+		{"ring", map[string]interface{}{"Shape": "ring", "Count": 4, "Spacing": 10}, http.StatusCreated, 4},
+		{"grid rows times cols overflows", map[string]interface{}{"Shape": "grid", "Rows": 3, "Cols": 6148914691236517206, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"grid without rows", map[string]interface{}{"Shape": "grid", "Rows": 0, "Cols": 3, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"grid with negative cols", map[string]interface{}{"Shape": "grid", "Rows": -2, "Cols": -3, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"grid over peer limit", map[string]interface{}{"Shape": "grid", "Rows": 40, "Cols": 30, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"grid with too many cols", map[string]interface{}{"Shape": "grid", "Rows": 1, "Cols": 1001, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"no peers", map[string]interface{}{"Shape": "line", "Count": 0, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"over peer limit", map[string]interface{}{"Shape": "line", "Count": 1001, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"zero spacing", map[string]interface{}{"Shape": "line", "Count": 3, "Spacing": 0}, http.StatusBadRequest, 0},
+		{"bad origin", map[string]interface{}{"Shape": "line", "Count": 3, "Spacing": 10, "Origin": []float64{91, 0}}, http.StatusBadRequest, 0},
+		{"unknown shape", map[string]interface{}{"Shape": "star", "Count": 3, "Spacing": 10}, http.StatusBadRequest, 0},
+		{"over per user limit", map[string]interface{}{"Shape": "line", "Count": 101, "Spacing": 10}, http.StatusTooManyRequests, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(ts.world.sim.GetOverview().Actors)
+			resp := struct{ Actors []interface{} }{}
+			if status := ts.do("POST", "/api/v1/layouts", tt.req, &resp); status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", status, tt.wantStatus)
+			}
+			if len(resp.Actors) != tt.wantPeers {
+				t.Errorf("%v actors created, want %v", len(resp.Actors), tt.wantPeers)
+			}
+			if added := len(ts.world.sim.GetOverview().Actors) - before; added != tt.wantPeers {
+				t.Errorf("world has %v more actors, want %v", added, tt.wantPeers)
+			}
+		})
+	}
+}
+
+// apiV1ErrorBody is body of failed /api/v1 request
+type apiV1ErrorBody struct {
+	Error apiV1Error `json:"error"`
+}
+
+func TestActorsCRUD(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+	create := func(coord [2]float64) string {
+		a := meshsim.ActorInfo{}
+		if status := ts.do("POST", "/api/v1/actors", map[string]interface{}{"Coord": coord, "Exact": true, "Meta": map[string]string{"label": "x"}}, &a); status != http.StatusCreated {
+			t.Fatalf("create status = %v", status)
+		}
+		return a.ID
+	}
+	near, alsoNear, far := create([2]float64{53.9, 27.5}), create([2]float64{53.9, 27.5}), create([2]float64{10, 10})
+	if _, err := ts.world.sim.Step(1); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		// wantIDs lists actors of Actors response, wantCode is error code of failed request
+		wantIDs  []string
+		wantCode string
+	}{
+		{"list", "GET", "/api/v1/actors", http.StatusOK, sortedIDs(near, alsoNear, far), ""},
+		{"list in bbox", "GET", "/api/v1/actors?bbox=50,20,60,30", http.StatusOK, sortedIDs(near, alsoNear), ""},
+		{"list in empty bbox", "GET", "/api/v1/actors?bbox=-10,-10,-5,-5", http.StatusOK, []string{}, ""},
+		{"bad bbox", "GET", "/api/v1/actors?bbox=1,2,3", http.StatusBadRequest, nil, apiV1BadRequest},
+		{"inverted bbox", "GET", "/api/v1/actors?bbox=60,30,50,20", http.StatusBadRequest, nil, apiV1BadRequest},
+		{"neighbours", "GET", "/api/v1/actors/" + near + "/neighbours", http.StatusOK, []string{alsoNear}, ""},
+		{"neighbours of lonely actor", "GET", "/api/v1/actors/" + far + "/neighbours", http.StatusOK, []string{}, ""},
+		{"neighbours of unknown actor", "GET", "/api/v1/actors/nobody/neighbours", http.StatusNotFound, nil, apiV1NotFound},
+		{"get", "GET", "/api/v1/actors/" + far, http.StatusOK, nil, ""},
+		{"get unknown", "GET", "/api/v1/actors/nobody", http.StatusNotFound, nil, apiV1NotFound},
+		{"delete", "DELETE", "/api/v1/actors/" + far, http.StatusNoContent, nil, ""},
+		{"get deleted", "GET", "/api/v1/actors/" + far, http.StatusNotFound, nil, apiV1NotFound},
+		{"delete deleted", "DELETE", "/api/v1/actors/" + far, http.StatusNotFound, nil, apiV1NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := json.RawMessage{}
+			status := ts.do(tt.method, tt.path, nil, nil)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", status, tt.wantStatus)
+			}
+			if status == http.StatusNoContent {
+				return
+			}
+			// the request is idempotent, so it is repeated to read the body
+			ts.do(tt.method, tt.path, nil, &body)
+			if tt.wantCode != "" {
+				e := apiV1ErrorBody{}
+				if err := json.Unmarshal(body, &e); err != nil || e.Error.Code != tt.wantCode {
+					t.Errorf("error = %s, want code %v", body, tt.wantCode)
+				}
+				return
+			}
+			if tt.wantIDs != nil {
+				list := struct{ Actors []meshsim.ActorInfo }{}
+				json.Unmarshal(body, &list)
+				ids := []string{}
+				for _, a := range list.Actors {
+					ids = append(ids, a.ID)
+				}
+				if !reflect.DeepEqual(ids, tt.wantIDs) {
+					t.Errorf("actors = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+
+	links := struct{ Links []apiV1Link }{}
+	if status := ts.do("GET", "/api/v1/links", nil, &links); status != http.StatusOK {
+		t.Fatalf("links status = %v", status)
+	}
+	want := []apiV1Link{{near, alsoNear}, {alsoNear, near}}
+	sort.Slice(want, func(i, j int) bool { return want[i].From < want[j].From })
+	if !reflect.DeepEqual(links.Links, want) {
+		t.Errorf("links = %v, want %v", links.Links, want)
+	}
+}
+
+func sortedIDs(ids ...string) []string {
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCreateActorValidation(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{"default coord", map[string]interface{}{}, http.StatusCreated, ""},
+		{"not an object", []int{1}, http.StatusBadRequest, apiV1BadRequest},
+		{"bad coord", map[string]interface{}{"Coord": []float64{0, 0}}, http.StatusBadRequest, apiV1BadRequest},
+		{"unknown type", map[string]interface{}{"Type": "NoSuchPeer"}, http.StatusUnprocessableEntity, apiV1PeerFailed},
+		{"broken script", map[string]interface{}{"Script": "function ("}, http.StatusUnprocessableEntity, apiV1PeerFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := json.RawMessage{}
+			if status := ts.do("POST", "/api/v1/actors", tt.body, &body); status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v: %s", status, tt.wantStatus, body)
+			}
+			e := apiV1ErrorBody{}
+			json.Unmarshal(body, &e)
+			if e.Error.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", e.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestPatchActor(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		// check inspects patched actor
+		check func(t *testing.T, a meshsim.ActorInfo)
+	}{
+		{
+			name:       "teleport",
+			body:       map[string]interface{}{"Coord": [2]float64{10, 20}},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, a meshsim.ActorInfo) {
+				if a.Coord != [2]float64{10, 20} {
+					t.Errorf("Coord = %v", a.Coord)
+				}
+			},
+		},
+		{
+			name:       "anchor",
+			body:       map[string]interface{}{"Anchor": [2]float64{-5, 7}},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, a meshsim.ActorInfo) {
+				if a.Motion.Anchor != [2]float64{-5, 7} {
+					t.Errorf("Anchor = %v", a.Motion.Anchor)
+				}
+			},
+		},
+		{
+			name:       "velocity and freeze",
+			body:       map[string]interface{}{"Velocity": map[string]float64{"Speed": 3, "Heading": 450}, "Frozen": true},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, a meshsim.ActorInfo) {
+				if want := (meshsim.ActorMotion{Anchor: a.Motion.Anchor, Speed: 3, Heading: 90, Frozen: true}); a.Motion != want {
+					t.Errorf("Motion = %+v, want %+v", a.Motion, want)
+				}
+			},
+		},
+		{
+			name:       "meta merge",
+			body:       map[string]interface{}{"Meta": map[string]interface{}{"label": nil, "color": "red"}},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, a meshsim.ActorInfo) {
+				if want := map[string]interface{}{"kind": "tower", "color": "red"}; !reflect.DeepEqual(a.Meta, want) {
+					t.Errorf("Meta = %v, want %v", a.Meta, want)
+				}
+			},
+		},
+		{name: "negative speed", body: map[string]interface{}{"Velocity": map[string]float64{"Speed": -1}}, wantStatus: http.StatusBadRequest},
+		{name: "bad coord", body: map[string]interface{}{"Coord": [2]float64{91, 0}}, wantStatus: http.StatusBadRequest},
+		{name: "bad anchor", body: map[string]interface{}{"Anchor": [2]float64{0, 181}}, wantStatus: http.StatusBadRequest},
+		{name: "not an object", body: "frozen", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := meshsim.ActorInfo{}
+			ts.do("POST", "/api/v1/actors", map[string]interface{}{"Meta": map[string]string{"label": "x", "kind": "tower"}}, &created)
+			before, _ := ts.world.sim.GetActor(meshpeer.NetworkID(created.ID))
+			patched := meshsim.ActorInfo{}
+			if status := ts.do("PATCH", "/api/v1/actors/"+created.ID, tt.body, &patched); status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", status, tt.wantStatus)
+			}
+			if tt.check == nil {
+				// failed patch leaves actor as it was
+				after, _ := ts.world.sim.GetActor(meshpeer.NetworkID(created.ID))
+				if !reflect.DeepEqual(after.Motion, before.Motion) || !reflect.DeepEqual(after.Meta, before.Meta) {
+					t.Errorf("actor changed: %+v, was %+v", after, before)
+				}
+				return
+			}
+			tt.check(t, patched)
+		})
+	}
+
+	if status := ts.do("PATCH", "/api/v1/actors/nobody", map[string]interface{}{"Frozen": true}, nil); status != http.StatusNotFound {
+		t.Errorf("patch unknown status = %v, want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestPatchParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		// want changes default params to what the world should have afterwards
+		want func(p *meshsim.Params)
+	}{
+		{"Dt", map[string]interface{}{"Dt": 0.1}, http.StatusOK, func(p *meshsim.Params) { p.Dt = 0.1 }},
+		{"several", map[string]interface{}{"RadioRange": 120, "MaxPeers": 0}, http.StatusOK, func(p *meshsim.Params) {
+			p.RadioRange = 120
+			p.MaxPeers = 0
+		}},
+		{"nothing", map[string]interface{}{}, http.StatusOK, func(p *meshsim.Params) {}},
+		{"unknown field", map[string]interface{}{"Dt": 0.1, "Dtt": 1}, http.StatusBadRequest, func(p *meshsim.Params) {}},
+		{"zero Dt", map[string]interface{}{"Dt": 0}, http.StatusBadRequest, func(p *meshsim.Params) {}},
+		{"DefaultCoord off Earth", map[string]interface{}{"DefaultCoord": [2]float64{91, 0}}, http.StatusBadRequest, func(p *meshsim.Params) {}},
+		{"not an object", []int{1}, http.StatusBadRequest, func(p *meshsim.Params) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			want := ts.world.sim.Params()
+			tt.want(&want)
+			if status := ts.do("PATCH", "/api/v1/params", tt.body, nil); status != tt.wantStatus {
+				t.Fatalf("status = %v, want %v", status, tt.wantStatus)
+			}
+			got := meshsim.Params{}
+			if status := ts.do("GET", "/api/v1/params", nil, &got); status != http.StatusOK {
+				t.Fatalf("get status = %v", status)
+			}
+			if got != want {
+				t.Errorf("params = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"mesh-simulator/meshpeer"
+)
+
+// role is what user is allowed to do, every role may do everything the lower ones may
+type role int
+
+const (
+	// roleNone may not use the API
+	roleNone role = iota
+	// roleViewer reads simulation state
+	roleViewer
+	// roleOperator creates peers and controls the ones it created
+	roleOperator
+	// roleAdmin controls every peer, the simulation itself, worlds and snapshots
+	roleAdmin
+)
+
+var roleNames = []string{"none", "viewer", "operator", "admin"}
+
+func (r role) String() string {
+	return roleNames[r]
+}
+
+// MarshalText makes role a string in JSON
+func (r role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func parseRole(s string) (role, error) {
+	for i, name := range roleNames {
+		if s == name {
+			return role(i), nil
+		}
+	}
+	return roleNone, fmt.Errorf("unknown role %q, expected one of %v", s, strings.Join(roleNames, ", "))
+}
+
+// userContextKey is gin context key of the user request is made by
+const userContextKey = "user"
+
+// authUser is who request is made by, Name is empty for anonymous requests
+type authUser struct {
+	Name string
+	Role role
+}
+
+// mayControl tells whether user requesting from addr may change or remove actor with given
+// owner: admins may do it with any actor, operators with the ones they created. Anonymous
+// operators are told apart by address, see actorOwner
+func (u authUser) mayControl(owner string, addr string) bool {
+	return u.Role >= roleAdmin || (u.Role >= roleOperator && actorOwner(u, addr) == owner)
+}
+
+// authToken is AuthTokensFile entry, the file maps tokens to them:
+// {"<token>": {"User": "alice", "Role": "operator"}}
+type authToken struct {
+	User string
+	Role string
+}
+
+// authenticator identifies users by API tokens. Without tokens auth is disabled and every
+// request is made by anonymous admin, as it was before auth was introduced
+type authenticator struct {
+	tokens    map[string]authUser
+	anonymous role
+}
+
+func newAuthenticator(tokensFile string, anonymousRole string) (*authenticator, error) {
+	au := &authenticator{
+		anonymous: roleAdmin,
+	}
+	if tokensFile == "" {
+		return au, nil
+	}
+	b, err := ioutil.ReadFile(tokensFile)
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]authToken{}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("bad tokens file %v: %v", tokensFile, err)
+	}
+	au.tokens = map[string]authUser{}
+	for token, e := range entries {
+		if token == "" || e.User == "" {
+			return nil, fmt.Errorf("tokens file %v has empty token or user", tokensFile)
+		}
+		if strings.HasPrefix(e.User, anonymousOwner) {
+			return nil, fmt.Errorf("tokens file %v has user %v, %v names are reserved for anonymous users", tokensFile, e.User, anonymousOwner)
+		}
+		r, err := parseRole(e.Role)
+		if err != nil {
+			return nil, fmt.Errorf("token of %v: %v", e.User, err)
+		}
+		au.tokens[token] = authUser{e.User, r}
+	}
+	if au.anonymous, err = parseRole(anonymousRole); err != nil {
+		return nil, err
+	}
+	return au, nil
+}
+
+func (au *authenticator) enabled() bool {
+	return au.tokens != nil
+}
+
+// user returns user of the token, empty token means anonymous user
+func (au *authenticator) user(token string) (authUser, bool) {
+	if !au.enabled() || token == "" {
+		return authUser{Role: au.anonymous}, true
+	}
+	u, ok := au.tokens[token]
+	return u, ok
+}
+
+// requestToken returns token of Authorization: Bearer header or token query parameter,
+// the latter is for browsers, which cannot set headers of WebSocket requests
+func requestToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// identify stores the user request is made by, requests with unknown token are rejected
+func (au *authenticator) identify(c *gin.Context) {
+	u, ok := au.user(requestToken(c.Request))
+	if !ok {
+		failRequest(c, http.StatusUnauthorized, apiV1Unauthorized, "invalid token")
+		return
+	}
+	c.Set(userContextKey, u)
+}
+
+// require rejects requests of users below given role
+func (au *authenticator) require(min role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := userOf(c)
+		if u.Role >= min {
+			return
+		}
+		if u.Name == "" {
+			failRequest(c, http.StatusUnauthorized, apiV1Unauthorized, "token required")
+			return
+		}
+		failRequest(c, http.StatusForbidden, apiV1Forbidden, fmt.Sprintf("%v role required", min))
+	}
+}
+
+// userOf returns the user request is made by
+func userOf(c *gin.Context) authUser {
+	return c.MustGet(userContextKey).(authUser)
+}
+
+// ownerOf returns owner of actors created by request, see actorOwner. Forwarding headers
+// are ignored as clients may set them to anything
+func ownerOf(c *gin.Context) string {
+	return actorOwner(userOf(c), c.Request.RemoteAddr)
+}
+
+// authorizeActor fails request unless its user may control the actor. Unknown actors are let
+// through, so handlers report them the usual way
+func authorizeActor(c *gin.Context, w *world, id meshpeer.NetworkID) bool {
+	info, ok := w.sim.GetActor(id)
+	if !ok || userOf(c).mayControl(info.Owner, c.Request.RemoteAddr) {
+		return true
+	}
+	failRequest(c, http.StatusForbidden, apiV1Forbidden, "actor belongs to another user")
+	return false
+}
+
+// failRequest aborts request with error in the format of API it belongs to:
+// /api/v1 error object or {ok, error} of the older routes
+func failRequest(c *gin.Context, status int, code string, message string) {
+	if strings.Contains(c.Request.URL.Path, "/api/v1/") {
+		apiV1Fail(c, status, code, message)
+	} else {
+		c.AbortWithStatusJSON(status, gin.H{"ok": false, "error": message})
+	}
+}
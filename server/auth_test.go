@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestMayControl(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  authUser
+		addr  string
+		owner string
+		want  bool
+	}{
+		{"admin any actor", authUser{"root", roleAdmin}, "10.0.0.1:5000", "alice", true},
+		{"operator own actor", authUser{"alice", roleOperator}, "10.0.0.1:5000", "alice", true},
+		{"operator foreign actor", authUser{"bob", roleOperator}, "10.0.0.1:5000", "alice", false},
+		{"operator anonymous actor of same address", authUser{"alice", roleOperator}, "10.0.0.1:5000", "anonymous@10.0.0.1", false},
+		{"anonymous operator own actor", authUser{Role: roleOperator}, "10.0.0.1:5000", "anonymous@10.0.0.1", true},
+		{"anonymous operator own actor from another port", authUser{Role: roleOperator}, "10.0.0.1:6000", "anonymous@10.0.0.1", true},
+		{"anonymous operator actor of another address", authUser{Role: roleOperator}, "10.0.0.2:5000", "anonymous@10.0.0.1", false},
+		{"anonymous operator named actor", authUser{Role: roleOperator}, "10.0.0.1:5000", "alice", false},
+		{"viewer own actor", authUser{"alice", roleViewer}, "10.0.0.1:5000", "alice", false},
+		{"anonymous viewer", authUser{Role: roleViewer}, "10.0.0.1:5000", "anonymous@10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.mayControl(tt.owner, tt.addr); got != tt.want {
+				t.Errorf("mayControl(%q, %q) = %v, want %v", tt.owner, tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestAuthenticator returns authenticator with the given tokens file contents
+func newTestAuthenticator(t *testing.T, tokens string, anonymousRole string) (*authenticator, error) {
+	f, err := ioutil.TempFile("", "tokens*.json")
+	if err != nil {
+		t.Fatalf("tokens file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(tokens); err != nil {
+		t.Fatalf("tokens file: %v", err)
+	}
+	f.Close()
+	return newAuthenticator(f.Name(), anonymousRole)
+}
+
+func TestAnonymousOperatorControlsOwnPeers(t *testing.T) {
+	au, err := newTestAuthenticator(t, `{"t-alice": {"User": "alice", "Role": "operator"}}`, "operator")
+	if err != nil {
+		t.Fatalf("authenticator: %v", err)
+	}
+	ts := newAuthTestServer(t, au)
+	create := func(token string) string {
+		resp := struct {
+			OK bool
+			ID string
+		}{}
+		if code := ts.doAs(token, "POST", "/create_peer", map[string]interface{}{}, &resp); code != http.StatusOK || !resp.OK {
+			t.Fatalf("create_peer as %q = %v", token, code)
+		}
+		return resp.ID
+	}
+	anonymousPeer, alicePeer := create(""), create("t-alice")
+
+	tests := []struct {
+		name  string
+		token string
+		id    string
+		want  int
+	}{
+		{"anonymous deletes peer of alice", "", alicePeer, http.StatusForbidden},
+		{"alice deletes anonymous peer", "t-alice", anonymousPeer, http.StatusForbidden},
+		{"anonymous deletes own peer", "", anonymousPeer, http.StatusOK},
+		{"alice deletes own peer", "t-alice", alicePeer, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ts.doAs(tt.token, "POST", "/delete_peer", map[string]string{"ID": tt.id}, nil); code != tt.want {
+				t.Errorf("delete_peer = %v, want %v", code, tt.want)
+			}
+		})
+	}
+	if n := len(ts.world.sim.GetOverview().Actors); n != 0 {
+		t.Errorf("%v peers left", n)
+	}
+}
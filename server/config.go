@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"mesh-simulator/meshsim"
+)
+
+// envPrefix is prepended to upper-cased config field names to get environment variables
+// overriding them, e.g. MESHSIM_RADIORANGE
+const envPrefix = "MESHSIM_"
+
+// applyEnv overrides config values read from settings.json with environment variables.
+// Flags given on command line take precedence over environment
+func applyEnv() error {
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envPrefix + strings.ToUpper(f.Name))
+		if !ok || explicit[f.Name] || err != nil {
+			return
+		}
+		if e := flag.Set(f.Name, v); e != nil {
+			err = fmt.Errorf("bad %v%v: %v", envPrefix, strings.ToUpper(f.Name), e)
+		}
+	})
+	return err
+}
+
+// simParams returns simulation parameters worlds start with
+func (conf *config) simParams() meshsim.Params {
+	return meshsim.Params{
+		Dt:              conf.SimStep,
+		TimeRatio:       conf.TimeRatio,
+		RadioRange:      conf.RadioRange,
+		MaxPeers:        conf.MaxPeers,
+		DefaultCoord:    [2]float64{conf.DefaultLat, conf.DefaultLon},
+		SpawnSpread:     conf.SpawnSpread,
+		JitterAmplitude: conf.JitterAmplitude,
+		JitterFrequency: conf.JitterFrequency,
+	}
+}
+
+// validateParams checks simulation parameters, DefaultCoord has to be a coordinate peers may be
+// created at
+func validateParams(p meshsim.Params) error {
+	if err := p.Validate(); err != nil {
+		return badRequest("%v", err)
+	}
+	if err := validateCoord(p.DefaultCoord); err != nil {
+		return badRequest("DefaultCoord: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// gateway lets devices without WebSocket stack join simulation over UDP or TCP,
+// see meshpeer.BinaryPeer for the framing. Its clients are not authenticated, so with auth
+// enabled it runs only if GatewayAllowAnonymous is set
+type gateway struct {
+	sim        *meshsim.Simulator
+	logger     *meshlog.Logger
+	udpTimeout time.Duration
+	// checkCapacity is world limit of actors, see world.checkCapacity
+	checkCapacity func(owner string, count int) error
+
+	mtx   sync.Mutex
+	peers map[meshpeer.NetworkID]*meshpeer.BinaryPeer
+	// listeners are UDP and TCP sockets being served, closed by close
+	listeners []io.Closer
+	closed    chan struct{}
+}
+
+func newGateway(sim *meshsim.Simulator, udpTimeout time.Duration, logger *meshlog.Logger,
+	checkCapacity func(owner string, count int) error) *gateway {
+	return &gateway{
+		sim:           sim,
+		logger:        logger,
+		udpTimeout:    udpTimeout,
+		checkCapacity: checkCapacity,
+		peers:         make(map[meshpeer.NetworkID]*meshpeer.BinaryPeer),
+		closed:        make(chan struct{}),
+	}
+}
+
+// track adds listener to be closed by close, it returns false if the gateway is closed already
+func (gw *gateway) track(l io.Closer) bool {
+	gw.mtx.Lock()
+	defer gw.mtx.Unlock()
+
+	select {
+	case <-gw.closed:
+		return false
+	default:
+	}
+	gw.listeners = append(gw.listeners, l)
+	return true
+}
+
+// close stops serving UDP and TCP, serveUDP and serveTCP return nil then
+func (gw *gateway) close() {
+	gw.mtx.Lock()
+	defer gw.mtx.Unlock()
+
+	select {
+	case <-gw.closed:
+		return
+	default:
+	}
+	close(gw.closed)
+	for _, l := range gw.listeners {
+		l.Close()
+	}
+	gw.listeners = nil
+}
+
+// isClosed tells if err of a listener is caused by close
+func (gw *gateway) isClosed() bool {
+	select {
+	case <-gw.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// addPeer adds peer of client at addr, the peer is owned by the address for MaxActorsPerUser
+func (gw *gateway) addPeer(hello meshpeer.BinaryHelloData, addr net.Addr, send func([]byte)) (*meshpeer.BinaryPeer, error) {
+	coord := gw.sim.Params().DefaultCoord
+	if hello.HasCoord {
+		coord = hello.Coord
+	}
+	if err := validateCoord(coord); err != nil {
+		return nil, err
+	}
+	owner := actorOwner(authUser{}, addr.String())
+	if err := gw.checkCapacity(owner, 1); err != nil {
+		return nil, err
+	}
+	api, _ := gw.sim.AddActorWithOptions(coord, map[string]interface{}{"color": "blue"}, meshsim.ActorOptions{Owner: owner})
+	peer := meshpeer.NewBinaryPeer(api, send, gw.logger.With(meshlog.KeyPeer, api.GetMyID()).Std(meshlog.LevelWarn), hello)
+
+	gw.mtx.Lock()
+	gw.peers[api.GetMyID()] = peer
+	gw.logger.Debug("Gateway peer added", meshlog.KeyPeer, api.GetMyID(), "peers", len(gw.peers))
+	gw.mtx.Unlock()
+	return peer, nil
+}
+
+func (gw *gateway) removePeer(id meshpeer.NetworkID) {
+	gw.sim.RemoveActor(id)
+
+	gw.mtx.Lock()
+	delete(gw.peers, id)
+	gw.logger.Debug("Gateway peer removed", meshlog.KeyPeer, id, "peers", len(gw.peers))
+	gw.mtx.Unlock()
+}
+
+// restart re-registers handlers of gateway peer after simulated device restart
+func (gw *gateway) restart(id meshpeer.NetworkID) {
+	gw.mtx.Lock()
+	defer gw.mtx.Unlock()
+
+	if p, ok := gw.peers[id]; ok {
+		p.Restart()
+	}
+}
+
+type udpClient struct {
+	id       meshpeer.NetworkID
+	peer     *meshpeer.BinaryPeer
+	lastSeen time.Time
+}
+
+func (gw *gateway) serveUDP(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	if !gw.track(conn) {
+		conn.Close()
+		return nil
+	}
+	gw.logger.Info("UDP gateway listening", "addr", addr)
+
+	clientsMtx := sync.Mutex{}
+	clients := map[string]*udpClient{}
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-gw.closed:
+				return
+			case <-ticker.C:
+			}
+			clientsMtx.Lock()
+			for a, cl := range clients {
+				if time.Since(cl.lastSeen) > gw.udpTimeout {
+					gw.logger.Info("UDP client timed out", "remote", a, meshlog.KeyPeer, cl.id)
+					delete(clients, a)
+					gw.removePeer(cl.id)
+				}
+			}
+			clientsMtx.Unlock()
+		}
+	}()
+
+	buf := make([]byte, 65536)
+	for {
+		n, remote, err := conn.ReadFrom(buf)
+		if err != nil {
+			if gw.isClosed() {
+				return nil
+			}
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		frame := append([]byte{}, buf[:n]...)
+		key := remote.String()
+
+		clientsMtx.Lock()
+		cl, known := clients[key]
+		if known {
+			select {
+			case <-cl.peer.Done():
+				// the peer was removed from simulation, the client has to say hello again
+				delete(clients, key)
+				gw.removePeer(cl.id)
+				known = false
+			default:
+				cl.lastSeen = time.Now()
+			}
+		}
+		switch {
+		case frame[0] == meshpeer.BinaryHello:
+			if !known {
+				hello, err := meshpeer.DecodeBinaryHello(frame[1:])
+				if err != nil {
+					conn.WriteTo(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())), remote)
+					break
+				}
+				peer, err := gw.addPeer(hello, remote, func(f []byte) {
+					conn.WriteTo(f, remote)
+				})
+				if err != nil {
+					conn.WriteTo(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())), remote)
+					break
+				}
+				cl = &udpClient{peer.ID(), peer, time.Now()}
+				clients[key] = cl
+			}
+			conn.WriteTo(cl.peer.Welcome(), remote)
+		case !known:
+			conn.WriteTo(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte("send hello first")), remote)
+		case frame[0] == meshpeer.BinaryBye:
+			delete(clients, key)
+			gw.removePeer(cl.id)
+		default:
+			if err := cl.peer.HandleFrame(frame); err != nil {
+				conn.WriteTo(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())), remote)
+			}
+		}
+		clientsMtx.Unlock()
+	}
+}
+
+// tcpSendQueueSize limits frames waiting to be written to a TCP client, the rest is dropped
+const tcpSendQueueSize = 256
+
+func (gw *gateway) serveTCP(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if !gw.track(ln) {
+		ln.Close()
+		return nil
+	}
+	gw.logger.Info("TCP gateway listening", "addr", addr)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if gw.isClosed() {
+				return nil
+			}
+			return err
+		}
+		go gw.handleTCP(conn)
+	}
+}
+
+// errTCPFrameTooLong is returned by writeTCPFrame for frames whose length does not fit in uint16 prefix
+var errTCPFrameTooLong = errors.New("frame too long for TCP gateway")
+
+func writeTCPFrame(w io.Writer, frame []byte) error {
+	if len(frame) > math.MaxUint16 {
+		return errTCPFrameTooLong
+	}
+	l := make([]byte, 2)
+	binary.BigEndian.PutUint16(l, uint16(len(frame)))
+	if _, err := w.Write(l); err != nil {
+		return err
+	}
+	_, err := w.Write(frame)
+	return err
+}
+
+func readTCPFrame(r io.Reader) ([]byte, error) {
+	l := make([]byte, 2)
+	if _, err := io.ReadFull(r, l); err != nil {
+		return nil, err
+	}
+	frame := make([]byte, binary.BigEndian.Uint16(l))
+	_, err := io.ReadFull(r, frame)
+	return frame, err
+}
+
+func (gw *gateway) handleTCP(conn net.Conn) {
+	defer conn.Close()
+
+	out := make(chan []byte, tcpSendQueueSize)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case f := <-out:
+				err := writeTCPFrame(conn, f)
+				if err == errTCPFrameTooLong {
+					gw.logger.Warn("Dropped frame to TCP gateway client", "remote", conn.RemoteAddr().String(), "type", f[0], "len", len(f), meshlog.KeyError, err)
+					continue
+				}
+				if err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+	send := func(f []byte) {
+		select {
+		case out <- f:
+		default:
+		}
+	}
+
+	var peer *meshpeer.BinaryPeer
+	defer func() {
+		if peer != nil {
+			gw.removePeer(peer.ID())
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		frame, err := readTCPFrame(r)
+		if err != nil {
+			return
+		}
+		if len(frame) == 0 {
+			continue
+		}
+		switch {
+		case frame[0] == meshpeer.BinaryHello:
+			if peer == nil {
+				hello, err := meshpeer.DecodeBinaryHello(frame[1:])
+				if err != nil {
+					send(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())))
+					continue
+				}
+				if peer, err = gw.addPeer(hello, conn.RemoteAddr(), send); err != nil {
+					send(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())))
+					continue
+				}
+				// the connection ends when the peer is removed from simulation
+				go func(peer *meshpeer.BinaryPeer) {
+					select {
+					case <-peer.Done():
+						conn.Close()
+					case <-done:
+					}
+				}(peer)
+			}
+			send(peer.Welcome())
+		case peer == nil:
+			send(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte("send hello first")))
+		case frame[0] == meshpeer.BinaryBye:
+			return
+		default:
+			if err := peer.HandleFrame(frame); err != nil {
+				send(meshpeer.EncodeBinaryFrame(meshpeer.BinaryError, []byte(err.Error())))
+			}
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// waitListening waits until gateway started serving
+func waitListening(t *testing.T, gw *gateway) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		gw.mtx.Lock()
+		n := len(gw.listeners)
+		gw.mtx.Unlock()
+		if n > 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("gateway does not listen")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGatewayClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		serve func(gw *gateway) error
+	}{
+		{"UDP", func(gw *gateway) error { return gw.serveUDP("127.0.0.1:0") }},
+		{"TCP", func(gw *gateway) error { return gw.serveTCP("127.0.0.1:0") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := meshsim.New(meshlog.Discard())
+			defer sim.Stop()
+			gw := newGateway(sim, time.Minute, meshlog.Discard(), func(string, int) error { return nil })
+			served := make(chan error, 1)
+			go func() { served <- tt.serve(gw) }()
+			waitListening(t, gw)
+
+			gw.close()
+			select {
+			case err := <-served:
+				if err != nil {
+					t.Errorf("serve returned %v after close", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("serve does not return after close")
+			}
+			// serving closed gateway returns at once
+			if err := tt.serve(gw); err != nil {
+				t.Errorf("serve of closed gateway returned %v", err)
+			}
+		})
+	}
+}
+
+func TestTCPFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		frame   []byte
+		wantErr error
+	}{
+		{"empty", []byte{}, nil},
+		{"short", []byte{meshpeer.BinaryPing}, nil},
+		{"longest", make([]byte, math.MaxUint16), nil},
+		{"too long", make([]byte, math.MaxUint16+1), errTCPFrameTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := writeTCPFrame(buf, tt.frame); err != tt.wantErr {
+				t.Fatalf("write error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if buf.Len() != 0 {
+					t.Errorf("%v bytes written for rejected frame", buf.Len())
+				}
+				return
+			}
+			if buf.Len() != 2+len(tt.frame) {
+				t.Errorf("%v bytes written, want %v", buf.Len(), 2+len(tt.frame))
+			}
+			got, err := readTCPFrame(buf)
+			if err != nil || !bytes.Equal(got, tt.frame) {
+				t.Errorf("read %v bytes, %v", len(got), err)
+			}
+		})
+	}
+}
+
+func TestReadTCPFrameTruncated(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"no length", []byte{}},
+		{"half length", []byte{0}},
+		{"short payload", []byte{0, 3, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readTCPFrame(bytes.NewReader(tt.input)); err == nil {
+				t.Error("truncated frame is read")
+			}
+		})
+	}
+}
+
+// udpExchange sends frame to the UDP gateway and returns frames answered within timeout
+func udpExchange(t *testing.T, conn net.Conn, frame []byte, timeout time.Duration) [][]byte {
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ret := [][]byte{}
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return ret
+		}
+		ret = append(ret, append([]byte{}, buf[:n]...))
+	}
+}
+
+func TestUDPGateway(t *testing.T) {
+	// serveUDP does not report its port, so a free one is found first
+	probe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := probe.LocalAddr().String()
+	probe.Close()
+
+	sim := meshsim.New(meshlog.Discard())
+	defer sim.Stop()
+	sim.Pause()
+	gw := newGateway(sim, time.Minute, meshlog.Discard(), func(string, int) error { return nil })
+	go gw.serveUDP(addr)
+	defer gw.close()
+	waitListening(t, gw)
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	frames := udpExchange(t, conn, []byte{meshpeer.BinaryHello}, 200*time.Millisecond)
+	if len(frames) != 1 || frames[0][0] != meshpeer.BinaryWelcome {
+		t.Fatalf("hello answered %q, want welcome", frames)
+	}
+	welcome := frames[0]
+
+	tests := []struct {
+		name  string
+		frame []byte
+		want  []byte
+	}{
+		{"repeated hello", []byte{meshpeer.BinaryHello}, welcome},
+		{"ping", []byte{meshpeer.BinaryPing}, []byte{meshpeer.BinaryPong}},
+		{"unknown frame", []byte{0x7f}, append([]byte{meshpeer.BinaryError}, "unknown frame type 0x7f"...)},
+		{"bye", []byte{meshpeer.BinaryBye}, nil},
+		{"ping after bye", []byte{meshpeer.BinaryPing}, append([]byte{meshpeer.BinaryError}, "send hello first"...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := udpExchange(t, conn, tt.frame, 200*time.Millisecond)
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Errorf("answered %q", got)
+				}
+				return
+			}
+			if len(got) != 1 || !bytes.Equal(got[0], tt.want) {
+				t.Errorf("answered %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if n := len(sim.GetOverview().Actors); n != 0 {
+		t.Errorf("%v actors left after bye", n)
+	}
+}
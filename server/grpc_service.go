@@ -0,0 +1,634 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshrpc"
+	"mesh-simulator/meshsim"
+)
+
+// grpcSendQueueSize limits events waiting to be sent to a gRPC peer, the rest is dropped
+const grpcSendQueueSize = 256
+
+// grpcService implements meshrpc Peer and Simulator services, see meshrpc/meshsim.proto
+type grpcService struct {
+	sim        *meshsim.Simulator
+	logger     *meshlog.Logger
+	createPeer func(spec peerSpec) (meshpeer.NetworkID, error)
+	deletePeer func(id meshpeer.NetworkID) error
+	// checkCapacity is world limit of actors, see world.checkCapacity
+	checkCapacity func(owner string, count int) error
+
+	mtx   sync.Mutex
+	peers map[meshpeer.NetworkID]*grpcPeer
+}
+
+func newGRPCService(sim *meshsim.Simulator, logger *meshlog.Logger,
+	createPeer func(spec peerSpec) (meshpeer.NetworkID, error),
+	deletePeer func(id meshpeer.NetworkID) error,
+	checkCapacity func(owner string, count int) error) *grpcService {
+	return &grpcService{
+		sim:           sim,
+		logger:        logger,
+		createPeer:    createPeer,
+		deletePeer:    deletePeer,
+		checkCapacity: checkCapacity,
+		peers:         make(map[meshpeer.NetworkID]*grpcPeer),
+	}
+}
+
+// restart re-registers handlers of gRPC peer after simulated device restart
+func (gs *grpcService) restart(id meshpeer.NetworkID) {
+	gs.mtx.Lock()
+	defer gs.mtx.Unlock()
+
+	if p, ok := gs.peers[id]; ok {
+		p.registerHandlers()
+	}
+}
+
+// grpcPeer is mesh peer driven by a Peer.Connect stream
+type grpcPeer struct {
+	api       meshpeer.MeshAPI
+	events    chan *meshrpc.PeerEvent
+	closed    chan struct{}
+	closeOnce sync.Once
+	// dropped counts events lost to full queue since the client was last told about it
+	dropped int64
+
+	mtx          sync.Mutex
+	currentPeers map[meshpeer.NetworkID]struct{}
+	tickInterval meshpeer.NetworkTime
+	noTicks      bool
+	lastTick     meshpeer.NetworkTime
+	tickSent     bool
+}
+
+func newGRPCPeer(api meshpeer.MeshAPI, hello *meshrpc.Hello) *grpcPeer {
+	ret := &grpcPeer{
+		api:          api,
+		events:       make(chan *meshrpc.PeerEvent, grpcSendQueueSize),
+		closed:       make(chan struct{}),
+		currentPeers: make(map[meshpeer.NetworkID]struct{}),
+		tickInterval: meshpeer.NetworkTime(hello.TickIntervalMs) * 1000,
+		noTicks:      hello.NoTicks,
+	}
+	ret.send(ret.welcome())
+	ret.registerHandlers()
+	if b, ok := api.(meshpeer.LifecycleBinder); ok {
+		b.BindLifecycle(ret)
+	}
+	return ret
+}
+
+// Start implements meshpeer.Lifecycle
+func (p *grpcPeer) Start() {}
+
+// Stop implements meshpeer.Lifecycle
+func (p *grpcPeer) Stop() {}
+
+// Close implements meshpeer.Lifecycle, it ends Connect stream of the peer
+func (p *grpcPeer) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+	return nil
+}
+
+// send queues event without blocking, as mesh handlers are called from simulation tick.
+// Events which do not fit are counted, see droppedError
+func (p *grpcPeer) send(ev *meshrpc.PeerEvent) {
+	select {
+	case p.events <- ev:
+	default:
+		atomic.AddInt64(&p.dropped, 1)
+	}
+}
+
+// droppedError returns error event telling how many events were dropped since the last call,
+// or nil if none were
+func (p *grpcPeer) droppedError() *meshrpc.PeerEvent {
+	n := atomic.SwapInt64(&p.dropped, 0)
+	if n == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%v events dropped, client reads too slowly", n)
+	return &meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_Error{Error: &meshrpc.Error{Message: msg}}}
+}
+
+func (p *grpcPeer) welcome() *meshrpc.PeerEvent {
+	return &meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_Welcome{Welcome: &meshrpc.PeerID{Id: string(p.api.GetMyID())}}}
+}
+
+func (p *grpcPeer) sendError(err error) {
+	p.send(&meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_Error{Error: &meshrpc.Error{Message: err.Error()}}})
+}
+
+func (p *grpcPeer) registerHandlers() {
+	p.mtx.Lock()
+	p.currentPeers = make(map[meshpeer.NetworkID]struct{})
+	p.mtx.Unlock()
+
+	p.api.RegisterPeerAppearedHandler(func(id meshpeer.NetworkID) {
+		p.mtx.Lock()
+		p.currentPeers[id] = struct{}{}
+		p.mtx.Unlock()
+		p.send(&meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_PeerAppeared{PeerAppeared: &meshrpc.PeerID{Id: string(id)}}})
+	})
+	p.api.RegisterPeerDisappearedHandler(func(id meshpeer.NetworkID) {
+		p.mtx.Lock()
+		delete(p.currentPeers, id)
+		p.mtx.Unlock()
+		p.send(&meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_PeerDisappeared{PeerDisappeared: &meshrpc.PeerID{Id: string(id)}}})
+	})
+	p.api.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+		p.send(&meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_Receive{Receive: &meshrpc.Receive{PeerId: string(id), Data: data}}})
+	})
+	p.api.RegisterTimeTickHandler(func(ts meshpeer.NetworkTime) {
+		p.mtx.Lock()
+		if p.noTicks || (p.tickSent && ts-p.lastTick < p.tickInterval) {
+			p.mtx.Unlock()
+			return
+		}
+		p.lastTick = ts
+		p.tickSent = true
+		p.mtx.Unlock()
+
+		p.send(&meshrpc.PeerEvent{Event: &meshrpc.PeerEvent_Tick{Tick: &meshrpc.Tick{Ts: int64(ts)}}})
+	})
+}
+
+func (p *grpcPeer) handleRequest(req *meshrpc.PeerRequest) error {
+	switch r := req.Request.(type) {
+	case *meshrpc.PeerRequest_Hello:
+		p.send(p.welcome())
+	case *meshrpc.PeerRequest_Send:
+		if r.Send == nil {
+			return fmt.Errorf("empty send")
+		}
+		p.api.SendMessage(meshpeer.NetworkID(r.Send.PeerId), r.Send.Data)
+	case *meshrpc.PeerRequest_Broadcast:
+		if r.Broadcast == nil {
+			return fmt.Errorf("empty broadcast")
+		}
+		p.mtx.Lock()
+		targets := []meshpeer.NetworkID{}
+		for id := range p.currentPeers {
+			targets = append(targets, id)
+		}
+		p.mtx.Unlock()
+		for _, id := range targets {
+			p.api.SendMessage(id, r.Broadcast.Data)
+		}
+	case *meshrpc.PeerRequest_DebugData:
+		var data interface{}
+		if err := json.Unmarshal([]byte(r.DebugData.GetJson()), &data); err != nil {
+			return fmt.Errorf("debug data is not valid JSON: %v", err)
+		}
+		p.api.SendDebugData(data)
+	case *meshrpc.PeerRequest_MoveTo:
+		loc, ok := p.api.(meshpeer.LocationAPI)
+		if !ok {
+			return fmt.Errorf("peer cannot move")
+		}
+		coord := [2]float64{r.MoveTo.GetLat(), r.MoveTo.GetLon()}
+		if err := validateCoord(coord); err != nil {
+			return err
+		}
+		loc.MoveTo(coord)
+	default:
+		return fmt.Errorf("unknown request")
+	}
+	return nil
+}
+
+// authorize fails unless user of the call may control the actor, see authUser.mayControl
+func (gs *grpcService) authorize(ctx context.Context, id meshpeer.NetworkID) error {
+	if info, ok := gs.sim.GetActor(id); ok && !grpcUser(ctx).mayControl(info.Owner, grpcAddr(ctx)) {
+		return status.Error(codes.PermissionDenied, "actor belongs to another user")
+	}
+	return nil
+}
+
+// Connect implements meshrpc.PeerServer
+func (gs *grpcService) Connect(stream meshrpc.Peer_ConnectServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := req.GetHello()
+	if hello == nil {
+		return status.Error(codes.FailedPrecondition, "send hello first")
+	}
+	coord := gs.sim.Params().DefaultCoord
+	if hello.Coord != nil {
+		coord = [2]float64{hello.Coord.Lat, hello.Coord.Lon}
+	}
+	if err := validateCoord(coord); err != nil {
+		return grpcError(err, codes.InvalidArgument)
+	}
+	owner := grpcOwner(stream.Context())
+	if err := gs.checkCapacity(owner, 1); err != nil {
+		return grpcError(err, codes.ResourceExhausted)
+	}
+	api, _ := gs.sim.AddActorWithOptions(coord, map[string]interface{}{"color": "purple"}, meshsim.ActorOptions{Owner: owner})
+	peer := newGRPCPeer(api, hello)
+	id := api.GetMyID()
+
+	gs.mtx.Lock()
+	gs.peers[id] = peer
+	gs.logger.Debug("gRPC peer connected", meshlog.KeyPeer, id, "peers", len(gs.peers))
+	gs.mtx.Unlock()
+	defer func() {
+		gs.sim.RemoveActor(id)
+		gs.mtx.Lock()
+		delete(gs.peers, id)
+		gs.logger.Debug("gRPC peer disconnected", meshlog.KeyPeer, id, "peers", len(gs.peers))
+		gs.mtx.Unlock()
+	}()
+
+	sendErr := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stream.Context().Done():
+				return
+			case ev := <-peer.events:
+				if err := stream.Send(ev); err != nil {
+					sendErr <- err
+					return
+				}
+				// the queue has room again, so the client learns about the loss in order
+				if ev := peer.droppedError(); ev != nil {
+					gs.logger.Warn("gRPC peer events dropped", meshlog.KeyPeer, id, "error", ev.GetError().GetMessage())
+					if err := stream.Send(ev); err != nil {
+						sendErr <- err
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err := peer.handleRequest(req); err != nil {
+				peer.sendError(err)
+			}
+		}
+	}()
+
+	select {
+	case <-peer.closed:
+		return status.Error(codes.Aborted, "peer removed from simulation")
+	case err := <-sendErr:
+		return err
+	case err := <-recvErr:
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+}
+
+// CreatePeer implements meshrpc.SimulatorServer
+func (gs *grpcService) CreatePeer(ctx context.Context, req *meshrpc.CreatePeerRequest) (*meshrpc.CreatePeerResponse, error) {
+	coord := gs.sim.Params().DefaultCoord
+	if req.StartCoord != nil {
+		coord = [2]float64{req.StartCoord.Lat, req.StartCoord.Lon}
+	}
+	meta := map[string]interface{}{}
+	if req.MetaJson != "" {
+		if err := json.Unmarshal([]byte(req.MetaJson), &meta); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "meta is not a JSON object: "+err.Error())
+		}
+	}
+	var config json.RawMessage
+	if req.ConfigJson != "" {
+		config = json.RawMessage(req.ConfigJson)
+	}
+	id, err := gs.createPeer(peerSpec{coord, req.Exact, meta, req.Type, req.Script, config, grpcOwner(ctx)})
+	if err != nil {
+		return nil, grpcError(err, codes.InvalidArgument)
+	}
+	return &meshrpc.CreatePeerResponse{Id: string(id)}, nil
+}
+
+// DeletePeer implements meshrpc.SimulatorServer
+func (gs *grpcService) DeletePeer(ctx context.Context, req *meshrpc.PeerID) (*meshrpc.Empty, error) {
+	if err := gs.authorize(ctx, meshpeer.NetworkID(req.Id)); err != nil {
+		return nil, err
+	}
+	if err := gs.deletePeer(meshpeer.NetworkID(req.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &meshrpc.Empty{}, nil
+}
+
+// SendMessage implements meshrpc.SimulatorServer
+func (gs *grpcService) SendMessage(ctx context.Context, req *meshrpc.SendMessageRequest) (*meshrpc.Empty, error) {
+	if err := gs.authorize(ctx, meshpeer.NetworkID(req.Id)); err != nil {
+		return nil, err
+	}
+	targets := []meshpeer.NetworkID{}
+	for _, i := range req.TargetIds {
+		targets = append(targets, meshpeer.NetworkID(i))
+	}
+	if err := gs.sim.SendMessage(meshpeer.NetworkID(req.Id), targets, req.Data); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &meshrpc.Empty{}, nil
+}
+
+func marshalJSONString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// GetOverview implements meshrpc.SimulatorServer
+func (gs *grpcService) GetOverview(ctx context.Context, req *meshrpc.Empty) (*meshrpc.Overview, error) {
+	overview := gs.sim.GetOverview()
+	ret := &meshrpc.Overview{Ts: overview.TS}
+	for _, a := range overview.Actors {
+		actor := &meshrpc.Actor{
+			Id:               a.ID,
+			Coord:            &meshrpc.Coord{Lat: a.Coord[0], Lon: a.Coord[1]},
+			Peers:            a.Peers,
+			MetaJson:         marshalJSONString(a.Meta),
+			CurrentStateJson: marshalJSONString(a.CurrentState),
+			DebugDataJson:    marshalJSONString(a.DebugData),
+			Down:             a.Down,
+			Owner:            a.Owner,
+		}
+		if a.Health != nil {
+			actor.HealthJson = marshalJSONString(a.Health)
+		}
+		ret.Actors = append(ret.Actors, actor)
+	}
+	sort.Slice(ret.Actors, func(i, j int) bool { return ret.Actors[i].Id < ret.Actors[j].Id })
+	return ret, nil
+}
+
+func (gs *grpcService) simulationState() *meshrpc.SimulationState {
+	paused, simTime := gs.sim.State()
+	return &meshrpc.SimulationState{Paused: paused, SimTime: int64(simTime * 1000000)}
+}
+
+// Pause implements meshrpc.SimulatorServer
+func (gs *grpcService) Pause(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	gs.sim.Pause()
+	return gs.simulationState(), nil
+}
+
+// Resume implements meshrpc.SimulatorServer
+func (gs *grpcService) Resume(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	gs.sim.Resume()
+	return gs.simulationState(), nil
+}
+
+// maxStepTicks limits ticks of a single Step call, which holds the simulation lock all along
+const maxStepTicks = 10000
+
+// Step implements meshrpc.SimulatorServer
+func (gs *grpcService) Step(ctx context.Context, req *meshrpc.StepRequest) (*meshrpc.SimulationState, error) {
+	if req.Ticks == 0 || req.Ticks > maxStepTicks {
+		return nil, status.Errorf(codes.InvalidArgument, "ticks must be from 1 to %v", maxStepTicks)
+	}
+	if _, err := gs.sim.Step(int(req.Ticks)); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return gs.simulationState(), nil
+}
+
+// GetState implements meshrpc.SimulatorServer
+func (gs *grpcService) GetState(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	return gs.simulationState(), nil
+}
+
+// grpcWorldMetadata is gRPC metadata key naming the world a call works with, calls without it
+// go to the default world
+const grpcWorldMetadata = "mesh-world"
+
+// grpcAuthMetadata is gRPC metadata key of "Bearer <token>", the same as HTTP Authorization header
+const grpcAuthMetadata = "authorization"
+
+// grpcUserKey is context key of the user gRPC call is made by
+type grpcUserKey struct{}
+
+// grpcUser returns the user call is made by, see grpcRouter.service
+func grpcUser(ctx context.Context) authUser {
+	u, _ := ctx.Value(grpcUserKey{}).(authUser)
+	return u
+}
+
+// grpcAddr returns address the call is made from, empty if unknown
+func grpcAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// grpcOwner returns owner of actors created by the call, see actorOwner
+func grpcOwner(ctx context.Context) string {
+	return actorOwner(grpcUser(ctx), grpcAddr(ctx))
+}
+
+// grpcUserStream passes the user to Connect in stream context
+type grpcUserStream struct {
+	meshrpc.Peer_ConnectServer
+	ctx context.Context
+}
+
+func (s grpcUserStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcRouter serves meshrpc services, passing every call to grpcService of the world it names
+type grpcRouter struct {
+	worlds *worldRegistry
+	auth   *authenticator
+	logger *meshlog.Logger
+	srv    *grpc.Server
+}
+
+// setGRPCLogger routes gRPC library logs to logger. The library logger is process wide and
+// read by its goroutines without locking, so it is set once before any gRPC server starts
+func setGRPCLogger(logger *meshlog.Logger) {
+	grpclog.SetLoggerV2(grpclog.NewLoggerV2(logger.Writer(meshlog.LevelDebug), logger.Writer(meshlog.LevelWarn), logger.Writer(meshlog.LevelError)))
+}
+
+// newGRPCRouter returns router of given worlds
+func newGRPCRouter(worlds *worldRegistry, auth *authenticator, logger *meshlog.Logger) *grpcRouter {
+	gr := &grpcRouter{
+		worlds: worlds,
+		auth:   auth,
+		logger: logger,
+		srv:    grpc.NewServer(),
+	}
+	meshrpc.RegisterPeerServer(gr.srv, gr)
+	meshrpc.RegisterSimulatorServer(gr.srv, gr)
+	return gr
+}
+
+func (gr *grpcRouter) serve(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	gr.logger.Info("gRPC server listening", "addr", addr)
+	return gr.srv.Serve(ln)
+}
+
+// stop lets running calls finish until ctx is done, then cuts them off
+func (gr *grpcRouter) stop(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		gr.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		gr.srv.Stop()
+	}
+}
+
+// service checks that the caller has at least min role and returns service of the world it
+// names together with context carrying the caller
+func (gr *grpcRouter) service(ctx context.Context, min role) (*grpcService, context.Context, error) {
+	name, token := defaultWorldName, ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(grpcWorldMetadata); len(v) > 0 {
+			name = v[0]
+		}
+		if v := md.Get(grpcAuthMetadata); len(v) > 0 {
+			token = strings.TrimPrefix(v[0], "Bearer ")
+		}
+	}
+	u, ok := gr.auth.user(token)
+	if !ok {
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if u.Role < min {
+		if u.Name == "" {
+			return nil, nil, status.Error(codes.Unauthenticated, "token required")
+		}
+		return nil, nil, status.Errorf(codes.PermissionDenied, "%v role required", min)
+	}
+	w := gr.worlds.get(name)
+	if w == nil {
+		return nil, nil, status.Errorf(codes.NotFound, "world %q not found", name)
+	}
+	return w.grpc, context.WithValue(ctx, grpcUserKey{}, u), nil
+}
+
+// Connect implements meshrpc.PeerServer
+func (gr *grpcRouter) Connect(stream meshrpc.Peer_ConnectServer) error {
+	gs, ctx, err := gr.service(stream.Context(), roleOperator)
+	if err != nil {
+		return err
+	}
+	return gs.Connect(grpcUserStream{stream, ctx})
+}
+
+// CreatePeer implements meshrpc.SimulatorServer
+func (gr *grpcRouter) CreatePeer(ctx context.Context, req *meshrpc.CreatePeerRequest) (*meshrpc.CreatePeerResponse, error) {
+	gs, ctx, err := gr.service(ctx, roleOperator)
+	if err != nil {
+		return nil, err
+	}
+	return gs.CreatePeer(ctx, req)
+}
+
+// DeletePeer implements meshrpc.SimulatorServer
+func (gr *grpcRouter) DeletePeer(ctx context.Context, req *meshrpc.PeerID) (*meshrpc.Empty, error) {
+	gs, ctx, err := gr.service(ctx, roleOperator)
+	if err != nil {
+		return nil, err
+	}
+	return gs.DeletePeer(ctx, req)
+}
+
+// SendMessage implements meshrpc.SimulatorServer
+func (gr *grpcRouter) SendMessage(ctx context.Context, req *meshrpc.SendMessageRequest) (*meshrpc.Empty, error) {
+	gs, ctx, err := gr.service(ctx, roleOperator)
+	if err != nil {
+		return nil, err
+	}
+	return gs.SendMessage(ctx, req)
+}
+
+// GetOverview implements meshrpc.SimulatorServer
+func (gr *grpcRouter) GetOverview(ctx context.Context, req *meshrpc.Empty) (*meshrpc.Overview, error) {
+	gs, ctx, err := gr.service(ctx, roleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return gs.GetOverview(ctx, req)
+}
+
+// Pause implements meshrpc.SimulatorServer
+func (gr *grpcRouter) Pause(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	gs, ctx, err := gr.service(ctx, roleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return gs.Pause(ctx, req)
+}
+
+// Resume implements meshrpc.SimulatorServer
+func (gr *grpcRouter) Resume(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	gs, ctx, err := gr.service(ctx, roleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return gs.Resume(ctx, req)
+}
+
+// Step implements meshrpc.SimulatorServer
+func (gr *grpcRouter) Step(ctx context.Context, req *meshrpc.StepRequest) (*meshrpc.SimulationState, error) {
+	gs, ctx, err := gr.service(ctx, roleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return gs.Step(ctx, req)
+}
+
+// GetState implements meshrpc.SimulatorServer
+func (gr *grpcRouter) GetState(ctx context.Context, req *meshrpc.Empty) (*meshrpc.SimulationState, error) {
+	gs, ctx, err := gr.service(ctx, roleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return gs.GetState(ctx, req)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mesh-simulator/meshrpc"
+)
+
+func TestGRPCMoveTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		coord   [2]float64
+		wantErr bool
+	}{
+		{"valid place", [2]float64{53.91, 27.56}, false},
+		{"NaN", [2]float64{math.NaN(), 27.56}, true},
+		{"infinite longitude", [2]float64{53.91, math.Inf(-1)}, true},
+		{"latitude out of range", [2]float64{-91, 27.56}, true},
+		{"zero coordinate", [2]float64{0, 0}, true},
+	}
+	ts := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, id := ts.openGRPC()
+			defer stream.CloseSend()
+			before, _ := ts.world.sim.GetActor(id)
+
+			move := &meshrpc.PeerRequest{Request: &meshrpc.PeerRequest_MoveTo{MoveTo: &meshrpc.Coord{Lat: tt.coord[0], Lon: tt.coord[1]}}}
+			if err := stream.Send(move); err != nil {
+				t.Fatalf("send moveTo: %v", err)
+			}
+			errs := make(chan string, 1)
+			go func() {
+				for {
+					ev, err := stream.Recv()
+					if err != nil {
+						return
+					}
+					if e := ev.GetError(); e != nil {
+						errs <- e.GetMessage()
+					}
+				}
+			}()
+
+			if tt.wantErr {
+				select {
+				case <-errs:
+				case <-time.After(5 * time.Second):
+					t.Fatal("no error event")
+				}
+				if a, _ := ts.world.sim.GetActor(id); a.Coord != before.Coord {
+					t.Errorf("peer moved to %v after rejected moveTo", a.Coord)
+				}
+				return
+			}
+			ts.waitFor("peer to move", func() bool {
+				a, _ := ts.world.sim.GetActor(id)
+				return a.Coord == tt.coord
+			})
+			select {
+			case msg := <-errs:
+				t.Errorf("unexpected error event %q", msg)
+			default:
+			}
+		})
+	}
+}
+
+func TestGRPCPeerDroppedEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		sent    int
+		wantMsg string
+	}{
+		{"queue not full", grpcSendQueueSize, ""},
+		{"one dropped", grpcSendQueueSize + 1, "1 events dropped, client reads too slowly"},
+		{"many dropped", grpcSendQueueSize + 10, "10 events dropped, client reads too slowly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &grpcPeer{events: make(chan *meshrpc.PeerEvent, grpcSendQueueSize)}
+			for i := 0; i < tt.sent; i++ {
+				p.send(&meshrpc.PeerEvent{})
+			}
+			ev := p.droppedError()
+			if tt.wantMsg == "" {
+				if ev != nil {
+					t.Fatalf("unexpected error event %v", ev)
+				}
+				return
+			}
+			if msg := ev.GetError().GetMessage(); msg != tt.wantMsg {
+				t.Errorf("error = %q, want %q", msg, tt.wantMsg)
+			}
+			if ev := p.droppedError(); ev != nil {
+				t.Errorf("drops reported twice: %v", ev)
+			}
+		})
+	}
+}
+
+func TestGRPCSimulatorErrors(t *testing.T) {
+	ts := newTestServer(t)
+	c := meshrpc.NewSimulatorClient(ts.grpc)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"step while running", func() error { _, err := c.Step(ctx, &meshrpc.StepRequest{Ticks: 1}); return err }, codes.FailedPrecondition},
+		{"no ticks", func() error { _, err := c.Step(ctx, &meshrpc.StepRequest{}); return err }, codes.InvalidArgument},
+		{"too many ticks", func() error { _, err := c.Step(ctx, &meshrpc.StepRequest{Ticks: maxStepTicks + 1}); return err }, codes.InvalidArgument},
+		{"bad meta", func() error { _, err := c.CreatePeer(ctx, &meshrpc.CreatePeerRequest{MetaJson: "[1]"}); return err }, codes.InvalidArgument},
+		{"bad coord", func() error {
+			_, err := c.CreatePeer(ctx, &meshrpc.CreatePeerRequest{StartCoord: &meshrpc.Coord{Lat: 100, Lon: 0}})
+			return err
+		}, codes.InvalidArgument},
+		{"unknown type", func() error { _, err := c.CreatePeer(ctx, &meshrpc.CreatePeerRequest{Type: "NoSuchPeer"}); return err }, codes.InvalidArgument},
+		{"delete unknown peer", func() error { _, err := c.DeletePeer(ctx, &meshrpc.PeerID{Id: "nobody"}); return err }, codes.NotFound},
+		{"send from unknown peer", func() error {
+			_, err := c.SendMessage(ctx, &meshrpc.SendMessageRequest{Id: "nobody", Data: []byte("x")})
+			return err
+		}, codes.FailedPrecondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.call()); code != tt.want {
+				t.Errorf("code = %v, want %v", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCSimulatorControl(t *testing.T) {
+	ts := newTestServer(t)
+	c := meshrpc.NewSimulatorClient(ts.grpc)
+	ctx := context.Background()
+
+	state, err := c.Pause(ctx, &meshrpc.Empty{})
+	if err != nil || !state.Paused {
+		t.Fatalf("Pause = %v, %v", state, err)
+	}
+	ids := []string{}
+	for i := 0; i < 2; i++ {
+		resp, err := c.CreatePeer(ctx, &meshrpc.CreatePeerRequest{Exact: true, Script: `meshAPI.registerMessageHandler(function (id, data) { meshAPI.setDebugMessage(JSON.stringify({From: id, Data: data})); });`})
+		if err != nil {
+			t.Fatalf("CreatePeer: %v", err)
+		}
+		ids = append(ids, resp.Id)
+	}
+	stepped, err := c.Step(ctx, &meshrpc.StepRequest{Ticks: 5})
+	if err != nil || !stepped.Paused || stepped.SimTime-state.SimTime != 100000 {
+		t.Fatalf("Step = %v, %v, want 100ms later than %v", stepped, err, state)
+	}
+	if _, err := c.SendMessage(ctx, &meshrpc.SendMessageRequest{Id: ids[0], Data: []byte("hi")}); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if _, err := c.Step(ctx, &meshrpc.StepRequest{Ticks: 1}); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+
+	overview, err := c.GetOverview(ctx, &meshrpc.Empty{})
+	if err != nil || len(overview.Actors) != 2 {
+		t.Fatalf("GetOverview = %v, %v", overview, err)
+	}
+	for _, a := range overview.Actors {
+		if len(a.Peers) != 1 {
+			t.Errorf("%v sees %v", a.Id, a.Peers)
+		}
+		if a.Id == ids[1] && a.DebugDataJson != `{"From":"`+ids[0]+`","Data":"hi"}` {
+			t.Errorf("receiver debug data = %v", a.DebugDataJson)
+		}
+	}
+
+	if _, err := c.DeletePeer(ctx, &meshrpc.PeerID{Id: ids[0]}); err != nil {
+		t.Fatalf("DeletePeer: %v", err)
+	}
+	if state, err := c.Resume(ctx, &meshrpc.Empty{}); err != nil || state.Paused {
+		t.Errorf("Resume = %v, %v", state, err)
+	}
+	if state, err := c.GetState(ctx, &meshrpc.Empty{}); err != nil || state.Paused {
+		t.Errorf("GetState = %v, %v", state, err)
+	}
+	if overview, _ := c.GetOverview(ctx, &meshrpc.Empty{}); len(overview.Actors) != 1 {
+		t.Errorf("%v actors after delete", len(overview.Actors))
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshrpc"
+)
+
+// testServer runs the default world with HTTP, gRPC and TCP gateway endpoints. Other worlds
+// may be created over HTTP, they are served under /worlds/<name> as in the real server
+type testServer struct {
+	t      *testing.T
+	world  *world
+	worlds *worldRegistry
+	http   *httptest.Server
+	grpc   *grpc.ClientConn
+	tcp    net.Listener
+	// snapshots is directory of snapshot files
+	snapshots string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	au, err := newAuthenticator("", "")
+	if err != nil {
+		t.Fatalf("authenticator: %v", err)
+	}
+	return newAuthTestServer(t, au)
+}
+
+// newAuthTestServer is newTestServer authenticating requests with au
+func newAuthTestServer(t *testing.T, au *authenticator) *testServer {
+	conf := (&config{}).Default().(*config)
+	conf.ExamplePeers = 0
+	conf.StorageDir = ""
+	// every peer sees every other one and ticks come often
+	conf.SpawnSpread = 0
+	conf.JitterAmplitude = 0
+	conf.TimeRatio = 0.1
+	// ws_rpc peers leave simulation right after disconnect
+	conf.RPCSessionGrace = 0
+	conf.MaxRequestSize = 64 * 1024
+	logger := meshlog.Discard()
+
+	worlds := newWorldRegistry(conf, logger)
+	w, err := worlds.create(defaultWorldName, worlds.defaultSettings())
+	if err != nil {
+		t.Fatalf("create world: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(au.identify, limitBody(int64(conf.MaxRequestSize)))
+	snapshots, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	worlds.register(r, au)
+	for _, g := range []*gin.RouterGroup{r.Group("/", worlds.useDefault), r.Group("/worlds/:world", worlds.useNamed)} {
+		registerWorldRoutes(g, au, newConnLimit(0))
+		newAPIv1().register(g, au)
+		newSnapshotStore(snapshots).register(g, au)
+	}
+	r.NoRoute(apiV1NoRoute)
+	ts := &testServer{t: t, world: w, worlds: worlds, http: httptest.NewServer(r), snapshots: snapshots}
+
+	grpcSrv := newGRPCRouter(worlds, au, logger)
+	grpcLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go grpcSrv.srv.Serve(grpcLn)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if ts.grpc, err = grpc.DialContext(ctx, grpcLn.Addr().String(), grpc.WithInsecure(), grpc.WithBlock()); err != nil {
+		t.Fatalf("dial gRPC: %v", err)
+	}
+	// server side of the connection is set up by the first call
+	if _, err := meshrpc.NewSimulatorClient(ts.grpc).GetState(ctx, &meshrpc.Empty{}); err != nil {
+		t.Fatalf("gRPC GetState: %v", err)
+	}
+
+	if ts.tcp, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ts.tcp.Accept()
+			if err != nil {
+				return
+			}
+			go w.gw.handleTCP(conn)
+		}
+	}()
+
+	t.Cleanup(func() {
+		ts.tcp.Close()
+		ts.grpc.Close()
+		grpcSrv.srv.Stop()
+		ts.http.Close()
+		worlds.stopAll("test finished")
+		os.RemoveAll(snapshots)
+	})
+	return ts
+}
+
+// connectJS creates peer running JS script
+func (ts *testServer) connectJS() meshpeer.NetworkID {
+	id, err := ts.world.createPeer(peerSpec{
+		Coord:  ts.world.sim.Params().DefaultCoord,
+		Script: `meshAPI.registerTimeTickHandler(function (ts) {});`,
+	})
+	if err != nil {
+		ts.t.Fatalf("create JS peer: %v", err)
+	}
+	return id
+}
+
+// connectBuiltin creates built-in Go peer
+func (ts *testServer) connectBuiltin() meshpeer.NetworkID {
+	id, err := ts.world.createPeer(peerSpec{Coord: ts.world.sim.Params().DefaultCoord, Type: "SimplePeer1"})
+	if err != nil {
+		ts.t.Fatalf("create built-in peer: %v", err)
+	}
+	return id
+}
+
+// connectRPC joins simulation over /ws_rpc, the connection is read until it is closed
+func (ts *testServer) connectRPC() meshpeer.NetworkID {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.http.URL, "http")+"/ws_rpc", nil)
+	if err != nil {
+		ts.t.Fatalf("dial ws_rpc: %v", err)
+	}
+	session := make(chan string, 1)
+	go func() {
+		defer conn.Close()
+		for {
+			var msg struct {
+				Method string
+				Params struct{ PeerID string }
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Method == "session" {
+				session <- msg.Params.PeerID
+			}
+		}
+	}()
+	select {
+	case id := <-session:
+		return meshpeer.NetworkID(id)
+	case <-time.After(5 * time.Second):
+		ts.t.Fatal("no ws_rpc session event")
+	}
+	return ""
+}
+
+// openGRPC joins simulation with Peer.Connect stream and returns it right after welcome
+func (ts *testServer) openGRPC() (meshrpc.Peer_ConnectClient, meshpeer.NetworkID) {
+	stream, err := meshrpc.NewPeerClient(ts.grpc).Connect(context.Background())
+	if err != nil {
+		ts.t.Fatalf("gRPC Connect: %v", err)
+	}
+	if err := stream.Send(&meshrpc.PeerRequest{Request: &meshrpc.PeerRequest_Hello{Hello: &meshrpc.Hello{NoTicks: true}}}); err != nil {
+		ts.t.Fatalf("gRPC hello: %v", err)
+	}
+	ev, err := stream.Recv()
+	if err != nil || ev.GetWelcome() == nil {
+		ts.t.Fatalf("gRPC welcome: %v %v", ev, err)
+	}
+	return stream, meshpeer.NetworkID(ev.GetWelcome().GetId())
+}
+
+// connectGRPC joins simulation with Peer.Connect stream, the stream is read until it ends
+func (ts *testServer) connectGRPC() meshpeer.NetworkID {
+	stream, id := ts.openGRPC()
+	go func() {
+		for {
+			if _, err := stream.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+	return id
+}
+
+// connectGateway joins simulation over TCP gateway, the connection is read until it is closed
+func (ts *testServer) connectGateway() meshpeer.NetworkID {
+	conn, err := net.Dial("tcp", ts.tcp.Addr().String())
+	if err != nil {
+		ts.t.Fatalf("dial gateway: %v", err)
+	}
+	if err := writeTCPFrame(conn, meshpeer.EncodeBinaryFrame(meshpeer.BinaryHello)); err != nil {
+		ts.t.Fatalf("gateway hello: %v", err)
+	}
+	r := bufio.NewReader(conn)
+	frame, err := readTCPFrame(r)
+	if err != nil || len(frame) < 2 || frame[0] != meshpeer.BinaryWelcome {
+		ts.t.Fatalf("gateway welcome: %v %v", frame, err)
+	}
+	go func() {
+		defer conn.Close()
+		for {
+			if _, err := readTCPFrame(r); err != nil {
+				return
+			}
+		}
+	}()
+	return meshpeer.NetworkID(frame[2 : 2+int(frame[1])])
+}
+
+// connectAll creates peer of every runtime type and waits until they all see each other
+func (ts *testServer) connectAll() map[string]meshpeer.NetworkID {
+	ids := map[string]meshpeer.NetworkID{
+		"js":      ts.connectJS(),
+		"builtin": ts.connectBuiltin(),
+		"rpc":     ts.connectRPC(),
+		"grpc":    ts.connectGRPC(),
+		"gateway": ts.connectGateway(),
+	}
+	ts.waitFor("peers to see each other", func() bool {
+		actors := ts.world.sim.GetOverview().Actors
+		for _, id := range ids {
+			if a, ok := actors[string(id)]; !ok || len(a.Peers) != len(ids)-1 {
+				return false
+			}
+		}
+		return true
+	})
+	return ids
+}
+
+func (ts *testServer) waitFor(what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			ts.t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitGoroutines waits until number of goroutines gets back to baseline and dumps them if it does not
+func (ts *testServer) waitGoroutines(baseline int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			b := &strings.Builder{}
+			pprof.Lookup("goroutine").WriteTo(b, 1)
+			ts.t.Fatalf("%v goroutines left, baseline is %v:\n%v", runtime.NumGoroutine(), baseline, b)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRemoveActorReleasesPeers(t *testing.T) {
+	ts := newTestServer(t)
+	baseline := runtime.NumGoroutine()
+
+	ids := ts.connectAll()
+	for kind, id := range ids {
+		ts.world.sim.RemoveActor(id)
+		for otherID, a := range ts.world.sim.GetOverview().Actors {
+			for _, p := range a.Peers {
+				if p == string(id) {
+					t.Errorf("%v still sees removed %v peer %v", otherID, kind, id)
+				}
+			}
+		}
+	}
+	ts.waitGoroutines(baseline)
+
+	if n := len(ts.world.sim.GetOverview().Actors); n != 0 {
+		t.Errorf("%v actors left", n)
+	}
+	ts.world.gw.mtx.Lock()
+	if n := len(ts.world.gw.peers); n != 0 {
+		t.Errorf("%v gateway peers left", n)
+	}
+	ts.world.gw.mtx.Unlock()
+	ts.world.grpc.mtx.Lock()
+	if n := len(ts.world.grpc.peers); n != 0 {
+		t.Errorf("%v gRPC peers left", n)
+	}
+	ts.world.grpc.mtx.Unlock()
+	ts.world.rpcSessions.forEach(func(s *rpcSession) {
+		t.Errorf("ws_rpc session of %v left", s.meshPeerID)
+	})
+}
+
+func TestStopReleasesPeers(t *testing.T) {
+	ts := newTestServer(t)
+	baseline := runtime.NumGoroutine()
+
+	ts.connectAll()
+	ts.world.stop("test")
+	// the tick loop has exited too
+	ts.waitGoroutines(baseline - 1)
+
+	if n := len(ts.world.sim.GetOverview().Actors); n != 0 {
+		t.Errorf("%v actors left", n)
+	}
+	// stopping again and closing runtimes twice is harmless
+	ts.world.stop("test")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"mesh-simulator/meshpeer"
+)
+
+// apiV1LimitExceeded is /api/v1 error code of requests over a configured limit
+const apiV1LimitExceeded = "limit_exceeded"
+
+// requestError is an error caused by the request itself rather than by peer code,
+// it carries HTTP status and /api/v1 error code to answer with
+type requestError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{http.StatusBadRequest, apiV1BadRequest, fmt.Sprintf(format, args...)}
+}
+
+func limitExceeded(format string, args ...interface{}) error {
+	return &requestError{http.StatusTooManyRequests, apiV1LimitExceeded, fmt.Sprintf(format, args...)}
+}
+
+// errorStatus returns HTTP status and /api/v1 code of err, the given ones are for errors
+// that are not requestError
+func errorStatus(err error, status int, code string) (int, string) {
+	if e, ok := err.(*requestError); ok {
+		return e.status, e.code
+	}
+	return status, code
+}
+
+// grpcError converts requestError to gRPC status, other errors get the given code
+func grpcError(err error, code codes.Code) error {
+	if e, ok := err.(*requestError); ok {
+		code = codes.InvalidArgument
+		if e.code == apiV1LimitExceeded {
+			code = codes.ResourceExhausted
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+// validateCoord is meshpeer.ValidateCoord reporting bad coordinate as bad request
+func validateCoord(coord [2]float64) error {
+	if err := meshpeer.ValidateCoord(coord); err != nil {
+		return badRequest("%v", err)
+	}
+	return nil
+}
+
+// validateMeta checks that meta fits MaxMetaSize of the world when marshalled to JSON
+func (w *world) validateMeta(meta map[string]interface{}) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return badRequest("bad meta: %v", err)
+	}
+	if max := w.settings.MaxMetaSize; max > 0 && len(b) > max {
+		return badRequest("meta is %v bytes, limit is %v", len(b), max)
+	}
+	return nil
+}
+
+// validatePeer checks peer to create against world limits, before anything is created
+func (w *world) validatePeer(spec peerSpec) error {
+	if err := validateCoord(spec.Coord); err != nil {
+		return err
+	}
+	if err := w.validateMeta(spec.Meta); err != nil {
+		return err
+	}
+	if max := w.settings.MaxScriptSize; max > 0 && len(spec.Script) > max {
+		return badRequest("script is %v bytes, limit is %v", len(spec.Script), max)
+	}
+	return w.checkCapacity(spec.Owner, 1)
+}
+
+// anonymousOwner prefixes client address in owner of actors created without token
+const anonymousOwner = "anonymous@"
+
+// actorOwner returns owner of actors user creates from addr. Anonymous users are told apart
+// by their address, so MaxActorsPerUser holds for them too
+func actorOwner(u authUser, addr string) string {
+	if u.Name != "" {
+		return u.Name
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return anonymousOwner + addr
+}
+
+// checkCapacity fails if count more actors of owner would exceed MaxActors or MaxActorsPerUser.
+// The check is not atomic with adding actors, concurrent requests may overshoot limits slightly
+func (w *world) checkCapacity(owner string, count int) error {
+	total, owned := w.sim.CountActors(owner)
+	if max := w.settings.MaxActors; max > 0 && total+count > max {
+		return limitExceeded("world has %v actors, adding %v exceeds limit of %v", total, count, max)
+	}
+	if max := w.settings.MaxActorsPerUser; max > 0 && owner != "" && owned+count > max {
+		return limitExceeded("%v owns %v actors, adding %v exceeds limit of %v", owner, owned, count, max)
+	}
+	return nil
+}
+
+// connLimit counts open connections against max, zero max means no limit
+type connLimit struct {
+	max   int32
+	count int32
+}
+
+func newConnLimit(max int) *connLimit {
+	return &connLimit{
+		max: int32(max),
+	}
+}
+
+// acquire takes a connection slot, it fails if there is none left
+func (l *connLimit) acquire() bool {
+	if atomic.AddInt32(&l.count, 1) > l.max && l.max > 0 {
+		atomic.AddInt32(&l.count, -1)
+		return false
+	}
+	return true
+}
+
+func (l *connLimit) release() {
+	atomic.AddInt32(&l.count, -1)
+}
+
+// limitBody makes reading request body fail after max bytes, so a huge upload cannot exhaust memory
+func limitBody(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if max > 0 && c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		}
+	}
+}
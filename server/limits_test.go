@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidatePeer(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+	ts.world.settings.MaxActors = 3
+	ts.world.settings.MaxActorsPerUser = 1
+	ts.world.settings.MaxScriptSize = 10
+	ts.world.settings.MaxMetaSize = 20
+	coord := ts.world.sim.Params().DefaultCoord
+	if _, err := ts.world.createPeer(peerSpec{Coord: coord, Type: "SimplePeer1", Owner: "alice"}); err != nil {
+		t.Fatalf("createPeer: %v", err)
+	}
+	ts.connectBuiltin()
+
+	tests := []struct {
+		name string
+		spec peerSpec
+		// wantStatus and wantCode are of the error, zero wantStatus means no error
+		wantStatus int
+		wantCode   string
+	}{
+		{"fits", peerSpec{Coord: coord, Script: "log(1)", Meta: map[string]interface{}{"a": 1}, Owner: "bob"}, 0, ""},
+		{"nobody owns", peerSpec{Coord: coord}, 0, ""},
+		{"bad coord", peerSpec{Coord: [2]float64{0, 200}}, http.StatusBadRequest, apiV1BadRequest},
+		{"script too big", peerSpec{Coord: coord, Script: "log(123456)"}, http.StatusBadRequest, apiV1BadRequest},
+		{"meta too big", peerSpec{Coord: coord, Meta: map[string]interface{}{"label": "long label"}}, http.StatusBadRequest, apiV1BadRequest},
+		{"user limit", peerSpec{Coord: coord, Owner: "alice"}, http.StatusTooManyRequests, apiV1LimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ts.world.validatePeer(tt.spec)
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error")
+			}
+			if status, code := errorStatus(err, 0, ""); status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("error %v is %v %v, want %v %v", err, status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+
+	ts.connectBuiltin()
+	if err := ts.world.checkCapacity("", 1); err == nil {
+		t.Errorf("world of %v actors accepts one more", ts.world.settings.MaxActors)
+	}
+}
+
+func TestAPIActorLimits(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.settings.MaxActorsPerUser = 2
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{"first", map[string]interface{}{}, http.StatusCreated},
+		{"second", map[string]interface{}{}, http.StatusCreated},
+		// anonymous requests of one address share the limit
+		{"third", map[string]interface{}{}, http.StatusTooManyRequests},
+		{"body too big", map[string]interface{}{"Script": strings.Repeat(" ", 128*1024)}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("POST", "/api/v1/actors", tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+	if total, owned := ts.world.sim.CountActors(anonymousOwner + "127.0.0.1"); total != 2 || owned != 2 {
+		t.Errorf("actors = %v, owned %v, want 2 owned ones", total, owned)
+	}
+}
+
+func TestConnLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		max  int
+		// acquired is how many connections are taken before the one checked
+		acquired int
+		want     bool
+	}{
+		{"free slot", 2, 1, true},
+		{"full", 2, 2, false},
+		{"no limit", 0, 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newConnLimit(tt.max)
+			for i := 0; i < tt.acquired; i++ {
+				l.acquire()
+			}
+			if got := l.acquire(); got != tt.want {
+				t.Errorf("acquire = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	l := newConnLimit(1)
+	l.acquire()
+	l.release()
+	if !l.acquire() {
+		t.Errorf("released slot cannot be acquired")
+	}
+}
+
+func TestGRPCError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"bad request", badRequest("bad"), codes.InvalidArgument},
+		{"limit", limitExceeded("many"), codes.ResourceExhausted},
+		{"other", http.ErrHandlerTimeout, codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(grpcError(tt.err, codes.Internal)); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
+	"context"
+	"encoding/json"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,40 +16,205 @@ import (
 	"github.com/tucher/autosettings"
 
 	"net/http"
-	"sync"
 
+	"mesh-simulator/meshlog"
 	"mesh-simulator/meshpeer"
 	"mesh-simulator/meshsim"
 )
 
 type config struct {
-	DEBUG          bool
+	DEBUG          bool   `autosettings:"log debug records and run HTTP router in debug mode, overrides LogLevel"`
 	LogFile        string `autosettings:"logfile full path or stdout"`
+	LogLevel       string `autosettings:"lowest level of logged records: debug, info, warn or error"`
+	LogFormat      string `autosettings:"log record format: logfmt or json"`
 	HTTPAddress    string `autosettings:"address and port for http mode"`
 	HistorySeconds int
+
+	SimStep         float64 `autosettings:"simulation time advanced by one tick, in seconds"`
+	TimeRatio       float64 `autosettings:"wall clock time per simulation time, 1 runs simulation in real time, 2 twice slower"`
+	RadioRange      float64 `autosettings:"distance in meters within which peers see each other"`
+	MaxPeers        int     `autosettings:"how many of the nearest peers in radio range a peer sees"`
+	DefaultLat      float64 `autosettings:"latitude of place peers are created at when no position is given"`
+	DefaultLon      float64 `autosettings:"longitude of place peers are created at when no position is given"`
+	SpawnSpread     float64 `autosettings:"standard deviation in degrees of random offset of created peers from requested place"`
+	JitterAmplitude float64 `autosettings:"max amplitude in degrees of random walk of created peers"`
+	JitterFrequency float64 `autosettings:"max frequency in Hz of random walk of created peers"`
+
+	ExamplePeers  int    `autosettings:"number of example JS peers the default world starts with unless a snapshot is restored"`
+	ExampleScript string `autosettings:"script of example JS peers"`
+
+	JSMaxErrors      int  `autosettings:"disable JS peer after this number of uncaught exceptions, 0 to never disable"`
+	JSRemoveDisabled bool `autosettings:"remove disabled JS peers from simulation instead of pausing them"`
+
+	StorageDir   string `autosettings:"directory to persist peers storage to, empty to keep it in memory only"`
+	StorageQuota int    `autosettings:"peer storage size limit in bytes"`
+
+	RPCQueueSize      int    `autosettings:"max number of messages queued for a ws_rpc client"`
+	RPCOverflowPolicy string `autosettings:"what to do when ws_rpc client queue is full: drop_ticks, drop_oldest or disconnect"`
+	RPCSessionGrace   int    `autosettings:"seconds to keep ws_rpc peer alive after disconnect, so client can resume it"`
+
+	UDPAddress        string `autosettings:"address and port of binary UDP gateway, empty to disable"`
+	TCPAddress        string `autosettings:"address and port of binary TCP gateway, empty to disable"`
+	UDPGatewayTimeout int    `autosettings:"seconds of silence after which UDP gateway client is removed"`
+	// the binary protocol carries no token, so gateway peers cannot be authenticated
+	GatewayAllowAnonymous bool `autosettings:"run UDP and TCP gateways when auth is enabled, letting anyone who reaches them add peers"`
+
+	GRPCAddress string `autosettings:"address and port of gRPC server, empty to disable"`
+
+	SnapshotDir     string `autosettings:"directory of named simulation snapshots"`
+	RestoreSnapshot string `autosettings:"snapshot file to restore on start instead of creating example peers, empty to start from scratch"`
+
+	AuthTokensFile    string `autosettings:"JSON file mapping API tokens to {User, Role} of their users, empty to disable auth"`
+	AuthAnonymousRole string `autosettings:"role of requests without token when auth is enabled: none, viewer, operator or admin"`
+	CORSOrigins       string `autosettings:"comma separated origins allowed to call the API from browser pages of other sites, * for any, empty for none"`
+
+	MaxActors        int `autosettings:"max actors in a world, 0 for no limit"`
+	MaxActorsPerUser int `autosettings:"max actors a user may create in a world, anonymous clients are counted per address, 0 for no limit"`
+	MaxScriptSize    int `autosettings:"max JS peer script size in bytes, 0 for no limit"`
+	MaxMetaSize      int `autosettings:"max peer meta size in bytes of JSON, 0 for no limit"`
+	MaxWSConnections int `autosettings:"max simultaneous ws_rpc connections, 0 for no limit"`
+	MaxRequestSize   int `autosettings:"max HTTP request body size in bytes, 0 for no limit"`
+
+	ShutdownTimeout   int    `autosettings:"seconds to wait for clean shutdown on SIGINT or SIGTERM before exiting anyway"`
+	FinalOverviewFile string `autosettings:"file to write state overview of every world to on shutdown, empty to skip"`
 }
 
 func (*config) Default() autosettings.Defaultable {
+	params := meshsim.DefaultParams()
 	return &config{
-		DEBUG:       true,
-		LogFile:     "stdout",
-		HTTPAddress: "0.0.0.0:8088",
+		LogFile:      "stdout",
+		LogLevel:     meshlog.LevelInfo.String(),
+		LogFormat:    string(meshlog.FormatLogfmt),
+		HTTPAddress:  "0.0.0.0:8088",
+		StorageQuota: meshpeer.DefaultStorageQuota,
+
+		SimStep:         params.Dt,
+		TimeRatio:       params.TimeRatio,
+		RadioRange:      params.RadioRange,
+		MaxPeers:        params.MaxPeers,
+		DefaultLat:      params.DefaultCoord[0],
+		DefaultLon:      params.DefaultCoord[1],
+		SpawnSpread:     params.SpawnSpread,
+		JitterAmplitude: params.JitterAmplitude,
+		JitterFrequency: params.JitterFrequency,
+
+		ExamplePeers:  10,
+		ExampleScript: "./meshpeer/peer.js",
+
+		RPCQueueSize:      meshpeer.DefaultRPCQueueSize,
+		RPCOverflowPolicy: string(meshpeer.RPCOverflowDropTicks),
+		RPCSessionGrace:   30,
+
+		UDPGatewayTimeout: 10,
+
+		SnapshotDir: "snapshots",
+
+		AuthAnonymousRole: "viewer",
+
+		MaxActors:        1000,
+		MaxActorsPerUser: 100,
+		MaxScriptSize:    256 * 1024,
+		MaxMetaSize:      4 * 1024,
+		MaxWSConnections: 256,
+		MaxRequestSize:   16 * 1024 * 1024,
+
+		ShutdownTimeout: 10,
 	}
 }
 
-func getLogger(name string) *log.Logger {
-	var logWriter io.Writer
-	if name == "stdout" {
-		logWriter = os.Stdout
-	} else {
-		var err error
-		logWriter, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+// getLogger returns logger configured by LogFile, LogLevel, LogFormat and DEBUG together with
+// its file, to be synced on exit. Bad values are reported to the logger with defaults used instead
+func getLogger(conf *config) (*meshlog.Logger, *os.File) {
+	logFile := os.Stdout
+	var errs []error
+	if conf.LogFile != "stdout" {
+		f, err := os.OpenFile(conf.LogFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
-			fmt.Println(err)
-			logWriter = os.Stdout
+			errs = append(errs, err)
+		} else {
+			logFile = f
+		}
+	}
+	level, err := meshlog.ParseLevel(conf.LogLevel)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if conf.DEBUG {
+		level = meshlog.LevelDebug
+	}
+	format, err := meshlog.ParseFormat(conf.LogFormat)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	logger := meshlog.New(logFile, format, level)
+	for _, err := range errs {
+		logger.Error("Bad log config, using default", meshlog.KeyError, err)
+	}
+	return logger, logFile
+}
+
+// requestLogger logs every HTTP request once it is served, failed ones at warn level
+func requestLogger(logger *meshlog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		level := meshlog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = meshlog.LevelWarn
+		}
+		keyvals := []interface{}{"method", c.Request.Method, "path", c.Request.URL.Path, "status", c.Writer.Status(),
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000, "remote", c.ClientIP()}
+		if u, ok := c.Get(userContextKey); ok && u.(authUser).Name != "" {
+			keyvals = append(keyvals, "user", u.(authUser).Name)
+		}
+		if w, ok := c.Get(worldContextKey); ok && w != nil {
+			keyvals = append(keyvals, meshlog.KeyWorld, w.(*world).name)
+		}
+		if len(c.Errors) > 0 {
+			keyvals = append(keyvals, meshlog.KeyError, c.Errors.String())
+		}
+		logger.Log(level, "HTTP request", keyvals...)
+	}
+}
+
+// newCORS allows cross-origin requests from listed origins, nil means same origin only
+func newCORS(origins string) gin.HandlerFunc {
+	if origins == "" {
+		return nil
+	}
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, "Authorization")
+	if origins == "*" {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		for _, o := range strings.Split(origins, ",") {
+			corsConfig.AllowOrigins = append(corsConfig.AllowOrigins, strings.TrimSpace(o))
 		}
 	}
-	return log.New(logWriter, "", log.Llongfile|log.Ldate|log.Ltime)
+	return cors.New(corsConfig)
+}
+
+// jsPeerType is /create_peer type of peers running JS script, which is the default
+const jsPeerType = "js"
+
+// builtinPeer is npcList entry of Go peer created from meshpeer registry
+type builtinPeer struct {
+	peerType string
+	config   json.RawMessage
+	peer     interface{}
+}
+
+// peerSpec describes peer to create, empty Type means JS Script
+type peerSpec struct {
+	Coord [2]float64
+	// Exact places peer right at Coord, see meshsim.ActorOptions
+	Exact  bool
+	Meta   map[string]interface{}
+	Type   string
+	Script string
+	Config json.RawMessage
+	// Owner is the user creating peer
+	Owner string
 }
 
 var wsupgrader = websocket.Upgrader{
@@ -59,40 +226,173 @@ func main() {
 
 	conf := &config{}
 	autosettings.ReadConfig(conf)
-	logger := getLogger(conf.LogFile)
-	logger.Println("Started")
-	r := gin.Default()
-	r.Use(cors.Default())
-
-	crowdSimulator := meshsim.New(logger)
-	npcList := map[meshpeer.NetworkID]interface{}{}
-	npcListMtx := &sync.Mutex{}
-
-	if jsCode, err := ioutil.ReadFile("./meshpeer/peer.js"); err == nil {
-		for i := 0; i < 10; i++ {
-			api, frontendAPI := crowdSimulator.AddActor([2]float64{53.904153, 27.556925}, map[string]interface{}{"color": "red", "label": strconv.Itoa(i)})
-			npc, err := meshpeer.NewJSPeer(string(jsCode), log.New(os.Stdout, "[JS PEER] ", log.LstdFlags), api, frontendAPI)
-			if err != nil {
-				crowdSimulator.RemoveActor(api.GetMyID())
-				logger.Println("Cannot create js peer: ", err.Error())
-			} else {
-				npcList[api.GetMyID()] = npc
+	envErr := applyEnv()
+	logger, logFile := getLogger(conf)
+	logger.Info("Started", "log_level", conf.LogLevel, "debug", conf.DEBUG)
+	if envErr != nil {
+		logger.Fatal("Bad environment", meshlog.KeyError, envErr)
+	}
+	if err := validateParams(conf.simParams()); err != nil {
+		logger.Fatal("Bad simulation parameters", meshlog.KeyError, err)
+	}
+
+	if _, err := meshpeer.ParseRPCOverflowPolicy(conf.RPCOverflowPolicy); err != nil {
+		logger.Warn("Bad RPCOverflowPolicy, using default", meshlog.KeyError, err, "policy", meshpeer.RPCOverflowDropTicks)
+		conf.RPCOverflowPolicy = string(meshpeer.RPCOverflowDropTicks)
+	}
+	au, err := newAuthenticator(conf.AuthTokensFile, conf.AuthAnonymousRole)
+	if err != nil {
+		logger.Fatal("Cannot load auth tokens", meshlog.KeyError, err)
+	}
+	if !au.enabled() {
+		logger.Warn("Auth is disabled, everyone may control the simulation")
+	}
+	if !conf.DEBUG {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	gin.DefaultWriter = logger.Writer(meshlog.LevelDebug)
+	gin.DefaultErrorWriter = logger.Writer(meshlog.LevelError)
+	setGRPCLogger(logger)
+	r := gin.New()
+	r.Use(requestLogger(logger), gin.Recovery())
+	if h := newCORS(conf.CORSOrigins); h != nil {
+		r.Use(h)
+	}
+	r.Use(au.identify, limitBody(int64(conf.MaxRequestSize)))
+
+	worlds := newWorldRegistry(conf, logger)
+	snapshots := newSnapshotStore(conf.SnapshotDir)
+
+	settings := worlds.defaultSettings()
+	if conf.RestoreSnapshot == "" {
+		settings.ExamplePeers = conf.ExamplePeers
+	}
+	defWorld, err := worlds.create(defaultWorldName, settings)
+	if err != nil {
+		logger.Fatal("Cannot create default world", meshlog.KeyError, err)
+	}
+	if conf.RestoreSnapshot != "" {
+		if err := snapshots.loadFile(defWorld, conf.RestoreSnapshot); err != nil {
+			logger.Error("Cannot restore snapshot", "file", conf.RestoreSnapshot, meshlog.KeyError, err)
+		}
+	}
+
+	if (conf.UDPAddress != "" || conf.TCPAddress != "") && au.enabled() {
+		if conf.GatewayAllowAnonymous {
+			logger.Warn("Binary gateway has no auth, anyone reaching it may add peers", "udp", conf.UDPAddress, "tcp", conf.TCPAddress)
+		} else {
+			logger.Warn("Binary gateway is not started as it has no auth, set GatewayAllowAnonymous to run it anyway", "udp", conf.UDPAddress, "tcp", conf.TCPAddress)
+			conf.UDPAddress, conf.TCPAddress = "", ""
+		}
+	}
+	if conf.UDPAddress != "" {
+		go func() {
+			if err := defWorld.gw.serveUDP(conf.UDPAddress); err != nil {
+				logger.Error("UDP gateway stopped", meshlog.KeyError, err)
+			}
+		}()
+	}
+	if conf.TCPAddress != "" {
+		go func() {
+			if err := defWorld.gw.serveTCP(conf.TCPAddress); err != nil {
+				logger.Error("TCP gateway stopped", meshlog.KeyError, err)
 			}
+		}()
+	}
+	var grpcSrv *grpcRouter
+	if conf.GRPCAddress != "" {
+		grpcSrv = newGRPCRouter(worlds, au, logger)
+		go func() {
+			if err := grpcSrv.serve(conf.GRPCAddress); err != nil {
+				logger.Error("gRPC server stopped", meshlog.KeyError, err)
+			}
+		}()
+	}
+
+	worlds.register(r, au)
+	api := newAPIv1()
+	wsLimit := newConnLimit(conf.MaxWSConnections)
+	// every world is served under /worlds/<name>, unscoped routes serve the default one
+	for _, g := range []*gin.RouterGroup{r.Group("/", worlds.useDefault), r.Group("/worlds/:world", worlds.useNamed)} {
+		registerWorldRoutes(g, au, wsLimit)
+		api.register(g, au)
+		snapshots.register(g, au)
+		g.StaticFile("/", "./static/viewer.html")
+	}
+	r.NoRoute(apiV1NoRoute)
+	r.Static("/static", "./static")
+
+	srv := &http.Server{Addr: conf.HTTPAddress, Handler: r, ErrorLog: logger.Std(meshlog.LevelWarn)}
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			logger.Fatal("HTTP server failed", meshlog.KeyError, err)
 		}
-	} else {
-		logger.Println("Example js peer script is not found")
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	logger.Info("Shutting down", "signal", <-sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(conf.ShutdownTimeout)*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		shutdown(ctx, srv, grpcSrv, worlds, conf.FinalOverviewFile, logger)
+		close(done)
+	}()
+	exitCode := 0
+	select {
+	case <-done:
+		logger.Info("Stopped")
+	case <-ctx.Done():
+		logger.Error("Shutdown timed out, exiting anyway")
+		exitCode = 1
+	case s := <-sig:
+		logger.Warn("Exiting at once", "signal", s)
+		exitCode = 1
 	}
+	logFile.Sync()
+	os.Exit(exitCode)
+}
 
-	crowdSimulator.Run()
+// shutdown stops the server: new requests are refused, time stops in every world and its final
+// state is saved to overviewFile unless it is empty, then worlds are stopped, so peer runtimes
+// are closed and remote peers disconnected
+func shutdown(ctx context.Context, srv *http.Server, grpcSrv *grpcRouter, worlds *worldRegistry, overviewFile string, logger *meshlog.Logger) {
+	// hijacked ws_rpc connections are not waited for, they are closed when their worlds stop
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP server shutdown failed", meshlog.KeyError, err)
+	}
+	worlds.pauseAll()
+	if overviewFile != "" {
+		if err := worlds.writeOverview(overviewFile); err != nil {
+			logger.Error("Cannot write final overview", meshlog.KeyError, err)
+		} else {
+			logger.Info("Final overview written", "file", overviewFile)
+		}
+	}
+	worlds.stopAll("server shutting down")
+	if grpcSrv != nil {
+		grpcSrv.stop(ctx)
+	}
+}
 
-	r.GET("/state_overview", func(c *gin.Context) {
-		c.JSON(http.StatusOK, crowdSimulator.GetOverview())
+// registerWorldRoutes adds simulation control routes to g, which scopes them to a world
+func registerWorldRoutes(g *gin.RouterGroup, au *authenticator, wsLimit *connLimit) {
+	g.GET("/state_overview", au.require(roleViewer), func(c *gin.Context) {
+		w := worldOf(c)
+		c.JSON(http.StatusOK, w.sim.GetOverview())
 	})
 
-	r.POST("/create_peer", func(c *gin.Context) {
+	g.POST("/create_peer", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
 		type msgData struct {
-			StartCoord [2]float64
+			// StartCoord is DefaultCoord of the world if omitted
+			StartCoord *[2]float64
+			Exact      bool
+			Type       string
 			Script     string
+			Config     json.RawMessage
 			Meta       map[string]interface{}
 		}
 		json := &msgData{}
@@ -101,20 +401,43 @@ func main() {
 			return
 		}
 
-		meshAPI, frontendAPI := crowdSimulator.AddActor(json.StartCoord, json.Meta)
-		npc, err := meshpeer.NewJSPeer(json.Script, log.New(os.Stdout, "[JS PEER] ", log.LstdFlags), meshAPI, frontendAPI)
-		if err != nil {
-			crowdSimulator.RemoveActor(meshAPI.GetMyID())
-			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+		coord := w.sim.Params().DefaultCoord
+		if json.StartCoord != nil {
+			coord = *json.StartCoord
+		}
+		spec := peerSpec{coord, json.Exact, json.Meta, json.Type, json.Script, json.Config, ownerOf(c)}
+		if id, err := w.createPeer(spec); err != nil {
+			status, _ := errorStatus(err, http.StatusBadRequest, "")
+			c.JSON(status, gin.H{"ok": false, "error": err.Error()})
 		} else {
-			npcListMtx.Lock()
-			defer npcListMtx.Unlock()
-			npcList[meshAPI.GetMyID()] = npc
-			c.JSON(http.StatusOK, gin.H{"ok": true, "id": string(meshAPI.GetMyID())})
+			c.JSON(http.StatusOK, gin.H{"ok": true, "id": string(id)})
 		}
 
 	})
-	r.POST("/delete_peer", func(c *gin.Context) {
+	g.GET("/peer_types", au.require(roleViewer), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "types": append([]string{jsPeerType}, meshpeer.PeerTypes()...)})
+	})
+	g.POST("/delete_peer", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
+		type msgData struct {
+			ID string
+		}
+		json := &msgData{}
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if !authorizeActor(c, w, meshpeer.NetworkID(json.ID)) {
+			return
+		}
+		if err := w.deletePeer(meshpeer.NetworkID(json.ID)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	g.POST("/reboot_peer", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
 		type msgData struct {
 			ID string
 		}
@@ -123,19 +446,132 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
 			return
 		}
-		npcListMtx.Lock()
-		defer npcListMtx.Unlock()
+		id := meshpeer.NetworkID(json.ID)
+		if !authorizeActor(c, w, id) {
+			return
+		}
+		w.npcListMtx.Lock()
+		defer w.npcListMtx.Unlock()
 
-		if _, ok := npcList[meshpeer.NetworkID(json.ID)]; !ok {
+		npc, ok := w.npcList[id]
+		if !ok {
 			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "peer not found"})
 			return
 		}
-
-		crowdSimulator.RemoveActor(meshpeer.NetworkID(json.ID))
-		delete(npcList, meshpeer.NetworkID(json.ID))
+		meshAPI, frontendAPI, err := w.sim.RebootActor(id)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if err := w.restartPeer(npc, meshAPI, frontendAPI); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
-	r.POST("/send_msg", func(c *gin.Context) {
+	g.POST("/crash_peer", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
+		type msgData struct {
+			ID           string
+			RestartAfter float64
+		}
+		json := &msgData{}
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if !authorizeActor(c, w, meshpeer.NetworkID(json.ID)) {
+			return
+		}
+		if err := w.sim.CrashActor(meshpeer.NetworkID(json.ID), json.RestartAfter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	g.POST("/restart_peer", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
+		type msgData struct {
+			ID string
+		}
+		json := &msgData{}
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if !authorizeActor(c, w, meshpeer.NetworkID(json.ID)) {
+			return
+		}
+		if err := w.sim.RestartActor(meshpeer.NetworkID(json.ID)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	g.POST("/fault_schedule", au.require(roleAdmin), func(c *gin.Context) {
+		w := worldOf(c)
+		type msgData struct {
+			Group string
+			MTBF  float64
+			MTTR  float64
+		}
+		json := &msgData{}
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		w.sim.SetFaultSchedule(json.Group, json.MTBF, json.MTTR)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	g.GET("/peer_logs", au.require(roleViewer), func(c *gin.Context) {
+		w := worldOf(c)
+		var since int64
+		if s, err := strconv.ParseInt(c.Query("since"), 10, 64); err == nil {
+			since = s
+		}
+		jsPeer, found := w.jsPeerOf(meshpeer.NetworkID(c.Query("id")))
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "peer not found"})
+			return
+		}
+		if jsPeer == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "peer has no log capture"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "logs": jsPeer.Logs(since)})
+	})
+	g.GET("/peer_logs_ws", au.require(roleViewer), func(c *gin.Context) {
+		w := worldOf(c)
+		id := meshpeer.NetworkID(c.Query("id"))
+		var since int64
+		if s, err := strconv.ParseInt(c.Query("since"), 10, 64); err == nil {
+			since = s
+		}
+		jsPeer, found := w.jsPeerOf(id)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "peer not found"})
+			return
+		}
+		if jsPeer == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "peer has no log capture"})
+			return
+		}
+		if !wsLimit.acquire() {
+			c.JSON(http.StatusTooManyRequests, gin.H{"ok": false, "error": "too many websocket connections"})
+			return
+		}
+		defer wsLimit.release()
+
+		conn, err := wsupgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			w.logger.Warn("Failed to set websocket upgrade", meshlog.KeyError, err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		streamPeerLogs(conn, w, id, since)
+	})
+	g.POST("/send_msg", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
 		type msgData struct {
 			ID        string
 			Data      string
@@ -146,101 +582,147 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
 			return
 		}
+		if !authorizeActor(c, w, meshpeer.NetworkID(json.ID)) {
+			return
+		}
 		targets := []meshpeer.NetworkID{}
 		for _, i := range json.TargetIDs {
 			targets = append(targets, meshpeer.NetworkID(i))
 		}
-		if err := crowdSimulator.SendMessage(meshpeer.NetworkID(json.ID), targets, meshpeer.NetworkMessage(json.Data)); err == nil {
+		if err := w.sim.SendMessage(meshpeer.NetworkID(json.ID), targets, meshpeer.NetworkMessage(json.Data)); err == nil {
 			c.JSON(http.StatusOK, gin.H{"ok": true})
 		} else {
 			c.JSON(http.StatusOK, gin.H{"ok": false, "error": err.Error()})
 		}
 	})
 
-	wsMutex := sync.RWMutex{}
-	allConns := make(map[string]*wsClient)
-
-	r.GET("/ws_rpc", func(c *gin.Context) {
-		latlon := [2]float64{
-			53.904153,
-			27.556925,
+	g.GET("/ws_rpc", au.require(roleOperator), func(c *gin.Context) {
+		w := worldOf(c)
+		latlon := w.sim.Params().DefaultCoord
+		for i, param := range []string{"lat", "lon"} {
+			if v, ok := c.GetQuery(param); ok {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "bad " + param})
+					return
+				}
+				latlon[i] = f
+			}
+		}
+		if err := validateCoord(latlon); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		rpcOptions := meshpeer.RPCPeerOptions{
+			Protocol:       meshpeer.RPCProtocolJSONRPC,
+			QueueSize:      w.settings.RPCQueueSize,
+			OverflowPolicy: w.overflowPolicy,
+			Subscriptions:  meshpeer.DefaultRPCSubscriptions(),
+		}
+		if c.Query("protocol") == "legacy" {
+			rpcOptions.Protocol = meshpeer.RPCProtocolLegacy
+		}
+		if events, ok := c.GetQuery("events"); ok {
+			// only listed event classes are enabled
+			enabled := map[string]bool{}
+			for _, class := range strings.Split(events, ",") {
+				if _, known := rpcOptions.Subscriptions[class]; !known && class != "" {
+					c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": "unknown event class " + class})
+					return
+				}
+				enabled[class] = true
+			}
+			for class, sub := range rpcOptions.Subscriptions {
+				sub.Enabled = enabled[class]
+				rpcOptions.Subscriptions[class] = sub
+			}
+		}
+		for class, param := range map[string]string{meshpeer.RPCEventTick: "tick_interval", meshpeer.RPCEventPosition: "position_interval"} {
+			if interval, err := strconv.ParseInt(c.Query(param), 10, 64); err == nil && interval >= 0 {
+				sub := rpcOptions.Subscriptions[class]
+				sub.IntervalMs = interval
+				rpcOptions.Subscriptions[class] = sub
+			}
 		}
-		if lat, err := strconv.ParseFloat(c.Query("lat"), 32); err == nil {
-			latlon[0] = lat
+		if p := c.Query("overflow"); p != "" {
+			policy, err := meshpeer.ParseRPCOverflowPolicy(p)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"ok": false, "error": err.Error()})
+				return
+			}
+			rpcOptions.OverflowPolicy = policy
 		}
-		if lon, err := strconv.ParseFloat(c.Query("lon"), 32); err == nil {
-			latlon[1] = lon
+		var session *rpcSession
+		resumed := false
+		if token := c.Query("session"); token != "" {
+			if session = w.rpcSessions.get(token); session == nil {
+				c.JSON(http.StatusNotFound, gin.H{"ok": false, "error": "session not found or expired"})
+				return
+			}
+			if !authorizeActor(c, w, session.meshPeerID) {
+				return
+			}
+			resumed = true
+		} else if err := w.checkCapacity(ownerOf(c), 1); err != nil {
+			c.JSON(http.StatusTooManyRequests, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		if !wsLimit.acquire() {
+			c.JSON(http.StatusTooManyRequests, gin.H{"ok": false, "error": "too many ws_rpc connections"})
+			return
 		}
+		defer wsLimit.release()
+
 		conn, err := wsupgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			logger.Println("Failed to set websocket upgrade: ", err)
+			w.logger.Warn("Failed to set websocket upgrade", meshlog.KeyError, err)
 			c.Status(http.StatusInternalServerError)
 			return
 		}
 
-		newConn := &wsClient{
-			conn:       conn,
-			outChannel: make(chan []byte),
-			inChannel:  make(chan []byte),
+		if session == nil {
+			session = w.rpcSessions.create(func(in chan []byte, out chan []byte) (meshpeer.NetworkID, *meshpeer.RPCPeer) {
+				api, frontendAPI := w.sim.AddActorWithOptions(latlon, map[string]interface{}{"color": "green"}, meshsim.ActorOptions{Owner: ownerOf(c)})
+				return api.GetMyID(), meshpeer.NewRPCPeer(in, out, w.peerLogger(api.GetMyID()), api, frontendAPI, rpcOptions)
+			})
 		}
-		api, _ := crowdSimulator.AddActor(latlon, map[string]interface{}{"color": "green"})
-		newConn.meshPeer = meshpeer.NewRPCPeer(newConn.inChannel, newConn.outChannel, log.New(os.Stdout, "[RPC PEER] ", log.LstdFlags), api)
-		newConn.meshPeerID = api.GetMyID()
-
-		wsMutex.Lock()
-		allConns[conn.RemoteAddr().String()] = newConn
-		logger.Println("WS connections count: ", len(allConns))
-		wsMutex.Unlock()
-
-		newConn.run(logger)
 
-		crowdSimulator.RemoveActor(newConn.meshPeerID)
-		wsMutex.Lock()
-		delete(allConns, conn.RemoteAddr().String())
-		logger.Println("WS connections count: ", len(allConns))
-		wsMutex.Unlock()
-
-	})
-	r.StaticFile("/", "./static/viewer.html")
-	r.Static("/static", "./static")
-
-	r.Run(conf.HTTPAddress)
-}
-
-type wsClient struct {
-	conn       *websocket.Conn
-	outChannel chan []byte
-	inChannel  chan []byte
-
-	meshPeerID meshpeer.NetworkID
-	meshPeer   *meshpeer.RPCPeer
-}
-
-func (cl *wsClient) run(logger *log.Logger) {
-	done := make(chan bool)
-	defer func() {
-		cl.conn.Close()
-		close(done)
-		close(cl.inChannel)
-	}()
-
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case n := <-cl.outChannel:
-				cl.conn.WriteMessage(websocket.TextMessage, n)
-			}
+		client := newWSClient(conn, session)
+		if !w.rpcSessions.attach(session, client) {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4404, "session expired"))
+			conn.Close()
+			return
 		}
+		type sessionMsg struct {
+			Token        string
+			PeerID       string
+			GraceSeconds int
+			Resumed      bool
+		}
+		session.meshPeer.Notify("session", sessionMsg{session.token, string(session.meshPeerID), w.settings.RPCSessionGrace, resumed})
 
-	}()
-
-	for {
-		_, msg, err := cl.conn.ReadMessage()
-		if err != nil {
-			return
+		endSession := client.run(w.logger)
+		w.rpcSessions.detach(session, client, endSession)
+	})
+	g.GET("/rpc_clients", au.require(roleViewer), func(c *gin.Context) {
+		w := worldOf(c)
+		type clientInfo struct {
+			PeerID     string
+			RemoteAddr string
+			Connected  bool
+			Queue      meshpeer.RPCQueueStats
 		}
-		cl.inChannel <- msg
-	}
+		ret := []clientInfo{}
+		w.rpcSessions.forEach(func(s *rpcSession) {
+			info := clientInfo{PeerID: string(s.meshPeerID), Queue: s.meshPeer.Stats()}
+			s.mtx.Lock()
+			if s.client != nil {
+				info.Connected = true
+				info.RemoteAddr = s.client.conn.RemoteAddr().String()
+			}
+			s.mtx.Unlock()
+			ret = append(ret, info)
+		})
+		c.JSON(http.StatusOK, gin.H{"ok": true, "clients": ret})
+	})
 }
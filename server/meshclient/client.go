@@ -0,0 +1,199 @@
+// Package meshclient talks to a running mesh simulator over its HTTP and WebSocket APIs.
+//
+// Client wraps simulator control endpoints, RemotePeer implements meshpeer.MeshAPI on top of
+// /ws_rpc, so peer code written against MeshAPI runs out of process unchanged:
+//
+//	c := meshclient.New("http://localhost:8088")
+//	api, err := c.ConnectPeer(meshclient.PeerOptions{})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	meshpeer.NewSimplePeer1("remote", logger, api)
+//	<-api.Done()
+package meshclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mesh-simulator/meshpeer"
+)
+
+// Client provides simulator HTTP API
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New returns client of simulator listening on baseURL, e.g. http://localhost:8088. Named world
+// is targeted by its path, e.g. http://localhost:8088/worlds/test
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken makes client authenticate with API token, needed when simulator auth is enabled
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// header returns headers every request carries
+func (c *Client) header() http.Header {
+	h := http.Header{}
+	if c.token != "" {
+		h.Set("Authorization", "Bearer "+c.token)
+	}
+	return h
+}
+
+func (c *Client) do(method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.http.Do(req)
+}
+
+// CreatePeerRequest describes peer to create. Type is one of PeerTypes, empty one means JS Script.
+// Config is marshalled to JSON and passed to built-in peer. Exact places peer right at StartCoord
+// instead of somewhere near it, zero StartCoord means the default place of the simulator
+type CreatePeerRequest struct {
+	StartCoord [2]float64
+	Exact      bool
+	Type       string
+	Script     string
+	Config     interface{}
+	Meta       map[string]interface{}
+}
+
+// Actor is a peer in simulation overview
+type Actor struct {
+	ID           string
+	Coord        [2]float64
+	Peers        []string
+	Meta         map[string]interface{}
+	CurrentState interface{}
+	DebugData    interface{}
+	Health       *meshpeer.PeerHealth
+	Down         bool
+	Owner        string
+}
+
+// Overview is simulation state as returned by /state_overview
+type Overview struct {
+	TS     int64
+	Actors map[string]Actor
+}
+
+type apiResponse struct {
+	Ok    bool
+	Error string
+	ID    string
+}
+
+// post sends JSON body and checks {ok, error} answer
+func (c *Client) post(path string, body interface{}) (apiResponse, error) {
+	ret := apiResponse{}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return ret, err
+	}
+	resp, err := c.do(http.MethodPost, path, bytes.NewReader(b))
+	if err != nil {
+		return ret, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&ret); err != nil {
+		return ret, fmt.Errorf("%v: bad response, status %v: %v", path, resp.StatusCode, err)
+	}
+	if !ret.Ok {
+		return ret, fmt.Errorf("%v: %v", path, ret.Error)
+	}
+	return ret, nil
+}
+
+// CreatePeer adds peer to simulation and returns its ID
+func (c *Client) CreatePeer(req CreatePeerRequest) (meshpeer.NetworkID, error) {
+	type msgData struct {
+		CreatePeerRequest
+		StartCoord *[2]float64 `json:",omitempty"`
+	}
+	msg := msgData{CreatePeerRequest: req}
+	if req.StartCoord != [2]float64{} {
+		msg.StartCoord = &req.StartCoord
+	}
+	resp, err := c.post("/create_peer", msg)
+	if err != nil {
+		return "", err
+	}
+	return meshpeer.NetworkID(resp.ID), nil
+}
+
+// PeerTypes returns peer types CreatePeer accepts
+func (c *Client) PeerTypes() ([]string, error) {
+	type typesResponse struct {
+		Types []string
+	}
+	ret := typesResponse{}
+	resp, err := c.do(http.MethodGet, "/peer_types", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("/peer_types: status %v", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&ret)
+	return ret.Types, err
+}
+
+// DeletePeer removes peer created with CreatePeer from simulation
+func (c *Client) DeletePeer(id meshpeer.NetworkID) error {
+	type msgData struct {
+		ID string
+	}
+	_, err := c.post("/delete_peer", msgData{string(id)})
+	return err
+}
+
+// SendMessage sends data on behalf of peer id. Empty targets means all peers visible to it
+func (c *Client) SendMessage(id meshpeer.NetworkID, targets []meshpeer.NetworkID, data meshpeer.NetworkMessage) error {
+	type msgData struct {
+		ID        string
+		Data      string
+		TargetIDs []string
+	}
+	msg := msgData{ID: string(id), Data: string(data), TargetIDs: []string{}}
+	for _, t := range targets {
+		msg.TargetIDs = append(msg.TargetIDs, string(t))
+	}
+	_, err := c.post("/send_msg", msg)
+	return err
+}
+
+// Overview returns current simulation state
+func (c *Client) Overview() (Overview, error) {
+	ret := Overview{}
+	resp, err := c.do(http.MethodGet, "/state_overview", nil)
+	if err != nil {
+		return ret, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ret, fmt.Errorf("/state_overview: status %v", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&ret)
+	return ret, err
+}
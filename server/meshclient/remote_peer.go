@@ -0,0 +1,394 @@
+package meshclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"mesh-simulator/meshpeer"
+)
+
+// sessionTimeout limits waiting for session event after /ws_rpc connection is established
+const sessionTimeout = 10 * time.Second
+
+// jsonRPCVersion is the version of JSON-RPC envelope of /ws_rpc messages
+const jsonRPCVersion = "2.0"
+
+// PeerOptions configures remote peer connection
+type PeerOptions struct {
+	// Coord is the start position, simulator default is used if nil
+	Coord *[2]float64
+	// Session resumes peer of previous connection, see RemotePeer.Session
+	Session string
+	// TickIntervalMs limits tick rate, 0 means every simulation tick
+	TickIntervalMs int64
+}
+
+// RPCError is error answered by simulator to a Call
+type RPCError struct {
+	Code    int
+	Message string
+	Data    json.RawMessage
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%v (%v)", e.Message, e.Code)
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      *int64          `json:"id,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+type rpcResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// RemotePeer implements meshpeer.MeshAPI and meshpeer.LocationAPI over /ws_rpc connection.
+// Handlers are called one by one from the connection reading goroutine, like the simulator
+// calls them from its tick. Messages are carried as JSON strings, so data must be valid UTF-8
+type RemotePeer struct {
+	conn     *websocket.Conn
+	writeMtx sync.Mutex
+
+	id      meshpeer.NetworkID
+	session string
+	done    chan struct{}
+	err     error
+
+	mtx                    sync.Mutex
+	nextID                 int64
+	pending                map[int64]chan rpcResult
+	position               [2]float64
+	peerAppearedHandler    func(id meshpeer.NetworkID)
+	peerDisappearedHandler func(id meshpeer.NetworkID)
+	messageHandler         func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)
+	timeTickHandler        func(ts meshpeer.NetworkTime)
+}
+
+// ConnectPeer joins simulation as a new peer, or resumes one if options.Session is set
+func (c *Client) ConnectPeer(options PeerOptions) (*RemotePeer, error) {
+	u, err := url.Parse(c.baseURL + "/ws_rpc")
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	q := u.Query()
+	if options.Coord != nil {
+		q.Set("lat", strconv.FormatFloat(options.Coord[0], 'f', -1, 64))
+		q.Set("lon", strconv.FormatFloat(options.Coord[1], 'f', -1, 64))
+	}
+	if options.Session != "" {
+		q.Set("session", options.Session)
+	}
+	q.Set("tick_interval", strconv.FormatInt(options.TickIntervalMs, 10))
+	q.Set("events", strings.Join([]string{meshpeer.RPCEventTick, meshpeer.RPCEventPeers, meshpeer.RPCEventMessages, meshpeer.RPCEventPosition}, ","))
+	u.RawQuery = q.Encode()
+
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), c.header())
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("/ws_rpc: %v, status %v", err, resp.StatusCode)
+		}
+		return nil, err
+	}
+
+	p := &RemotePeer{
+		conn:    conn,
+		done:    make(chan struct{}),
+		pending: make(map[int64]chan rpcResult),
+	}
+	sessionCh := make(chan struct{})
+	go p.readLoop(sessionCh)
+
+	select {
+	case <-sessionCh:
+	case <-p.done:
+		return nil, p.err
+	case <-time.After(sessionTimeout):
+		p.Close()
+		return nil, fmt.Errorf("/ws_rpc: no session event")
+	}
+
+	type posMsg struct {
+		Coord [2]float64
+	}
+	pos := posMsg{}
+	if err := p.Call("getPosition", nil, &pos); err == nil {
+		p.mtx.Lock()
+		p.position = pos.Coord
+		p.mtx.Unlock()
+	}
+	return p, nil
+}
+
+func (p *RemotePeer) readLoop(sessionCh chan struct{}) {
+	defer func() {
+		p.mtx.Lock()
+		for id, ch := range p.pending {
+			ch <- rpcResult{err: fmt.Errorf("connection closed")}
+			delete(p.pending, id)
+		}
+		p.mtx.Unlock()
+		close(p.done)
+	}()
+
+	for {
+		_, data, err := p.conn.ReadMessage()
+		if err != nil {
+			p.err = err
+			return
+		}
+		msgs := []rpcMessage{}
+		if len(data) > 0 && data[0] == '[' {
+			err = json.Unmarshal(data, &msgs)
+		} else {
+			msg := rpcMessage{}
+			err = json.Unmarshal(data, &msg)
+			msgs = append(msgs, msg)
+		}
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			if msg.Method == "" {
+				p.handleResponse(msg)
+				continue
+			}
+			if msg.Method == "session" {
+				type sessionMsg struct {
+					Token  string
+					PeerID string
+				}
+				s := sessionMsg{}
+				json.Unmarshal(msg.Params, &s)
+				if p.id == "" {
+					p.id = meshpeer.NetworkID(s.PeerID)
+					p.session = s.Token
+					close(sessionCh)
+				}
+				continue
+			}
+			p.handleEvent(msg.Method, msg.Params)
+		}
+	}
+}
+
+func (p *RemotePeer) handleResponse(msg rpcMessage) {
+	if msg.ID == nil {
+		return
+	}
+	p.mtx.Lock()
+	ch, ok := p.pending[*msg.ID]
+	delete(p.pending, *msg.ID)
+	p.mtx.Unlock()
+	if !ok {
+		return
+	}
+	if msg.Error != nil {
+		ch <- rpcResult{err: msg.Error}
+	} else {
+		ch <- rpcResult{result: msg.Result}
+	}
+}
+
+func (p *RemotePeer) handleEvent(method string, params json.RawMessage) {
+	type eventMsg struct {
+		PeerID string
+		meshpeer.RPCMessageData
+		TS    int64
+		Coord [2]float64
+	}
+	ev := eventMsg{}
+	if err := json.Unmarshal(params, &ev); err != nil {
+		return
+	}
+
+	p.mtx.Lock()
+	appeared, disappeared, received, tick := p.peerAppearedHandler, p.peerDisappearedHandler, p.messageHandler, p.timeTickHandler
+	if method == "positionUpdate" {
+		p.position = ev.Coord
+	}
+	p.mtx.Unlock()
+
+	switch method {
+	case "foundPeer":
+		if appeared != nil {
+			appeared(meshpeer.NetworkID(ev.PeerID))
+		}
+	case "lostPeer":
+		if disappeared != nil {
+			disappeared(meshpeer.NetworkID(ev.PeerID))
+		}
+	case "didReceiveFromPeer":
+		if data, err := ev.Message(); err == nil && received != nil {
+			received(meshpeer.NetworkID(ev.PeerID), data)
+		}
+	case "tick":
+		if tick != nil {
+			tick(meshpeer.NetworkTime(ev.TS))
+		}
+	}
+}
+
+func (p *RemotePeer) write(msg rpcMessage) error {
+	msg.JSONRPC = jsonRPCVersion
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.writeMtx.Lock()
+	defer p.writeMtx.Unlock()
+	return p.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+// notify sends JSON-RPC notification, which is not answered
+func (p *RemotePeer) notify(method string, params interface{}) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	p.write(rpcMessage{Method: method, Params: b})
+}
+
+// Call invokes RPC method and decodes its result into result, if not nil. It waits for the answer,
+// so it must not be called from handlers
+func (p *RemotePeer) Call(method string, params interface{}, result interface{}) error {
+	msg := rpcMessage{Method: method}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = b
+	}
+	ch := make(chan rpcResult, 1)
+	p.mtx.Lock()
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	p.mtx.Unlock()
+	msg.ID = &id
+
+	if err := p.write(msg); err != nil {
+		p.mtx.Lock()
+		delete(p.pending, id)
+		p.mtx.Unlock()
+		return err
+	}
+	res := <-ch
+	if res.err != nil {
+		return res.err
+	}
+	if result != nil {
+		return json.Unmarshal(res.result, result)
+	}
+	return nil
+}
+
+// Session returns token to resume this peer with after reconnect
+func (p *RemotePeer) Session() string {
+	return p.session
+}
+
+// Done is closed when connection to simulator is lost
+func (p *RemotePeer) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the reason of connection loss after Done is closed
+func (p *RemotePeer) Err() error {
+	<-p.done
+	return p.err
+}
+
+// Close disconnects from simulator, the peer is removed after session grace period
+func (p *RemotePeer) Close() error {
+	p.writeMtx.Lock()
+	p.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	p.writeMtx.Unlock()
+	err := p.conn.Close()
+	<-p.done
+	return err
+}
+
+// GetMyID implements meshpeer.MeshAPI
+func (p *RemotePeer) GetMyID() meshpeer.NetworkID {
+	return p.id
+}
+
+// RegisterPeerAppearedHandler implements meshpeer.MeshAPI
+func (p *RemotePeer) RegisterPeerAppearedHandler(h func(id meshpeer.NetworkID)) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.peerAppearedHandler = h
+}
+
+// RegisterPeerDisappearedHandler implements meshpeer.MeshAPI
+func (p *RemotePeer) RegisterPeerDisappearedHandler(h func(id meshpeer.NetworkID)) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.peerDisappearedHandler = h
+}
+
+// RegisterMessageHandler implements meshpeer.MeshAPI
+func (p *RemotePeer) RegisterMessageHandler(h func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.messageHandler = h
+}
+
+// RegisterTimeTickHandler implements meshpeer.MeshAPI
+func (p *RemotePeer) RegisterTimeTickHandler(h func(ts meshpeer.NetworkTime)) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.timeTickHandler = h
+}
+
+// SendMessage implements meshpeer.MeshAPI
+func (p *RemotePeer) SendMessage(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+	type msgSend struct {
+		PeerID string
+		meshpeer.RPCMessageData
+	}
+	p.notify("sendToPeer", msgSend{string(id), meshpeer.NewRPCMessageData(data)})
+}
+
+// SendDebugData implements meshpeer.MeshAPI
+func (p *RemotePeer) SendDebugData(data interface{}) {
+	p.notify("setDebugData", data)
+}
+
+// GetPosition implements meshpeer.LocationAPI, position is updated by simulator once a second
+func (p *RemotePeer) GetPosition() [2]float64 {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return p.position
+}
+
+// MoveTo implements meshpeer.LocationAPI
+func (p *RemotePeer) MoveTo(coord [2]float64) {
+	type posMsg struct {
+		Coord [2]float64
+	}
+	p.mtx.Lock()
+	p.position = coord
+	p.mtx.Unlock()
+	p.notify("moveTo", posMsg{coord})
+}
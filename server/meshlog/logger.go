@@ -0,0 +1,285 @@
+// Package meshlog is the levelled structured logger of the server. Every record is a single
+// logfmt or JSON line with time, level, message and key/value fields, so long runs can be
+// grepped and shipped to log storage
+package meshlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is severity of record, records below logger level are dropped
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns level of given name, LevelInfo is returned together with error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q, expected one of %v", s, strings.Join(levelNames, ", "))
+}
+
+// Format is how records are written
+type Format string
+
+const (
+	// FormatLogfmt writes records as key=value pairs
+	FormatLogfmt Format = "logfmt"
+	// FormatJSON writes records as JSON objects
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns format of given name, FormatLogfmt is returned together with error
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatLogfmt, FormatJSON:
+		return f, nil
+	}
+	return FormatLogfmt, fmt.Errorf("unknown log format %q, expected %v or %v", s, FormatLogfmt, FormatJSON)
+}
+
+// Field keys common to records of the server
+const (
+	KeyWorld   = "world"
+	KeyPeer    = "peer"
+	KeySimTime = "sim_time"
+	KeyError   = "error"
+)
+
+// sink is the output shared by a logger and loggers derived from it
+type sink struct {
+	mtx    sync.Mutex
+	w      io.Writer
+	format Format
+	level  Level
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Logger writes records with its fields added to each. It is safe for concurrent use
+type Logger struct {
+	sink   *sink
+	fields []field
+	// clock returns simulation time added to records as sim_time, it is called while
+	// simulation may be locked, so it must not block
+	clock func() float64
+}
+
+// New returns logger writing records of given level and above to w
+func New(w io.Writer, format Format, level Level) *Logger {
+	return &Logger{
+		sink: &sink{w: w, format: format, level: level},
+	}
+}
+
+// Discard returns logger dropping every record
+func Discard() *Logger {
+	return New(ioutil.Discard, FormatLogfmt, LevelError+1)
+}
+
+// With returns logger adding given key/value pairs to every record
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	ret := *l
+	ret.fields = append(append([]field{}, l.fields...), pairs(keyvals)...)
+	return &ret
+}
+
+// WithClock returns logger adding simulation time returned by clock to every record
+func (l *Logger) WithClock(clock func() float64) *Logger {
+	ret := *l
+	ret.clock = clock
+	return &ret
+}
+
+// Enabled tells whether records of given level are written, so costly fields may be skipped
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.sink.level
+}
+
+// Log writes record of given level with message and key/value pairs
+func (l *Logger) Log(level Level, msg string, keyvals ...interface{}) {
+	l.output(2, level, msg, keyvals)
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.output(2, LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.output(2, LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.output(2, LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.output(2, LevelError, msg, keyvals)
+}
+
+// Fatal writes error record and exits
+func (l *Logger) Fatal(msg string, keyvals ...interface{}) {
+	l.output(2, LevelError, msg, keyvals)
+	os.Exit(1)
+}
+
+// Writer returns writer turning every write into record of given level. It lets code
+// expecting io.Writer or log.Logger log through l
+func (l *Logger) Writer(level Level) io.Writer {
+	return &writer{l, level}
+}
+
+// Std returns log.Logger writing every message as record of given level, for code that takes log.Logger
+func (l *Logger) Std(level Level) *log.Logger {
+	return log.New(l.Writer(level), "", 0)
+}
+
+// output writes record, depth is the number of calls between the logging code and output
+func (l *Logger) output(depth int, level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	fields := make([]field, 0, 5+len(l.fields)+len(keyvals)/2)
+	fields = append(fields, field{"ts", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00")}, field{"level", level.String()})
+	if depth > 0 {
+		if _, file, line, ok := runtime.Caller(depth); ok {
+			fields = append(fields, field{"caller", filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file) + ":" + strconv.Itoa(line)})
+		}
+	}
+	fields = append(fields, field{"msg", msg})
+	if l.clock != nil {
+		fields = append(fields, field{KeySimTime, math.Round(l.clock()*1000) / 1000})
+	}
+	fields = append(fields, l.fields...)
+	fields = append(fields, pairs(keyvals)...)
+
+	var b []byte
+	if l.sink.format == FormatJSON {
+		b = appendJSON(nil, fields)
+	} else {
+		b = appendLogfmt(nil, fields)
+	}
+	b = append(b, '\n')
+
+	l.sink.mtx.Lock()
+	defer l.sink.mtx.Unlock()
+	l.sink.w.Write(b)
+}
+
+// pairs turns key/value list into fields, a value without key gets key "extra"
+func pairs(keyvals []interface{}) []field {
+	ret := make([]field, 0, len(keyvals)/2+1)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 == len(keyvals) {
+			ret = append(ret, field{"extra", keyvals[i]})
+			break
+		}
+		ret = append(ret, field{fmt.Sprint(keyvals[i]), keyvals[i+1]})
+	}
+	return ret
+}
+
+// plain returns value the way it should be written, errors and Stringers become strings
+func plain(v interface{}) interface{} {
+	switch x := v.(type) {
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	}
+	return v
+}
+
+func appendJSON(b []byte, fields []field) []byte {
+	b = append(b, '{')
+	for i, f := range fields {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		k, _ := json.Marshal(f.key)
+		b = append(b, k...)
+		b = append(b, ':')
+		v, err := json.Marshal(plain(f.value))
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprint(f.value))
+		}
+		b = append(b, v...)
+	}
+	return append(b, '}')
+}
+
+func appendLogfmt(b []byte, fields []field) []byte {
+	for i, f := range fields {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, f.key...)
+		b = append(b, '=')
+		s := logfmtValue(plain(f.value))
+		if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, func(r rune) bool { return r < ' ' }) >= 0 {
+			s = strconv.Quote(s)
+		}
+		b = append(b, s...)
+	}
+	return b
+}
+
+// logfmtValue formats scalars as they are and everything else as JSON
+func logfmtValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v)
+	}
+	if j, err := json.Marshal(v); err == nil {
+		return string(j)
+	}
+	return fmt.Sprint(v)
+}
+
+type writer struct {
+	l     *Logger
+	level Level
+}
+
+func (lw *writer) Write(p []byte) (int, error) {
+	if msg := strings.TrimSpace(string(p)); msg != "" {
+		lw.l.output(0, lw.level, msg, nil)
+	}
+	return len(p), nil
+}
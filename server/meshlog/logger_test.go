@@ -0,0 +1,180 @@
+package meshlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"mesh-simulator/meshlog"
+)
+
+// varying matches fields which differ from run to run
+var varying = regexp.MustCompile(`ts=\S+ |caller=\S+ `)
+
+func TestLogfmt(t *testing.T) {
+	tests := []struct {
+		name  string
+		level meshlog.Level
+		log   func(l *meshlog.Logger)
+		want  string
+	}{
+		{
+			name:  "fields",
+			level: meshlog.LevelInfo,
+			log:   func(l *meshlog.Logger) { l.Info("Peer added", meshlog.KeyPeer, "a1", "count", 3, "ok", true) },
+			want:  "level=info msg=\"Peer added\" peer=a1 count=3 ok=true\n",
+		},
+		{
+			name:  "quoting",
+			level: meshlog.LevelInfo,
+			log:   func(l *meshlog.Logger) { l.Warn("x", "empty", "", "eq", "a=b", "quote", `say "hi"`, "line", "a\nb") },
+			want:  `level=warn msg=x empty="" eq="a=b" quote="say \"hi\"" line="a\nb"` + "\n",
+		},
+		{
+			name:  "values",
+			level: meshlog.LevelInfo,
+			log: func(l *meshlog.Logger) {
+				l.Error("x", meshlog.KeyError, errors.New("broken pipe"), "min", meshlog.LevelWarn, "f", 0.25, "list", []int{1, 2}, "nil", nil)
+			},
+			want: `level=error msg=x error="broken pipe" min=warn f=0.25 list=[1,2] nil=""` + "\n",
+		},
+		{
+			name:  "value without key",
+			level: meshlog.LevelInfo,
+			log:   func(l *meshlog.Logger) { l.Info("x", "a", 1, "lonely") },
+			want:  "level=info msg=x a=1 extra=lonely\n",
+		},
+		{
+			name:  "below level",
+			level: meshlog.LevelWarn,
+			log:   func(l *meshlog.Logger) { l.Info("x"); l.Debug("y") },
+			want:  "",
+		},
+		{
+			name:  "debug",
+			level: meshlog.LevelDebug,
+			log:   func(l *meshlog.Logger) { l.Debug("x") },
+			want:  "level=debug msg=x\n",
+		},
+		{
+			name:  "With",
+			level: meshlog.LevelInfo,
+			log: func(l *meshlog.Logger) {
+				w := l.With(meshlog.KeyWorld, "alpha")
+				w.With(meshlog.KeyPeer, "a1").Info("x", "k", "v")
+				// fields of derived loggers do not leak into the parent one
+				w.Info("y")
+				l.Info("z")
+			},
+			want: "level=info msg=x world=alpha peer=a1 k=v\nlevel=info msg=y world=alpha\nlevel=info msg=z\n",
+		},
+		{
+			name:  "WithClock",
+			level: meshlog.LevelInfo,
+			log: func(l *meshlog.Logger) {
+				l.With(meshlog.KeyWorld, "alpha").WithClock(func() float64 { return 1.23456 }).Info("x")
+			},
+			want: "level=info msg=x sim_time=1.235 world=alpha\n",
+		},
+		{
+			name:  "Std",
+			level: meshlog.LevelInfo,
+			log: func(l *meshlog.Logger) {
+				std := l.Std(meshlog.LevelWarn)
+				std.Printf("http: %v\n", "accept error")
+				std.Print("  ")
+			},
+			want: "level=warn msg=\"http: accept error\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tt.log(meshlog.New(buf, meshlog.FormatLogfmt, tt.level))
+			if got := varying.ReplaceAllString(buf.String(), ""); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := meshlog.New(buf, meshlog.FormatJSON, meshlog.LevelInfo)
+	l.With(meshlog.KeyWorld, "alpha").Warn("Cannot save", meshlog.KeyError, errors.New("disk full"), "size", 3, "meta", map[string]string{"a": "b"})
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("record %q is not JSON: %v", buf.String(), err)
+	}
+	if caller, _ := got["caller"].(string); !strings.HasPrefix(caller, "meshlog/logger_test.go:") {
+		t.Errorf("caller = %q, want line of this test", caller)
+	}
+	if _, ok := got["ts"].(string); !ok {
+		t.Errorf("no ts in %v", got)
+	}
+	delete(got, "caller")
+	delete(got, "ts")
+	want := map[string]interface{}{
+		"level": "warn", "msg": "Cannot save", "world": "alpha", "error": "disk full",
+		"size": 3.0, "meta": map[string]interface{}{"a": "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("record = %v, want %v", got, want)
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	l := meshlog.Discard()
+	for _, level := range []meshlog.Level{meshlog.LevelDebug, meshlog.LevelInfo, meshlog.LevelWarn, meshlog.LevelError} {
+		if l.Enabled(level) {
+			t.Errorf("discarding logger is enabled for %v", level)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    meshlog.Level
+		wantErr bool
+	}{
+		{"debug", meshlog.LevelDebug, false},
+		{"WARN", meshlog.LevelWarn, false},
+		{"error", meshlog.LevelError, false},
+		{"verbose", meshlog.LevelInfo, true},
+		{"", meshlog.LevelInfo, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := meshlog.ParseLevel(tt.in)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseLevel = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    meshlog.Format
+		wantErr bool
+	}{
+		{"logfmt", meshlog.FormatLogfmt, false},
+		{"JSON", meshlog.FormatJSON, false},
+		{"xml", meshlog.FormatLogfmt, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := meshlog.ParseFormat(tt.in)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseFormat = %v, %v, want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
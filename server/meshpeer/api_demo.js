@@ -1,15 +1,20 @@
 log('started');
-var api = new MeshAPI();
+var api = meshAPI;
 var myId = api.getMyID();
 log('my ID:', myId);
-api.sendMessage('bla', "some message");
 
+// Binary payloads: pass ArrayBuffer or Uint8Array to sendMessage and ask for
+// Uint8Array delivery when registering the message handler. Only the last registered
+// handler is called, so one handler serves both text and binary messages
 api.registerMessageHandler(function(id, data) {
-    //log('onMessage: ', id, data);
-});
+    // data is Uint8Array here
+    log('onMessage: ', id, data.length, 'bytes');
+}, {binary: true});
 
 api.registerPeerAppearedHandler(function(id) {
     log('onPeerAppeared: ', id);
+    api.sendMessage(id, "some message");
+    api.sendMessage(id, new Uint8Array([0x01, 0x02, 0xff]));
 });
 
 api.registerPeerDisappearedHandler(function(id) {
@@ -21,6 +26,13 @@ api.registerTimeTickHandler(function(ts) {
     //log('onTimeTick: ', ts);
     api.setDebugMessage(JSON.stringify({MyID: api.getMyID(), MyTS:  currentTS}));
 });
-api.registerUserDataUpdateHandler(function(userDataObj){
+frontendAPI.registerUserDataUpdateHandler(function(userDataObj){
     //handle updated user data. This will be called from frontend
 });
+
+// Persistent storage survives peer reboots. Values are strings or binary
+storage.set('boots', String(parseInt(storage.get('boots') || '0') + 1));
+storage.set('blob', new Uint8Array([1, 2, 3]));
+var blob = storage.get('blob', {binary: true});
+log(storage.keys());
+storage.delete('blob');
@@ -0,0 +1,237 @@
+package meshpeer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+)
+
+// Binary gateway framing. Every frame is a type byte followed by payload. Over UDP one datagram
+// carries one frame, over TCP every frame is prefixed with its length as big endian uint16, so TCP
+// frames, type byte included, are at most 65535 bytes long. Longer frames to a TCP client are
+// dropped with a warning in server log, UDP ones are limited by datagram size the same way.
+// Peer IDs are encoded as one length byte followed by ID bytes, numbers are big endian.
+//
+// Client to server:
+//
+//	BinaryHello   [lat float64][lon float64][tick interval ms uint32], all fields optional:
+//	              payload is 0, 16 or 20 bytes long. Tick interval 0 means every simulation tick,
+//	              BinaryNoTicks disables ticks. Repeated hello returns the same ID
+//	BinarySend    [peer ID][data...]
+//	BinaryPing    keeps UDP client alive, answered with BinaryPong
+//	BinaryBye     leaves simulation
+//
+// Server to client:
+//
+//	BinaryWelcome          [peer ID] of this client
+//	BinaryPeerAppeared     [peer ID]
+//	BinaryPeerDisappeared  [peer ID]
+//	BinaryReceive          [peer ID][data...]
+//	BinaryTick             [ts int64], simulation time in microseconds
+//	BinaryPong
+//	BinaryError            [UTF-8 message...]
+const (
+	BinaryHello byte = 0x01
+	BinarySend  byte = 0x02
+	BinaryPing  byte = 0x03
+	BinaryBye   byte = 0x04
+
+	BinaryWelcome         byte = 0x81
+	BinaryPeerAppeared    byte = 0x82
+	BinaryPeerDisappeared byte = 0x83
+	BinaryReceive         byte = 0x84
+	BinaryTick            byte = 0x85
+	BinaryPong            byte = 0x86
+	BinaryError           byte = 0x8F
+)
+
+// BinaryNoTicks is the hello tick interval value disabling ticks
+const BinaryNoTicks = math.MaxUint32
+
+// BinaryHelloData is decoded BinaryHello payload
+type BinaryHelloData struct {
+	HasCoord       bool
+	Coord          [2]float64
+	TickIntervalMs uint32
+}
+
+// DecodeBinaryHello parses BinaryHello payload
+func DecodeBinaryHello(payload []byte) (BinaryHelloData, error) {
+	ret := BinaryHelloData{}
+	switch len(payload) {
+	case 0:
+	case 16, 20:
+		ret.HasCoord = true
+		ret.Coord[0] = math.Float64frombits(binary.BigEndian.Uint64(payload[0:8]))
+		ret.Coord[1] = math.Float64frombits(binary.BigEndian.Uint64(payload[8:16]))
+		if len(payload) == 20 {
+			ret.TickIntervalMs = binary.BigEndian.Uint32(payload[16:20])
+		}
+	default:
+		return ret, fmt.Errorf("bad hello length %v", len(payload))
+	}
+	return ret, nil
+}
+
+// EncodeBinaryFrame builds frame of given type
+func EncodeBinaryFrame(frameType byte, payload ...[]byte) []byte {
+	ret := []byte{frameType}
+	for _, p := range payload {
+		ret = append(ret, p...)
+	}
+	return ret
+}
+
+func encodeBinaryID(id NetworkID) []byte {
+	if len(id) > 255 {
+		id = id[:255]
+	}
+	return append([]byte{byte(len(id))}, id...)
+}
+
+func decodeBinaryID(payload []byte) (NetworkID, []byte, error) {
+	if len(payload) < 1 || len(payload) < 1+int(payload[0]) {
+		return "", nil, fmt.Errorf("truncated peer ID")
+	}
+	l := int(payload[0])
+	return NetworkID(payload[1 : 1+l]), payload[1+l:], nil
+}
+
+// BinaryPeer provides mesh network peer controlled with compact binary frames
+type BinaryPeer struct {
+	api    MeshAPI
+	send   func(frame []byte)
+	logger *log.Logger
+
+	mtx          sync.Mutex
+	tickInterval NetworkTime
+	noTicks      bool
+	lastTick     NetworkTime
+	tickSent     bool
+	stopped      bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewBinaryPeer returns new BinaryPeer. send must not block
+func NewBinaryPeer(api MeshAPI, send func(frame []byte), logger *log.Logger, hello BinaryHelloData) *BinaryPeer {
+	ret := &BinaryPeer{
+		api:          api,
+		send:         send,
+		logger:       logger,
+		tickInterval: NetworkTime(hello.TickIntervalMs) * 1000,
+		noTicks:      hello.TickIntervalMs == BinaryNoTicks,
+		done:         make(chan struct{}),
+	}
+	ret.registerHandlers()
+	bindLifecycle(api, ret)
+	return ret
+}
+
+// Start implements Lifecycle
+func (th *BinaryPeer) Start() {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+	th.stopped = false
+}
+
+// Stop implements Lifecycle, no frames are sent to client after it
+func (th *BinaryPeer) Stop() {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+	th.stopped = true
+}
+
+// Close implements Lifecycle, Done gets closed, so transport can drop the client
+func (th *BinaryPeer) Close() error {
+	th.Stop()
+	th.closeOnce.Do(func() {
+		close(th.done)
+	})
+	return nil
+}
+
+// Done is closed when the peer leaves simulation
+func (th *BinaryPeer) Done() <-chan struct{} {
+	return th.done
+}
+
+func (th *BinaryPeer) isStopped() bool {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+	return th.stopped
+}
+
+func (th *BinaryPeer) sendFrame(frame []byte) {
+	if th.isStopped() {
+		return
+	}
+	th.send(frame)
+}
+
+// ID returns mesh ID of this peer
+func (th *BinaryPeer) ID() NetworkID {
+	return th.api.GetMyID()
+}
+
+// Welcome returns BinaryWelcome frame for this peer
+func (th *BinaryPeer) Welcome() []byte {
+	return EncodeBinaryFrame(BinaryWelcome, encodeBinaryID(th.api.GetMyID()))
+}
+
+// HandleFrame processes frame received from client, except hello and bye which belong to transport
+func (th *BinaryPeer) HandleFrame(frame []byte) error {
+	if len(frame) == 0 {
+		return fmt.Errorf("empty frame")
+	}
+	if th.isStopped() {
+		return fmt.Errorf("peer left simulation")
+	}
+	switch frame[0] {
+	case BinarySend:
+		id, data, err := decodeBinaryID(frame[1:])
+		if err != nil {
+			return err
+		}
+		th.api.SendMessage(id, append(NetworkMessage{}, data...))
+	case BinaryPing:
+		th.sendFrame(EncodeBinaryFrame(BinaryPong))
+	default:
+		return fmt.Errorf("unknown frame type 0x%02x", frame[0])
+	}
+	return nil
+}
+
+// Restart registers peer handlers again after simulated device restart dropped them
+func (th *BinaryPeer) Restart() {
+	th.registerHandlers()
+}
+
+func (th *BinaryPeer) registerHandlers() {
+	th.api.RegisterPeerAppearedHandler(func(id NetworkID) {
+		th.sendFrame(EncodeBinaryFrame(BinaryPeerAppeared, encodeBinaryID(id)))
+	})
+	th.api.RegisterPeerDisappearedHandler(func(id NetworkID) {
+		th.sendFrame(EncodeBinaryFrame(BinaryPeerDisappeared, encodeBinaryID(id)))
+	})
+	th.api.RegisterMessageHandler(func(id NetworkID, data NetworkMessage) {
+		th.sendFrame(EncodeBinaryFrame(BinaryReceive, encodeBinaryID(id), data))
+	})
+	th.api.RegisterTimeTickHandler(func(ts NetworkTime) {
+		th.mtx.Lock()
+		if th.noTicks || (th.tickSent && ts-th.lastTick < th.tickInterval) {
+			th.mtx.Unlock()
+			return
+		}
+		th.lastTick = ts
+		th.tickSent = true
+		th.mtx.Unlock()
+
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, uint64(ts))
+		th.sendFrame(EncodeBinaryFrame(BinaryTick, b))
+	})
+}
@@ -0,0 +1,190 @@
+package meshpeer_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func helloPayload(lat, lon float64, tick ...uint32) []byte {
+	b := make([]byte, 16, 20)
+	binary.BigEndian.PutUint64(b[0:8], math.Float64bits(lat))
+	binary.BigEndian.PutUint64(b[8:16], math.Float64bits(lon))
+	for _, t := range tick {
+		b = b[:20]
+		binary.BigEndian.PutUint32(b[16:20], t)
+	}
+	return b
+}
+
+func TestDecodeBinaryHello(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    meshpeer.BinaryHelloData
+		wantErr bool
+	}{
+		{"empty", nil, meshpeer.BinaryHelloData{}, false},
+		{"coord", helloPayload(53.9, 27.5), meshpeer.BinaryHelloData{HasCoord: true, Coord: [2]float64{53.9, 27.5}}, false},
+		{"coord and tick interval", helloPayload(53.9, 27.5, 100), meshpeer.BinaryHelloData{HasCoord: true, Coord: [2]float64{53.9, 27.5}, TickIntervalMs: 100}, false},
+		{"no ticks", helloPayload(1, 2, meshpeer.BinaryNoTicks), meshpeer.BinaryHelloData{HasCoord: true, Coord: [2]float64{1, 2}, TickIntervalMs: meshpeer.BinaryNoTicks}, false},
+		{"truncated coord", helloPayload(1, 2)[:15], meshpeer.BinaryHelloData{}, true},
+		{"truncated tick interval", append(helloPayload(1, 2), 0, 0), meshpeer.BinaryHelloData{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := meshpeer.DecodeBinaryHello(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("hello = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// frameRecorder keeps frames sent to binary client
+type frameRecorder struct {
+	mtx    sync.Mutex
+	frames [][]byte
+}
+
+func (r *frameRecorder) send(f []byte) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.frames = append(r.frames, f)
+}
+
+// take returns recorded frames of given type and forgets all frames
+func (r *frameRecorder) take(frameType byte) [][]byte {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	ret := [][]byte{}
+	for _, f := range r.frames {
+		if f[0] == frameType {
+			ret = append(ret, f)
+		}
+	}
+	r.frames = nil
+	return ret
+}
+
+func newTestBinaryPeer(t *testing.T, hello meshpeer.BinaryHelloData) (*meshsim.Simulator, *meshpeer.BinaryPeer, *frameRecorder, meshpeer.MeshAPI) {
+	sim := meshsim.New(meshlog.Discard())
+	t.Cleanup(sim.Stop)
+	sim.Pause()
+	other, _ := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	api, _ := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	r := &frameRecorder{}
+	return sim, meshpeer.NewBinaryPeer(api, r.send, log.New(ioutil.Discard, "", 0), hello), r, other
+}
+
+func TestBinaryPeerHandleFrame(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     func(other meshpeer.NetworkID) []byte
+		wantErr   string
+		wantPong  bool
+		wantRecvd string
+	}{
+		{"send", func(other meshpeer.NetworkID) []byte {
+			return meshpeer.EncodeBinaryFrame(meshpeer.BinarySend, []byte{byte(len(other))}, []byte(other), []byte{0, 0xff})
+		}, "", false, "\x00\xff"},
+		{"ping", func(meshpeer.NetworkID) []byte { return []byte{meshpeer.BinaryPing} }, "", true, ""},
+		{"empty", func(meshpeer.NetworkID) []byte { return []byte{} }, "empty frame", false, ""},
+		{"unknown type", func(meshpeer.NetworkID) []byte { return []byte{0x7f} }, "unknown frame type 0x7f", false, ""},
+		{"truncated ID", func(meshpeer.NetworkID) []byte { return []byte{meshpeer.BinarySend, 10, 'a'} }, "truncated peer ID", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, p, r, other := newTestBinaryPeer(t, meshpeer.BinaryHelloData{TickIntervalMs: meshpeer.BinaryNoTicks})
+			received := make(chan meshpeer.NetworkMessage, 1)
+			other.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+				received <- data
+			})
+			if _, err := sim.Step(1); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+
+			err := p.HandleFrame(tt.frame(other.GetMyID()))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HandleFrame: %v", err)
+			}
+			if pongs := r.take(meshpeer.BinaryPong); (len(pongs) == 1) != tt.wantPong {
+				t.Errorf("%v pongs sent", len(pongs))
+			}
+			if _, err := sim.Step(1); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			select {
+			case data := <-received:
+				if string(data) != tt.wantRecvd {
+					t.Errorf("received %q, want %q", data, tt.wantRecvd)
+				}
+			default:
+				if tt.wantRecvd != "" {
+					t.Error("nothing received")
+				}
+			}
+		})
+	}
+}
+
+func TestBinaryPeerEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		// tickInterval is hello tick interval, 10 ticks 20ms each are made
+		tickInterval uint32
+		wantTicks    int
+	}{
+		{"every tick", 0, 10},
+		{"tick interval", 100, 2},
+		{"no ticks", meshpeer.BinaryNoTicks, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, p, r, other := newTestBinaryPeer(t, meshpeer.BinaryHelloData{TickIntervalMs: tt.tickInterval})
+			if _, err := sim.Step(5); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			other.SendMessage(p.ID(), []byte("hi"))
+			if _, err := sim.Step(5); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+
+			r.mtx.Lock()
+			frames := r.frames
+			r.mtx.Unlock()
+			byType := map[byte][][]byte{}
+			for _, f := range frames {
+				byType[f[0]] = append(byType[f[0]], f)
+			}
+			if n := len(byType[meshpeer.BinaryTick]); n != tt.wantTicks {
+				t.Errorf("%v ticks, want %v", n, tt.wantTicks)
+			}
+			id := append([]byte{byte(len(other.GetMyID()))}, other.GetMyID()...)
+			if got, want := byType[meshpeer.BinaryPeerAppeared], meshpeer.EncodeBinaryFrame(meshpeer.BinaryPeerAppeared, id); len(got) != 1 || !bytes.Equal(got[0], want) {
+				t.Errorf("appeared frames = %q, want %q", got, want)
+			}
+			if got, want := byType[meshpeer.BinaryReceive], meshpeer.EncodeBinaryFrame(meshpeer.BinaryReceive, id, []byte("hi")); len(got) != 1 || !bytes.Equal(got[0], want) {
+				t.Errorf("receive frames = %q, want %q", got, want)
+			}
+		})
+	}
+}
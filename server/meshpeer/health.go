@@ -0,0 +1,21 @@
+package meshpeer
+
+// Peer health states
+const (
+	PeerHealthOK       = "ok"
+	PeerHealthErroring = "erroring"
+	PeerHealthDisabled = "disabled"
+)
+
+// PeerHealth describes whether peer code runs without failures
+type PeerHealth struct {
+	State       string
+	ErrorCount  int
+	LastError   string
+	LastErrorTS NetworkTime
+}
+
+// HealthReporter is optionally implemented by MeshAPI providers which want to know peer health
+type HealthReporter interface {
+	ReportHealth(PeerHealth)
+}
@@ -0,0 +1,79 @@
+package meshpeer_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func TestJSPeerHealth(t *testing.T) {
+	tests := []struct {
+		name      string
+		failing   int
+		maxErrors int
+		steps     int
+		wantState string
+		wantCount int
+		disabled  int
+	}{
+		{"no exceptions", 0, 0, 3, meshpeer.PeerHealthOK, 0, 0},
+		{"erroring", 100, 0, 3, meshpeer.PeerHealthErroring, 3, 0},
+		{"disabled after MaxErrors", 100, 2, 5, meshpeer.PeerHealthDisabled, 2, 1},
+		{"below MaxErrors", 1, 2, 5, meshpeer.PeerHealthErroring, 1, 0},
+		// jsErrorWindow is 10 seconds, 500 ticks of 20ms
+		{"recovers after error window", 1, 0, 600, meshpeer.PeerHealthOK, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := meshsim.New(meshlog.Discard())
+			defer sim.Stop()
+			sim.Pause()
+			api, frontendAPI := sim.AddActor(sim.Params().DefaultCoord, nil)
+			disabled := 0
+			script := fmt.Sprintf(`var n = 0;
+				meshAPI.registerTimeTickHandler(function (ts) {
+					n++;
+					if (n <= %v) { throw new Error("boom " + n); }
+				});`, tt.failing)
+			p, err := meshpeer.NewJSPeer(script, log.New(ioutil.Discard, "", 0), api, frontendAPI, meshpeer.JSPeerOptions{
+				MaxErrors:  tt.maxErrors,
+				OnDisabled: func() { disabled++ },
+			})
+			if err != nil {
+				t.Fatalf("NewJSPeer: %v", err)
+			}
+			if _, err := sim.Step(tt.steps); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+
+			info, _ := sim.GetActor(api.GetMyID())
+			if info.Health == nil {
+				t.Fatal("health is not reported")
+			}
+			if h := *info.Health; h.State != tt.wantState || h.ErrorCount != tt.wantCount {
+				t.Errorf("health = %+v, want %v with %v errors", h, tt.wantState, tt.wantCount)
+			}
+			if tt.wantCount > 0 && !strings.Contains(info.Health.LastError, fmt.Sprintf("boom %v", tt.wantCount)) {
+				t.Errorf("last error = %q", info.Health.LastError)
+			}
+			if disabled != tt.disabled {
+				t.Errorf("OnDisabled called %v times, want %v", disabled, tt.disabled)
+			}
+			errors := 0
+			for _, e := range p.Logs(0) {
+				if e.Level == "error" {
+					errors++
+				}
+			}
+			if errors != tt.wantCount {
+				t.Errorf("%v exceptions logged, want %v", errors, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package meshpeer
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// DefaultJSLogBufferSize is the number of log entries kept for every JS peer
+const DefaultJSLogBufferSize = 500
+
+// JSLogEntry is a single console call made by a JS peer
+type JSLogEntry struct {
+	Seq     int64
+	Level   string
+	PeerID  NetworkID
+	TS      NetworkTime
+	Message string
+	Args    []interface{}
+}
+
+// jsLogBuffer keeps last log entries of a peer in a ring buffer and passes new ones to subscribers
+type jsLogBuffer struct {
+	mtx     sync.Mutex
+	entries []JSLogEntry
+	start   int
+	nextSeq int64
+
+	subscribers map[chan JSLogEntry]struct{}
+	closed      bool
+}
+
+func newJSLogBuffer(size int) *jsLogBuffer {
+	return &jsLogBuffer{entries: make([]JSLogEntry, 0, size)}
+}
+
+func (b *jsLogBuffer) add(e JSLogEntry) JSLogEntry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.nextSeq++
+	e.Seq = b.nextSeq
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, e)
+	} else if cap(b.entries) > 0 {
+		b.entries[b.start] = e
+		b.start = (b.start + 1) % len(b.entries)
+	}
+	// entries are added from simulation tick, so a subscriber that is not keeping up misses them
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return e
+}
+
+// subscribe returns entries with sequence number greater than seq together with channel
+// receiving later ones, which is closed by unsubscribe or when the peer is closed
+func (b *jsLogBuffer) subscribe(seq int64, size int) ([]JSLogEntry, chan JSLogEntry) {
+	backlog := b.since(seq)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	ch := make(chan JSLogEntry, size)
+	if b.closed {
+		close(ch)
+		return backlog, ch
+	}
+	// entries added between since and here are sent to ch, the subscriber skips duplicates by Seq
+	for i := 0; i < len(b.entries); i++ {
+		if e := b.entries[(b.start+i)%len(b.entries)]; len(backlog) == 0 && e.Seq > seq || len(backlog) > 0 && e.Seq > backlog[len(backlog)-1].Seq {
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+	if b.subscribers == nil {
+		b.subscribers = map[chan JSLogEntry]struct{}{}
+	}
+	b.subscribers[ch] = struct{}{}
+	return backlog, ch
+}
+
+func (b *jsLogBuffer) unsubscribe(ch chan JSLogEntry) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// close ends all subscriptions, the entries stay readable
+func (b *jsLogBuffer) close() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+}
+
+// since returns entries with sequence number greater than seq, oldest first
+func (b *jsLogBuffer) since(seq int64) []JSLogEntry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	ret := []JSLogEntry{}
+	for i := 0; i < len(b.entries); i++ {
+		e := b.entries[(b.start+i)%len(b.entries)]
+		if e.Seq > seq {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+func (th *JSPeer) formatLogArgs(args []goja.Value) (string, []interface{}) {
+	parts := []string{}
+	exported := []interface{}{}
+	for _, a := range args {
+		v := a.Export()
+		if _, isFunc := goja.AssertFunction(a); isFunc {
+			v = a.String()
+		} else if _, err := json.Marshal(v); err != nil {
+			v = a.String()
+		}
+		exported = append(exported, v)
+
+		if s, ok := v.(string); ok {
+			parts = append(parts, s)
+		} else if b, err := json.Marshal(v); err == nil {
+			parts = append(parts, string(b))
+		} else {
+			parts = append(parts, a.String())
+		}
+	}
+	return strings.Join(parts, " "), exported
+}
+
+func (th *JSPeer) consoleFunc(level string) func(goja.FunctionCall) goja.Value {
+	return func(args goja.FunctionCall) goja.Value {
+		msg, exported := th.formatLogArgs(args.Arguments)
+		th.log(JSLogEntry{
+			Level:   level,
+			PeerID:  th.id,
+			TS:      th.currentTS,
+			Message: msg,
+			Args:    exported,
+		}, level)
+		return goja.Undefined()
+	}
+}
+
+// log captures entry and passes it to OnLog or prints it with given tag
+func (th *JSPeer) log(e JSLogEntry, tag string) {
+	e = th.logs.add(e)
+	if th.options.OnLog != nil {
+		th.options.OnLog(e)
+		return
+	}
+	th.logger.Printf("[%v] [%v] [%.3f] %v", tag, e.PeerID, float64(e.TS)/1000000, e.Message)
+}
+
+func (th *JSPeer) setupConsole() {
+	console := th.jsRuntime.NewObject()
+	for _, level := range []string{"log", "info", "warn", "error", "debug"} {
+		console.Set(level, th.consoleFunc(level))
+	}
+	th.jsRuntime.Set("console", console)
+	th.jsRuntime.Set("log", th.consoleFunc("log"))
+}
+
+// Logs returns captured console entries with sequence number greater than seq
+func (th *JSPeer) Logs(seq int64) []JSLogEntry {
+	return th.logs.since(seq)
+}
+
+// SubscribeLogs returns captured console entries with sequence number greater than seq and
+// channel receiving entries captured later, up to size of them may wait to be read, the rest is
+// dropped. The channel is closed by cancel or when the peer is closed
+func (th *JSPeer) SubscribeLogs(seq int64, size int) (backlog []JSLogEntry, entries <-chan JSLogEntry, cancel func()) {
+	backlog, ch := th.logs.subscribe(seq, size)
+	return backlog, ch, func() { th.logs.unsubscribe(ch) }
+}
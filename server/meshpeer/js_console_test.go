@@ -0,0 +1,95 @@
+package meshpeer_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func TestJSConsoleFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      string
+		wantLevel string
+		wantMsg   string
+	}{
+		{"strings and numbers", `console.log("a", 1, 2.5, true)`, "log", "a 1 2.5 true"},
+		{"object", `console.warn({x: 1})`, "warn", `{"x":1}`},
+		{"array with global log", `log([1, "b"])`, "log", `[1,"b"]`},
+		{"null", `console.info(null)`, "info", "null"},
+		{"function", `console.error(function f() {})`, "error", "function f() {}"},
+		{"debug without args", `console.debug()`, "debug", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, p := newTestJSPeer(t, tt.call)
+			logs := p.Logs(0)
+			if len(logs) != 1 {
+				t.Fatalf("logs = %+v, want one entry", logs)
+			}
+			if e := logs[0]; e.Level != tt.wantLevel || e.Message != tt.wantMsg || e.Seq != 1 {
+				t.Errorf("entry = %+v, want %v %q", e, tt.wantLevel, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestJSLogsKeepLastEntries(t *testing.T) {
+	const logged = meshpeer.DefaultJSLogBufferSize + 100
+	_, p := newTestJSPeer(t, fmt.Sprintf(`for (var i = 1; i <= %v; i++) { console.log(i); }`, logged))
+	tests := []struct {
+		name      string
+		since     int64
+		wantFirst int64
+		wantCount int
+	}{
+		{"all kept", 0, logged - meshpeer.DefaultJSLogBufferSize + 1, meshpeer.DefaultJSLogBufferSize},
+		{"since kept entry", logged - 10, logged - 9, 10},
+		{"since last", logged, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := p.Logs(tt.since)
+			if len(logs) != tt.wantCount {
+				t.Fatalf("%v entries, want %v", len(logs), tt.wantCount)
+			}
+			for i, e := range logs {
+				if e.Seq != tt.wantFirst+int64(i) {
+					t.Fatalf("entry %v has Seq %v, want %v", i, e.Seq, tt.wantFirst+int64(i))
+				}
+			}
+		})
+	}
+}
+
+func TestJSSubscribeLogs(t *testing.T) {
+	sim := meshsim.New(meshlog.Discard())
+	defer sim.Stop()
+	sim.Pause()
+	api, frontendAPI := sim.AddActor(sim.Params().DefaultCoord, nil)
+	script := `console.log("first"); meshAPI.registerTimeTickHandler(function (ts) { console.log("tick"); });`
+	p, err := meshpeer.NewJSPeer(script, log.New(ioutil.Discard, "", 0), api, frontendAPI, meshpeer.JSPeerOptions{})
+	if err != nil {
+		t.Fatalf("NewJSPeer: %v", err)
+	}
+	backlog, entries, cancel := p.SubscribeLogs(0, 10)
+	if len(backlog) != 1 || backlog[0].Message != "first" {
+		t.Fatalf("backlog = %+v", backlog)
+	}
+	if _, err := sim.Step(1); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if e := <-entries; e.Message != "tick" || e.Seq != 2 {
+		t.Errorf("entry = %+v, want tick", e)
+	}
+	cancel()
+	if _, ok := <-entries; ok {
+		t.Error("subscription is open after cancel")
+	}
+	cancel()
+}
@@ -2,37 +2,89 @@ package meshpeer
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync/atomic"
 
 	"github.com/dop251/goja"
 )
 
 // JSPeer provides environment to run  mesh network peer implemented in JS
 type JSPeer struct {
+	// jsRuntime is never changed after creation, so Stop and Close may come from any goroutine
 	jsRuntime *goja.Runtime
 	logger    *log.Logger
+	script    string
+
+	id        NetworkID
+	currentTS NetworkTime
+	logs      *jsLogBuffer
+
+	options        JSPeerOptions
+	health         PeerHealth
+	healthReporter HealthReporter
+	// stopped and closed are set with atomic operations
+	stopped int32
+	closed  int32
+}
+
+// JSPeerOptions tunes JSPeer behaviour
+type JSPeerOptions struct {
+	// MaxErrors disables the peer after this number of uncaught exceptions, 0 means never
+	MaxErrors int
+	// OnDisabled is called once when the peer gets disabled. It is invoked from inside
+	// a mesh handler, so it must not block on the simulator
+	OnDisabled func()
+	// OnLog receives console calls and uncaught exceptions of the peer, which are printed to
+	// the peer logger without it
+	OnLog func(e JSLogEntry)
 }
 
 // NewJSPeer returns new RPCPeer
-func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI FrontendAPI) (*JSPeer, error) {
+func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI FrontendAPI, options JSPeerOptions) (*JSPeer, error) {
 	ret := &JSPeer{
 		jsRuntime: goja.New(),
 		logger:    logger,
+		script:    jsCode,
+		id:        meshAPI.GetMyID(),
+		logs:      newJSLogBuffer(DefaultJSLogBufferSize),
+		options:   options,
+		health:    PeerHealth{State: PeerHealthOK},
+	}
+	if hr, ok := meshAPI.(HealthReporter); ok {
+		ret.healthReporter = hr
+		hr.ReportHealth(ret.health)
 	}
+	meshAPI.RegisterTimeTickHandler(func(ts NetworkTime) {
+		ret.currentTS = ts
+		ret.updateHealth()
+	})
 	meshAPIObj := ret.jsRuntime.NewObject()
 
 	meshAPIObj.Set("registerMessageHandler", func(args goja.FunctionCall) goja.Value {
-		if len(args.Arguments) != 1 {
+		if len(args.Arguments) != 1 && len(args.Arguments) != 2 {
 			return ret.jsRuntime.ToValue(false)
 		}
 		f, ok := goja.AssertFunction(args.Arguments[0])
 		if !ok {
 			return ret.jsRuntime.ToValue(false)
 		}
+		binary := false
+		if len(args.Arguments) == 2 {
+			if opts, ok := args.Arguments[1].(*goja.Object); ok {
+				if b := opts.Get("binary"); b != nil {
+					binary = b.ToBoolean()
+				}
+			}
+		}
 		meshAPI.RegisterMessageHandler(func(id NetworkID, data NetworkMessage) {
-			if _, err := f(args.This, ret.jsRuntime.ToValue(string(id)), ret.jsRuntime.ToValue(string(data))); err != nil {
-				logger.Println(err.Error())
+			var payload goja.Value
+			if binary {
+				payload = ret.bytesToValue(data)
+			} else {
+				payload = ret.jsRuntime.ToValue(string(data))
 			}
+			ret.call(f, args.This, ret.jsRuntime.ToValue(string(id)), payload)
 		})
 		return ret.jsRuntime.ToValue(true)
 	})
@@ -46,9 +98,7 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 			return ret.jsRuntime.ToValue(false)
 		}
 		meshAPI.RegisterPeerAppearedHandler(func(id NetworkID) {
-			if _, err := f(args.This, ret.jsRuntime.ToValue(string(id))); err != nil {
-				logger.Println(err.Error())
-			}
+			ret.call(f, args.This, ret.jsRuntime.ToValue(string(id)))
 		})
 		return ret.jsRuntime.ToValue(true)
 	})
@@ -62,9 +112,7 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 			return ret.jsRuntime.ToValue(false)
 		}
 		meshAPI.RegisterPeerDisappearedHandler(func(id NetworkID) {
-			if _, err := f(args.This, ret.jsRuntime.ToValue(string(id))); err != nil {
-				logger.Println(err.Error())
-			}
+			ret.call(f, args.This, ret.jsRuntime.ToValue(string(id)))
 		})
 		return ret.jsRuntime.ToValue(true)
 	})
@@ -79,9 +127,9 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 		}
 
 		meshAPI.RegisterTimeTickHandler(func(ts NetworkTime) {
-			if _, err := f(args.This, ret.jsRuntime.ToValue(float64(ts))); err != nil {
-				logger.Println(err.Error())
-			}
+			ret.currentTS = ts
+			ret.updateHealth()
+			ret.call(f, args.This, ret.jsRuntime.ToValue(float64(ts)))
 		})
 		return ret.jsRuntime.ToValue(true)
 	})
@@ -91,11 +139,11 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 	})
 	meshAPIObj.Set("sendMessage", func(args goja.FunctionCall) goja.Value {
 		if len(args.Arguments) != 2 {
-			panic(ret.jsRuntime.ToValue("id as string and data as string are required"))
+			panic(ret.jsRuntime.ToValue("id as string and data as string, ArrayBuffer or Uint8Array are required"))
 		}
 		meshAPI.SendMessage(
 			NetworkID(args.Arguments[0].String()),
-			NetworkMessage(args.Arguments[1].String()),
+			ret.valueToBytes(args.Arguments[1]),
 		)
 		return goja.Undefined()
 	})
@@ -120,9 +168,7 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 		}
 
 		frontendAPI.RegisterUserDataUpdateHandler(func(d FrontendUserDataType) {
-			if _, err := f(args.This, ret.jsRuntime.ToValue(d)); err != nil {
-				logger.Println(err.Error())
-			}
+			ret.call(f, args.This, ret.jsRuntime.ToValue(d))
 		})
 
 		return ret.jsRuntime.ToValue(true)
@@ -142,23 +188,184 @@ func NewJSPeer(jsCode string, logger *log.Logger, meshAPI MeshAPI, frontendAPI F
 
 	ret.jsRuntime.Set("frontendAPI", frontendAPIObj)
 
-	ret.jsRuntime.Set("log", func(args goja.FunctionCall) goja.Value {
-		s := ""
-		for _, a := range args.Arguments {
-			s += a.String() + "\t"
-		}
-		ret.logger.Println("JS log message: ", s)
-		return goja.Undefined()
-	})
+	ret.setupConsole()
+
+	if sp, ok := meshAPI.(StorageProvider); ok {
+		ret.setupStorage(sp.GetStorage())
+	} else {
+		storage, _ := NewKVStorage("", DefaultStorageQuota)
+		ret.setupStorage(storage)
+	}
 
 	_, err := ret.jsRuntime.RunString(jsCode)
 	if err != nil {
 		if jserr, ok := err.(*goja.Exception); ok {
 			logger.Println("JS ERROR: ", jserr.String())
 		} else {
-			logger.Println("ERROR: ", jserr.String())
+			logger.Println("ERROR: ", err.Error())
 		}
 		return nil, err
 	}
+	bindLifecycle(meshAPI, ret)
 	return ret, nil
 }
+
+// Script returns JS code this peer was created from
+func (th *JSPeer) Script() string {
+	return th.script
+}
+
+// SerializeState implements StatefulPeer by calling optional serialize() function of the script.
+// Its result is converted with JSON.stringify, scripts without serialize() have no state
+func (th *JSPeer) SerializeState() (json.RawMessage, error) {
+	if atomic.LoadInt32(&th.closed) != 0 {
+		return nil, nil
+	}
+	serialize, ok := goja.AssertFunction(th.jsRuntime.Get("serialize"))
+	if !ok {
+		return nil, nil
+	}
+	v, err := serialize(goja.Undefined())
+	if err != nil {
+		return nil, err
+	}
+	if goja.IsUndefined(v) {
+		return nil, nil
+	}
+	stringify, _ := goja.AssertFunction(th.jsRuntime.Get("JSON").ToObject(th.jsRuntime).Get("stringify"))
+	str, err := stringify(goja.Undefined(), v)
+	if err != nil {
+		return nil, err
+	}
+	if goja.IsUndefined(str) {
+		return nil, fmt.Errorf("serialize() result is not JSON serialisable")
+	}
+	return json.RawMessage(str.String()), nil
+}
+
+// RestoreState implements StatefulPeer by passing state parsed with JSON.parse to restore()
+// function of the script. It fails if the script has no restore()
+func (th *JSPeer) RestoreState(state json.RawMessage) error {
+	if atomic.LoadInt32(&th.closed) != 0 {
+		return fmt.Errorf("peer is closed")
+	}
+	restore, ok := goja.AssertFunction(th.jsRuntime.Get("restore"))
+	if !ok {
+		return fmt.Errorf("script has no restore() function")
+	}
+	parse, _ := goja.AssertFunction(th.jsRuntime.Get("JSON").ToObject(th.jsRuntime).Get("parse"))
+	v, err := parse(goja.Undefined(), th.jsRuntime.ToValue(string(state)))
+	if err != nil {
+		return err
+	}
+	_, err = restore(goja.Undefined(), v)
+	return err
+}
+
+// jsErrorWindow is how long, in NetworkTime units, a peer stays in erroring state after the last exception
+const jsErrorWindow = NetworkTime(10000000)
+
+// Start implements Lifecycle. Script runs since the peer creation, so there is nothing to do
+func (th *JSPeer) Start() {}
+
+// Stop implements Lifecycle, it interrupts running script and no callbacks are invoked after it.
+// It may be called more than once and after Close
+func (th *JSPeer) Stop() {
+	if atomic.SwapInt32(&th.stopped, 1) == 0 {
+		th.jsRuntime.Interrupt("peer stopped")
+	}
+}
+
+// Close implements Lifecycle, it stops the peer, refuses further state calls and ends log
+// subscriptions. It may be called more than once
+func (th *JSPeer) Close() error {
+	th.Stop()
+	if atomic.SwapInt32(&th.closed, 1) == 0 {
+		th.logs.close()
+	}
+	return nil
+}
+
+// call invokes JS callback unless the peer is disabled or stopped and records uncaught exceptions
+func (th *JSPeer) call(f goja.Callable, this goja.Value, args ...goja.Value) {
+	if atomic.LoadInt32(&th.stopped) != 0 || th.health.State == PeerHealthDisabled {
+		return
+	}
+	if _, err := f(this, args...); err != nil {
+		th.handleError(err)
+	}
+}
+
+func (th *JSPeer) handleError(err error) {
+	msg := err.Error()
+	if jserr, ok := err.(*goja.Exception); ok {
+		msg = jserr.String()
+	}
+	th.log(JSLogEntry{
+		Level:   "error",
+		PeerID:  th.id,
+		TS:      th.currentTS,
+		Message: msg,
+		Args:    []interface{}{msg},
+	}, "exception")
+
+	th.health.ErrorCount++
+	th.health.LastError = msg
+	th.health.LastErrorTS = th.currentTS
+	th.health.State = PeerHealthErroring
+	if th.options.MaxErrors > 0 && th.health.ErrorCount >= th.options.MaxErrors {
+		th.health.State = PeerHealthDisabled
+		th.logger.Printf("[%v] disabled after %v errors", th.id, th.health.ErrorCount)
+		if th.options.OnDisabled != nil {
+			th.options.OnDisabled()
+		}
+	}
+	th.reportHealth()
+}
+
+// updateHealth returns erroring peer back to ok when no exceptions were thrown for a while
+func (th *JSPeer) updateHealth() {
+	if th.health.State == PeerHealthErroring && th.currentTS-th.health.LastErrorTS > jsErrorWindow {
+		th.health.State = PeerHealthOK
+		th.reportHealth()
+	}
+}
+
+func (th *JSPeer) reportHealth() {
+	if th.healthReporter != nil {
+		th.healthReporter.ReportHealth(th.health)
+	}
+}
+
+// valueToBytes converts message payload passed from JS to raw bytes.
+// ArrayBuffer and typed array views (Uint8Array, DataView, ...) are copied as is,
+// anything else is converted to string
+func (th *JSPeer) valueToBytes(v goja.Value) NetworkMessage {
+	if buf, ok := v.Export().(goja.ArrayBuffer); ok {
+		return append(NetworkMessage{}, buf.Bytes()...)
+	}
+	if obj, ok := v.(*goja.Object); ok {
+		if bufVal := obj.Get("buffer"); bufVal != nil {
+			if buf, ok := bufVal.Export().(goja.ArrayBuffer); ok {
+				data := buf.Bytes()
+				offset := int(obj.Get("byteOffset").ToInteger())
+				length := int(obj.Get("byteLength").ToInteger())
+				if offset >= 0 && length >= 0 && offset+length <= len(data) {
+					return append(NetworkMessage{}, data[offset:offset+length]...)
+				}
+			}
+		}
+	}
+	return NetworkMessage(v.String())
+}
+
+// bytesToValue wraps raw message bytes into a JS Uint8Array
+func (th *JSPeer) bytesToValue(data NetworkMessage) goja.Value {
+	buf := th.jsRuntime.NewArrayBuffer(append([]byte{}, data...))
+	arr, err := th.jsRuntime.New(th.jsRuntime.Get("Uint8Array"), th.jsRuntime.ToValue(buf))
+	if err != nil {
+		th.logger.Println(err.Error())
+		return th.jsRuntime.ToValue(buf)
+	}
+	return arr
+}
@@ -0,0 +1,214 @@
+package meshpeer_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func newTestJSPeer(t *testing.T, script string) (*meshsim.Simulator, *meshpeer.JSPeer) {
+	sim := meshsim.New(meshlog.Discard())
+	api, frontendAPI := sim.AddActor(sim.Params().DefaultCoord, nil)
+	p, err := meshpeer.NewJSPeer(script, log.New(ioutil.Discard, "", 0), api, frontendAPI, meshpeer.JSPeerOptions{})
+	if err != nil {
+		t.Fatalf("NewJSPeer: %v", err)
+	}
+	return sim, p
+}
+
+func TestJSPeerCloseIsIdempotent(t *testing.T) {
+	sim, p := newTestJSPeer(t, `console.log("started"); function serialize() { return {a: 1}; }`)
+	_, entries, _ := p.SubscribeLogs(0, 10)
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+	p.Stop()
+
+	if _, ok := <-entries; ok {
+		t.Error("log subscription is open after Close")
+	}
+	if state, err := p.SerializeState(); state != nil || err != nil {
+		t.Errorf("SerializeState after Close = %s, %v", state, err)
+	}
+	if err := p.RestoreState([]byte(`{}`)); err == nil {
+		t.Error("RestoreState after Close succeeded")
+	}
+	// the simulator closes runtimes of removed actors once more
+	sim.RemoveActor(p.Logs(0)[0].PeerID)
+}
+
+func TestJSPeerStopAndCloseConcurrently(t *testing.T) {
+	sim, p := newTestJSPeer(t, `meshAPI.registerTimeTickHandler(function (ts) { for (var i = 0; i < 1000; i++) {} });`)
+	sim.Pause()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			p.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			p.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			sim.Step(1)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAPIDemoRuns(t *testing.T) {
+	script, err := ioutil.ReadFile("api_demo.js")
+	if err != nil {
+		t.Fatalf("read demo: %v", err)
+	}
+	sim := meshsim.New(meshlog.Discard())
+	defer sim.Stop()
+	sim.Pause()
+	peers := []*meshpeer.JSPeer{}
+	for i := 0; i < 2; i++ {
+		api, frontendAPI := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+		p, err := meshpeer.NewJSPeer(string(script), log.New(ioutil.Discard, "", 0), api, frontendAPI, meshpeer.JSPeerOptions{})
+		if err != nil {
+			t.Fatalf("NewJSPeer: %v", err)
+		}
+		peers = append(peers, p)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := sim.Step(1); err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+	}
+
+	for i, p := range peers {
+		received := []string{}
+		for _, e := range p.Logs(0) {
+			if e.Level == "error" {
+				t.Errorf("peer %v: %v", i, e.Message)
+			}
+			if strings.HasPrefix(e.Message, "onMessage:") {
+				received = append(received, fmt.Sprint(e.Args[len(e.Args)-2]))
+			}
+		}
+		sort.Strings(received)
+		if got := strings.Join(received, ","); got != "12,3" {
+			t.Errorf("peer %v received messages of %v bytes, want 12,3", i, got)
+		}
+	}
+}
+
+// newPausedJSPair returns paused simulation with JS peer running script, its ID and Go actor
+// in its range
+func newPausedJSPair(t *testing.T, script string, options meshpeer.JSPeerOptions) (*meshsim.Simulator, meshpeer.NetworkID, meshpeer.MeshAPI) {
+	sim := meshsim.New(meshlog.Discard())
+	t.Cleanup(sim.Stop)
+	sim.Pause()
+	other, _ := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	api, frontendAPI := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	if _, err := meshpeer.NewJSPeer(script, log.New(ioutil.Discard, "", 0), api, frontendAPI, options); err != nil {
+		t.Fatalf("NewJSPeer: %v", err)
+	}
+	return sim, api.GetMyID(), other
+}
+
+func TestJSPeerSendMessagePayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []byte
+	}{
+		{"string", `"héllo"`, []byte("héllo")},
+		{"number", `42`, []byte("42")},
+		{"Uint8Array", `new Uint8Array([1, 2, 255])`, []byte{1, 2, 255}},
+		{"ArrayBuffer", `new Uint8Array([0, 255]).buffer`, []byte{0, 255}},
+		{"subarray", `new Uint8Array([1, 2, 3, 4]).subarray(1, 3)`, []byte{2, 3}},
+		{"DataView", `new DataView(new Uint8Array([9, 8, 7]).buffer, 1)`, []byte{8, 7}},
+		{"empty Uint8Array", `new Uint8Array(0)`, []byte{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, _, other := newPausedJSPair(t, `meshAPI.registerPeerAppearedHandler(function (id) {
+				meshAPI.sendMessage(id, `+tt.payload+`);
+			});`, meshpeer.JSPeerOptions{})
+			received := make(chan meshpeer.NetworkMessage, 1)
+			other.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+				received <- data
+			})
+			for i := 0; i < 2; i++ {
+				if _, err := sim.Step(1); err != nil {
+					t.Fatalf("Step: %v", err)
+				}
+			}
+			select {
+			case data := <-received:
+				if string(data) != string(tt.want) {
+					t.Errorf("received %v, want %v", []byte(data), tt.want)
+				}
+			default:
+				t.Fatal("nothing received")
+			}
+		})
+	}
+}
+
+func TestJSPeerReceivedPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		data    []byte
+		want    string
+	}{
+		{"text", `{}`, []byte("héllo"), `string héllo`},
+		{"text without options", ``, []byte("hi"), `string hi`},
+		{"binary", `{binary: true}`, []byte{0, 255, 1}, `Uint8Array 0,255,1`},
+		{"binary text", `{binary: true}`, []byte("hi"), `Uint8Array 104,105`},
+		{"binary empty", `{binary: true}`, []byte{}, `Uint8Array `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlerOptions := ""
+			if tt.options != "" {
+				handlerOptions = ", " + tt.options
+			}
+			logs := make(chan string, 1)
+			options := meshpeer.JSPeerOptions{OnLog: func(e meshpeer.JSLogEntry) { logs <- e.Message }}
+			sim, id, other := newPausedJSPair(t, `meshAPI.registerMessageHandler(function (id, data) {
+				if (typeof data === "string") {
+					console.log("string " + data);
+				} else {
+					console.log(data.constructor.name + " " + Array.prototype.join.call(data, ","));
+				}
+			}`+handlerOptions+`);`, options)
+			if _, err := sim.Step(1); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			other.SendMessage(id, tt.data)
+			if _, err := sim.Step(1); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			select {
+			case msg := <-logs:
+				if msg != tt.want {
+					t.Errorf("handler got %q, want %q", msg, tt.want)
+				}
+			default:
+				t.Fatal("handler is not called")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package meshpeer
+
+import (
+	"github.com/dop251/goja"
+)
+
+func (th *JSPeer) setupStorage(storage Storage) {
+	storageObj := th.jsRuntime.NewObject()
+
+	storageObj.Set("get", func(args goja.FunctionCall) goja.Value {
+		if len(args.Arguments) < 1 {
+			panic(th.jsRuntime.ToValue("key is required"))
+		}
+		v, ok := storage.Get(args.Arguments[0].String())
+		if !ok {
+			return goja.Undefined()
+		}
+		if len(args.Arguments) > 1 {
+			if opts, ok := args.Arguments[1].(*goja.Object); ok {
+				if b := opts.Get("binary"); b != nil && b.ToBoolean() {
+					return th.bytesToValue(v)
+				}
+			}
+		}
+		return th.jsRuntime.ToValue(string(v))
+	})
+
+	storageObj.Set("set", func(args goja.FunctionCall) goja.Value {
+		if len(args.Arguments) != 2 {
+			panic(th.jsRuntime.ToValue("key and value as string, ArrayBuffer or Uint8Array are required"))
+		}
+		if err := storage.Set(args.Arguments[0].String(), th.valueToBytes(args.Arguments[1])); err != nil {
+			panic(th.jsRuntime.NewGoError(err))
+		}
+		return goja.Undefined()
+	})
+
+	storageObj.Set("delete", func(args goja.FunctionCall) goja.Value {
+		if len(args.Arguments) != 1 {
+			panic(th.jsRuntime.ToValue("key is required"))
+		}
+		if err := storage.Delete(args.Arguments[0].String()); err != nil {
+			panic(th.jsRuntime.NewGoError(err))
+		}
+		return goja.Undefined()
+	})
+
+	storageObj.Set("keys", func(goja.FunctionCall) goja.Value {
+		return th.jsRuntime.ToValue(storage.Keys())
+	})
+
+	th.jsRuntime.Set("storage", storageObj)
+}
@@ -0,0 +1,24 @@
+package meshpeer
+
+// Lifecycle is implemented by peer runtimes holding goroutines or interpreters. Start is called
+// once the runtime is bound to its actor, Stop when the actor leaves simulation or gets another
+// runtime, and Close right after Stop to release resources. Stop is called from inside
+// simulation, so it must not block on it
+type Lifecycle interface {
+	Start()
+	Stop()
+	Close() error
+}
+
+// LifecycleBinder is optionally implemented by MeshAPI providers which manage peer runtimes
+type LifecycleBinder interface {
+	// BindLifecycle attaches runtime to the actor, previously bound runtime is stopped and closed
+	BindLifecycle(l Lifecycle)
+}
+
+// bindLifecycle attaches peer runtime to api if it manages lifecycles
+func bindLifecycle(api MeshAPI, l Lifecycle) {
+	if b, ok := api.(LifecycleBinder); ok {
+		b.BindLifecycle(l)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"sync"
 )
 
 type pkgStateUpdate struct {
@@ -78,8 +79,10 @@ type peerState struct {
 	UpdateTS  NetworkTime
 }
 
-// SimplePeer1 provides simplest flood peer strategy
+// SimplePeer1 provides simplest flood peer strategy. Its handlers are called by simulation
+// tick while SetState may be called from anywhere, so its state is guarded by mtx
 type SimplePeer1 struct {
+	mtx     sync.Mutex
 	api     MeshAPI
 	logger  *log.Logger
 	Label   string
@@ -217,7 +220,7 @@ func (th *SimplePeer1) handleTimeTick(ts NetworkTime) {
 
 	if th.currentTS > th.nextSendTime {
 		th.nextSendTime = th.currentTS + NetworkTime(3000000+rand.Int63n(5000000))
-		th.SetState(PeerUserState{Message: fmt.Sprintf("%v says %v", th.Label, th.currentTS/1000)})
+		th.setState(PeerUserState{Message: fmt.Sprintf("%v says %v", th.Label, th.currentTS/1000)})
 	}
 }
 
@@ -231,15 +234,23 @@ func NewSimplePeer1(label string, logger *log.Logger, api MeshAPI) *SimplePeer1
 		meshNetworkState: make(map[NetworkID]peerState),
 	}
 	api.RegisterMessageHandler(func(id NetworkID, data NetworkMessage) {
+		ret.mtx.Lock()
+		defer ret.mtx.Unlock()
 		ret.handleMessage(id, data)
 	})
 	api.RegisterPeerAppearedHandler(func(id NetworkID) {
+		ret.mtx.Lock()
+		defer ret.mtx.Unlock()
 		ret.handleAppearedPeer(id)
 	})
 	api.RegisterPeerDisappearedHandler(func(id NetworkID) {
+		ret.mtx.Lock()
+		defer ret.mtx.Unlock()
 		ret.handleDisappearedPeer(id)
 	})
 	api.RegisterTimeTickHandler(func(ts NetworkTime) {
+		ret.mtx.Lock()
+		defer ret.mtx.Unlock()
 		ret.handleTimeTick(ts)
 	})
 
@@ -248,6 +259,14 @@ func NewSimplePeer1(label string, logger *log.Logger, api MeshAPI) *SimplePeer1
 
 // SetState updates this peer user data
 func (th *SimplePeer1) SetState(p PeerUserState) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.setState(p)
+}
+
+// setState updates this peer user data, th.mtx must be held
+func (th *SimplePeer1) setState(p PeerUserState) {
 	th.meshNetworkState[th.api.GetMyID()] = peerState{
 		UserState: p,
 		UpdateTS:  th.currentTS,
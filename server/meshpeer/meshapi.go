@@ -1,5 +1,10 @@
 package meshpeer
 
+import (
+	"fmt"
+	"math"
+)
+
 // NetworkMessage is the lowest level mesh network data package
 type NetworkMessage []byte
 
@@ -19,3 +24,29 @@ type MeshAPI interface {
 	RegisterTimeTickHandler(func(ts NetworkTime))
 	SendDebugData(interface{})
 }
+
+// LocationAPI is optionally implemented by MeshAPI providers which let peer know and change its position
+type LocationAPI interface {
+	GetPosition() [2]float64
+	MoveTo(coord [2]float64)
+}
+
+// ValidateCoord checks that coord is a place on Earth, remote clients must not move peers
+// anywhere else. 0,0 is rejected as well: it is what clients send when they forget to set it
+func ValidateCoord(coord [2]float64) error {
+	for _, v := range coord {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("coordinate %v,%v is not a number", coord[0], coord[1])
+		}
+	}
+	if coord[0] < -90 || coord[0] > 90 {
+		return fmt.Errorf("latitude %v is out of -90..90", coord[0])
+	}
+	if coord[1] < -180 || coord[1] > 180 {
+		return fmt.Errorf("longitude %v is out of -180..180", coord[1])
+	}
+	if coord == [2]float64{} {
+		return fmt.Errorf("coordinate 0,0 is not allowed, omit it to use the default place")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package meshpeer_test
+
+import (
+	"math"
+	"testing"
+
+	"mesh-simulator/meshpeer"
+)
+
+func TestValidateCoord(t *testing.T) {
+	tests := []struct {
+		name    string
+		coord   [2]float64
+		wantErr bool
+	}{
+		{"place", [2]float64{53.904153, 27.556925}, false},
+		{"poles and antimeridian", [2]float64{-90, 180}, false},
+		{"NaN latitude", [2]float64{math.NaN(), 27.5}, true},
+		{"infinite longitude", [2]float64{53.9, math.Inf(1)}, true},
+		{"latitude over 90", [2]float64{90.5, 27.5}, true},
+		{"longitude under -180", [2]float64{53.9, -180.5}, true},
+		{"zero", [2]float64{0, 0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := meshpeer.ValidateCoord(tt.coord); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCoord(%v) = %v, want error %v", tt.coord, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
-log('started');
+console.log('started');
 
 var myId = meshAPI.getMyID();
-log('my ID:', myId);
+console.log('my ID:', myId);
 
 var currentTS = 0;
 var meshNetworkState = {};
@@ -156,3 +156,13 @@ meshAPI.registerTimeTickHandler(function(ts) {
 });
 
 frontendAPI.registerUserDataUpdateHandler(handleUserData); // This will be called from frontend
+
+// serialize and restore keep synced network state in simulation snapshots
+function serialize() {
+    return {meshNetworkState: meshNetworkState};
+}
+
+function restore(state) {
+    meshNetworkState = state.meshNetworkState || {};
+    sendFrontendUpdate();
+}
@@ -0,0 +1,67 @@
+package meshpeer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// PeerFactory creates built-in Go peer on top of given API. config is the JSON blob passed on
+// peer creation, it may be empty. The actor may be ticking already, so the factory must set up
+// peer state before registering handlers, which then guard it themselves
+type PeerFactory func(api MeshAPI, logger *log.Logger, config json.RawMessage) (interface{}, error)
+
+var (
+	peerTypesMtx sync.RWMutex
+	peerTypes    = map[string]PeerFactory{}
+)
+
+// RegisterPeerType makes built-in peer implementation available under given type name
+func RegisterPeerType(name string, factory PeerFactory) {
+	peerTypesMtx.Lock()
+	defer peerTypesMtx.Unlock()
+
+	peerTypes[name] = factory
+}
+
+// PeerTypes returns sorted names of registered built-in peer types
+func PeerTypes() []string {
+	peerTypesMtx.RLock()
+	defer peerTypesMtx.RUnlock()
+
+	ret := []string{}
+	for name := range peerTypes {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// NewPeerOfType creates built-in peer of registered type
+func NewPeerOfType(name string, api MeshAPI, logger *log.Logger, config json.RawMessage) (interface{}, error) {
+	peerTypesMtx.RLock()
+	factory, ok := peerTypes[name]
+	peerTypesMtx.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown peer type %q", name)
+	}
+	return factory(api, logger, config)
+}
+
+func init() {
+	RegisterPeerType("SimplePeer1", func(api MeshAPI, logger *log.Logger, config json.RawMessage) (interface{}, error) {
+		type simplePeer1Config struct {
+			Label string
+		}
+		conf := simplePeer1Config{Label: string(api.GetMyID())}
+		if len(config) > 0 {
+			if err := json.Unmarshal(config, &conf); err != nil {
+				return nil, fmt.Errorf("bad SimplePeer1 config: %v", err)
+			}
+		}
+		return NewSimplePeer1(conf.Label, logger, api), nil
+	})
+}
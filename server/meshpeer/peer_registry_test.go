@@ -0,0 +1,77 @@
+package meshpeer_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func TestNewPeerOfType(t *testing.T) {
+	tests := []struct {
+		name      string
+		peerType  string
+		config    string
+		wantLabel string
+		wantErr   string
+	}{
+		{"default label is peer ID", "SimplePeer1", "", "<id>", ""},
+		{"label from config", "SimplePeer1", `{"Label": "gate"}`, "gate", ""},
+		{"bad config", "SimplePeer1", `{"Label": 1}`, "", "bad SimplePeer1 config"},
+		{"unknown type", "NoSuchPeer", "", "", "unknown peer type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := meshsim.New(meshlog.Discard())
+			api, _ := sim.AddActor(sim.Params().DefaultCoord, nil)
+			peer, err := meshpeer.NewPeerOfType(tt.peerType, api, log.New(ioutil.Discard, "", 0), json.RawMessage(tt.config))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewPeerOfType: %v", err)
+			}
+			want := strings.Replace(tt.wantLabel, "<id>", string(api.GetMyID()), 1)
+			if label := peer.(*meshpeer.SimplePeer1).Label; label != want {
+				t.Errorf("label = %q, want %q", label, want)
+			}
+		})
+	}
+}
+
+func TestSimplePeer1SetStateWhileTicking(t *testing.T) {
+	sim := meshsim.New(meshlog.Discard())
+	defer sim.Stop()
+	sim.Pause()
+	coord := sim.Params().DefaultCoord
+	peers := []*meshpeer.SimplePeer1{}
+	for i := 0; i < 3; i++ {
+		api, _ := sim.AddActorWithOptions(coord, nil, meshsim.ActorOptions{Exact: true})
+		peers = append(peers, meshpeer.NewSimplePeer1(string(api.GetMyID()), log.New(ioutil.Discard, "", 0), api))
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := sim.Step(1); err != nil {
+				t.Errorf("Step: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		peers[i%len(peers)].SetState(meshpeer.PeerUserState{Message: "state"})
+	}
+	wg.Wait()
+}
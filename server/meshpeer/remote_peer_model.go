@@ -2,19 +2,58 @@ package meshpeer
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"sort"
+	"sync"
 )
 
+// RPCProtocol selects wire format of RPCPeer
+type RPCProtocol int
+
+// Supported RPC protocols
+const (
+	// RPCProtocolJSONRPC is JSON-RPC 2.0, see static/rpc/ws_rpc.v2.schema.json
+	RPCProtocolJSONRPC RPCProtocol = iota
+	// RPCProtocolLegacy is the original {Cmd, Args} format, see static/rpc/ws_rpc.v1.schema.json
+	RPCProtocolLegacy
+)
+
+// RPCProtocolVersion is the version of the /ws_rpc protocol
+const RPCProtocolVersion = "2.0"
+
+// RPCLegacyProtocolVersion is the version of the legacy /ws_rpc protocol
+const RPCLegacyProtocolVersion = "1.0"
+
 // RPCPeer provides RPC controllable mesh network peer
 type RPCPeer struct {
-	api    MeshAPI
-	in     chan []byte
-	out    chan []byte
-	logger *log.Logger
+	api         MeshAPI
+	frontendAPI FrontendAPI
+	in          chan []byte
+	out         chan []byte
+	logger      *log.Logger
+	codec       rpcCodec
+	queue       *rpcOutQueue
+	done        chan struct{}
+	closing     chan struct{}
+	closeOnce   sync.Once
+
+	mtx           sync.Mutex
+	currentPeers  map[NetworkID]struct{}
+	currentTS     NetworkTime
+	subscriptions map[string]*rpcSubscriptionState
 }
 
 // HandleAppearedPeer implements crowd.MeshActor
 func (th *RPCPeer) handleAppearedPeer(id NetworkID) {
+	th.mtx.Lock()
+	th.currentPeers[id] = struct{}{}
+	send := th.subscribed(RPCEventPeers, th.currentTS)
+	th.mtx.Unlock()
+
+	if !send {
+		return
+	}
 	type apMsg struct {
 		PeerID string
 	}
@@ -23,6 +62,14 @@ func (th *RPCPeer) handleAppearedPeer(id NetworkID) {
 
 // HandleDisappearedPeer implements crowd.MeshActor
 func (th *RPCPeer) handleDisappearedPeer(id NetworkID) {
+	th.mtx.Lock()
+	delete(th.currentPeers, id)
+	send := th.subscribed(RPCEventPeers, th.currentTS)
+	th.mtx.Unlock()
+
+	if !send {
+		return
+	}
 	type disapMsg struct {
 		PeerID string
 	}
@@ -31,114 +78,357 @@ func (th *RPCPeer) handleDisappearedPeer(id NetworkID) {
 
 // HandleMessage implements crowd.MeshActor
 func (th *RPCPeer) handleMessage(id NetworkID, data NetworkMessage) {
+	th.mtx.Lock()
+	send := th.subscribed(RPCEventMessages, th.currentTS)
+	th.mtx.Unlock()
+
+	if !send {
+		return
+	}
 	type rcvMsg struct {
 		PeerID string
-		Data   string
+		RPCMessageData
 	}
-	th.sendRPC("didReceiveFromPeer", rcvMsg{string(id), string(data)})
+	th.sendRPC("didReceiveFromPeer", rcvMsg{string(id), NewRPCMessageData(data)})
 }
 
 func (th *RPCPeer) handleTimeTick(ts NetworkTime) {
-	type tickMsg struct {
-		TS int64
+	th.mtx.Lock()
+	th.currentTS = ts
+	sendTick := th.subscribed(RPCEventTick, ts)
+	sendPosition := th.subscribed(RPCEventPosition, ts)
+	th.mtx.Unlock()
+
+	if sendTick {
+		type tickMsg struct {
+			TS int64
+		}
+		th.sendRPC("tick", tickMsg{int64(ts)})
+	}
+	if loc, ok := th.api.(LocationAPI); ok && sendPosition {
+		type posMsg struct {
+			TS    int64
+			Coord [2]float64
+		}
+		th.sendRPC("positionUpdate", posMsg{int64(ts), loc.GetPosition()})
+	}
+}
+
+func (th *RPCPeer) handleUserData(d FrontendUserDataType) {
+	th.mtx.Lock()
+	send := th.subscribed(RPCEventUserData, th.currentTS)
+	th.mtx.Unlock()
+
+	if !send {
+		return
+	}
+	type userDataMsg struct {
+		Data FrontendUserDataType
 	}
-	th.sendRPC("tick", tickMsg{int64(ts)})
+	th.sendRPC("userDataUpdate", userDataMsg{d})
 }
 
 func (th *RPCPeer) sendRPC(cmd string, args interface{}) {
-	c := rpcCommand{}
-	c.Cmd = cmd
-	c.SetData(args)
-	th.out <- c.Serialise()
+	th.queue.push(rpcOutItem{isTick: cmd == "tick", periodic: cmd == "tick" || cmd == "positionUpdate", data: th.codec.event(cmd, args)})
 }
 
-func (th *RPCPeer) sendAnswer(ok bool, args interface{}, err error) {
-	type outputStructure struct {
-		Ok    bool
-		Error string
-		Args  interface{}
+// pump moves queued output to the out channel, so a slow client never blocks the simulation
+func (th *RPCPeer) pump() {
+	for {
+		select {
+		case <-th.done:
+			return
+		case <-th.queue.notify:
+		}
+		for {
+			msg, ok := th.queue.pop()
+			if !ok {
+				break
+			}
+			select {
+			case th.out <- msg:
+			case <-th.done:
+				return
+			}
+		}
 	}
-	o := outputStructure{}
-	o.Ok = ok
-	o.Args = args
-	if err != nil {
-		o.Error = err.Error()
+}
+
+// Notify sends custom event to the client
+func (th *RPCPeer) Notify(method string, params interface{}) {
+	th.sendRPC(method, params)
+}
+
+// Overflowed is closed when client gets disconnected by RPCOverflowDisconnect policy
+func (th *RPCPeer) Overflowed() <-chan struct{} {
+	return th.queue.overflow
+}
+
+// SetDetached tells whether the client is away and may come back. Meanwhile tick and position
+// events are dropped, other output is kept and RPCOverflowDisconnect policy does not apply
+func (th *RPCPeer) SetDetached(detached bool) {
+	th.queue.setDetached(detached)
+}
+
+// Stats returns output queue statistics
+func (th *RPCPeer) Stats() RPCQueueStats {
+	return th.queue.getStats()
+}
+
+func (th *RPCPeer) currentPeersList() []string {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	ret := []string{}
+	for id := range th.currentPeers {
+		ret = append(ret, string(id))
 	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (th *RPCPeer) handleCommand(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "getVersion":
+		type versionMsg struct {
+			Version string
+		}
+		return versionMsg{th.codec.version()}, nil
+
+	case "getMyID":
+		type idMsg struct {
+			PeerID string
+		}
+		return idMsg{string(th.api.GetMyID())}, nil
 
-	b, _ := json.Marshal(o)
-	th.out <- b
+	case "getPeers":
+		type peersMsg struct {
+			Peers []string
+		}
+		return peersMsg{th.currentPeersList()}, nil
+
+	case "getTime":
+		th.mtx.Lock()
+		defer th.mtx.Unlock()
+		type tickMsg struct {
+			TS int64
+		}
+		return tickMsg{int64(th.currentTS)}, nil
+
+	case "getPosition":
+		loc, ok := th.api.(LocationAPI)
+		if !ok {
+			return nil, &RPCError{rpcNotSupported, "position is not supported", nil}
+		}
+		type posMsg struct {
+			Coord [2]float64
+		}
+		return posMsg{loc.GetPosition()}, nil
+
+	case "subscribe":
+		type subMsg struct {
+			Event      string
+			IntervalMs int64
+		}
+		args := &subMsg{}
+		if err := parseParams(params, &args); err != nil {
+			return nil, err
+		}
+		return nil, th.setSubscription(args.Event, RPCSubscription{Enabled: true, IntervalMs: args.IntervalMs})
+
+	case "unsubscribe":
+		type unsubMsg struct {
+			Event string
+		}
+		args := &unsubMsg{}
+		if err := parseParams(params, &args); err != nil {
+			return nil, err
+		}
+		return nil, th.setSubscription(args.Event, RPCSubscription{Enabled: false})
+
+	case "getSubscriptions":
+		type subsMsg struct {
+			Subscriptions map[string]RPCSubscription
+		}
+		return subsMsg{th.getSubscriptions()}, nil
+
+	case "sendToPeer":
+		type msgSend struct {
+			PeerID string
+			RPCMessageData
+		}
+		args := &msgSend{}
+		if err := parseParams(params, &args); err != nil {
+			return nil, err
+		}
+		data, err := args.Message()
+		if err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error(), nil}
+		}
+		th.api.SendMessage(NetworkID(args.PeerID), data)
+		return nil, nil
+
+	case "broadcast":
+		args := &RPCMessageData{}
+		if err := parseParams(params, &args); err != nil {
+			return nil, err
+		}
+		data, err := args.Message()
+		if err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error(), nil}
+		}
+		for _, id := range th.currentPeersList() {
+			th.api.SendMessage(NetworkID(id), data)
+		}
+		return nil, nil
+
+	case "setDebugData":
+		th.api.SendDebugData(params)
+		return nil, nil
+
+	case "setUserData":
+		if th.frontendAPI == nil {
+			return nil, &RPCError{rpcNotSupported, "frontend is not supported", nil}
+		}
+		update := FrontEndUpdateObject{}
+		if err := parseParams(params, &update); err != nil {
+			return nil, err
+		}
+		th.frontendAPI.HandleUpdate(update)
+		return nil, nil
+
+	case "moveTo":
+		loc, ok := th.api.(LocationAPI)
+		if !ok {
+			return nil, &RPCError{rpcNotSupported, "position is not supported", nil}
+		}
+		type posMsg struct {
+			Coord [2]float64
+		}
+		args := &posMsg{}
+		if err := parseParams(params, &args); err != nil {
+			return nil, err
+		}
+		if err := ValidateCoord(args.Coord); err != nil {
+			return nil, &RPCError{rpcInvalidParams, err.Error(), nil}
+		}
+		loc.MoveTo(args.Coord)
+		return nil, nil
+
+	default:
+		th.logger.Printf("WS MESSAGE: %v %s", method, params)
+		return nil, &RPCError{rpcMethodNotFound, fmt.Sprintf("unknown command %q", method), nil}
+	}
 }
 
 func (th *RPCPeer) run() {
-	for msg := range th.in {
-		cmd := rpcCommand{}
-		if cmd.Deserialise(msg) != nil {
-			th.logger.Printf("parsing error: %v", msg)
-		}
-		switch cmd.Cmd {
-		case "sendToPeer":
-			type msgSend struct {
-				PeerID string
-				Data   string
+	defer close(th.done)
+	for {
+		var msg []byte
+		select {
+		case <-th.closing:
+			return
+		case m, ok := <-th.in:
+			if !ok {
+				return
 			}
-			args := &msgSend{}
-			if cmd.GetData(&args) != nil {
-				th.logger.Printf("parsing error: %v", msg)
-				th.sendAnswer(false, nil, nil)
-			} else {
-				th.api.SendMessage(NetworkID(args.PeerID), NetworkMessage(args.Data))
-				th.sendAnswer(true, nil, nil)
+			msg = m
+		}
+		answer := th.codec.process(msg, func(method string, params json.RawMessage) (interface{}, error) {
+			res, err := th.handleCommand(method, params)
+			if err != nil {
+				th.logger.Printf("%v failed: %v", method, err)
 			}
-
-		default:
-			th.logger.Printf("WS MESSAGE: %+v", cmd)
+			return res, err
+		})
+		if answer != nil {
+			th.queue.push(rpcOutItem{data: answer})
 		}
 	}
 }
 
-// NewRPCPeer returns new RPCPeer
-func NewRPCPeer(in chan []byte, out chan []byte, logger *log.Logger, api MeshAPI) *RPCPeer {
+// RPCPeerOptions tunes RPCPeer behaviour
+type RPCPeerOptions struct {
+	Protocol RPCProtocol
+	// QueueSize limits number of messages waiting to be read by client, DefaultRPCQueueSize if 0
+	QueueSize      int
+	OverflowPolicy RPCOverflowPolicy
+	// Subscriptions override DefaultRPCSubscriptions for the new client
+	Subscriptions map[string]RPCSubscription
+}
+
+// NewRPCPeer returns new RPCPeer. It stops when in channel is closed
+func NewRPCPeer(in chan []byte, out chan []byte, logger *log.Logger, api MeshAPI, frontendAPI FrontendAPI, options RPCPeerOptions) *RPCPeer {
 	ret := &RPCPeer{
-		api,
-		in,
-		out,
-		logger,
+		api:           api,
+		frontendAPI:   frontendAPI,
+		in:            in,
+		out:           out,
+		logger:        logger,
+		queue:         newRPCOutQueue(options.QueueSize, options.OverflowPolicy),
+		done:          make(chan struct{}),
+		closing:       make(chan struct{}),
+		currentPeers:  make(map[NetworkID]struct{}),
+		subscriptions: newRPCSubscriptions(options.Subscriptions),
 	}
-	api.RegisterMessageHandler(func(id NetworkID, data NetworkMessage) {
-		ret.handleMessage(id, data)
-	})
-	api.RegisterPeerAppearedHandler(func(id NetworkID) {
-		ret.handleAppearedPeer(id)
-	})
-	api.RegisterPeerDisappearedHandler(func(id NetworkID) {
-		ret.handleDisappearedPeer(id)
-	})
-	api.RegisterTimeTickHandler(func(ts NetworkTime) {
-		ret.handleTimeTick(ts)
-	})
+	if options.Protocol == RPCProtocolLegacy {
+		ret.codec = legacyRPCCodec{}
+	} else {
+		ret.codec = jsonRPCCodec{}
+	}
+	ret.registerHandlers()
 
+	go ret.pump()
 	go ret.run()
+	bindLifecycle(api, ret)
 	return ret
 }
 
-type rpcCommand struct {
-	Cmd  string
-	Args json.RawMessage
-}
+// Start implements Lifecycle
+func (th *RPCPeer) Start() {}
 
-func (th *rpcCommand) SetData(data interface{}) {
-	th.Args, _ = json.Marshal(data)
+// Stop implements Lifecycle
+func (th *RPCPeer) Stop() {}
+
+// Close implements Lifecycle. It stops serving commands and releases peer goroutines,
+// Done gets closed once they exit
+func (th *RPCPeer) Close() error {
+	th.closeOnce.Do(func() {
+		close(th.closing)
+	})
+	return nil
 }
 
-func (th *rpcCommand) GetData(obj interface{}) error {
-	return json.Unmarshal(th.Args, obj)
+// Done is closed when peer stops serving, because in channel got closed or peer was closed
+func (th *RPCPeer) Done() <-chan struct{} {
+	return th.done
 }
 
-func (th *rpcCommand) Serialise() []byte {
-	b, _ := json.Marshal(th)
-	return b
+// Restart registers peer handlers again after simulated device restart dropped them
+func (th *RPCPeer) Restart() {
+	th.mtx.Lock()
+	th.currentPeers = make(map[NetworkID]struct{})
+	th.mtx.Unlock()
+
+	th.registerHandlers()
 }
 
-func (th *rpcCommand) Deserialise(d []byte) error {
-	return json.Unmarshal(d, th)
+func (th *RPCPeer) registerHandlers() {
+	th.api.RegisterMessageHandler(func(id NetworkID, data NetworkMessage) {
+		th.handleMessage(id, data)
+	})
+	th.api.RegisterPeerAppearedHandler(func(id NetworkID) {
+		th.handleAppearedPeer(id)
+	})
+	th.api.RegisterPeerDisappearedHandler(func(id NetworkID) {
+		th.handleDisappearedPeer(id)
+	})
+	th.api.RegisterTimeTickHandler(func(ts NetworkTime) {
+		th.handleTimeTick(ts)
+	})
+	if th.frontendAPI != nil {
+		th.frontendAPI.RegisterUserDataUpdateHandler(func(d FrontendUserDataType) {
+			th.handleUserData(d)
+		})
+	}
 }
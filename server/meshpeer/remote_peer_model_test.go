@@ -0,0 +1,278 @@
+package meshpeer_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// testRPCPeer is RPCPeer of a paused simulation, the test plays its ws_rpc client
+type testRPCPeer struct {
+	t      *testing.T
+	sim    *meshsim.Simulator
+	api    meshpeer.MeshAPI
+	in     chan []byte
+	out    chan []byte
+	nextID int
+}
+
+func newTestRPCPeer(t *testing.T, options meshpeer.RPCPeerOptions) *testRPCPeer {
+	sim := meshsim.New(meshlog.Discard())
+	sim.Pause()
+	api, frontendAPI := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	p := &testRPCPeer{t: t, sim: sim, api: api, in: make(chan []byte), out: make(chan []byte, 16)}
+	peer := meshpeer.NewRPCPeer(p.in, p.out, log.New(ioutil.Discard, "", 0), api, frontendAPI, options)
+	t.Cleanup(func() {
+		close(p.in)
+		<-peer.Done()
+		sim.Stop()
+	})
+	return p
+}
+
+// rpcResponse is JSON-RPC 2.0 response or notification
+type rpcResponse struct {
+	ID     *int
+	Method string
+	Params json.RawMessage
+	Result json.RawMessage
+	Error  *meshpeer.RPCError
+}
+
+// call sends JSON-RPC 2.0 request and returns its response, notifications are skipped
+func (p *testRPCPeer) call(method string, params interface{}) rpcResponse {
+	p.nextID++
+	req, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": p.nextID, "method": method, "params": params})
+	if err != nil {
+		p.t.Fatalf("marshal request: %v", err)
+	}
+	p.in <- req
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.out:
+			resp := rpcResponse{}
+			if err := json.Unmarshal(msg, &resp); err != nil {
+				p.t.Fatalf("bad response %s: %v", msg, err)
+			}
+			if resp.ID != nil && *resp.ID == p.nextID {
+				return resp
+			}
+		case <-deadline:
+			p.t.Fatalf("no response to %v", method)
+		}
+	}
+}
+
+func TestRPCPeerMoveTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  interface{}
+		wantErr bool
+	}{
+		{"valid place", map[string]interface{}{"Coord": []float64{53.91, 27.56}}, false},
+		{"latitude out of range", map[string]interface{}{"Coord": []float64{91, 27.56}}, true},
+		{"longitude out of range", map[string]interface{}{"Coord": []float64{53.91, -181}}, true},
+		{"zero coordinate", map[string]interface{}{"Coord": []float64{0, 0}}, true},
+		{"not a coordinate", map[string]interface{}{"Coord": "here"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{})
+			before := p.api.(meshpeer.LocationAPI).GetPosition()
+
+			resp := p.call("moveTo", tt.params)
+			if gotErr := resp.Error != nil; gotErr != tt.wantErr {
+				t.Fatalf("error = %+v, want error %v", resp.Error, tt.wantErr)
+			}
+			if tt.wantErr {
+				if resp.Error.Code != -32602 {
+					t.Errorf("error code = %v, want invalid params", resp.Error.Code)
+				}
+				if _, err := p.sim.Step(1); err != nil {
+					t.Fatalf("Step: %v", err)
+				}
+				if pos := p.api.(meshpeer.LocationAPI).GetPosition(); pos != before {
+					t.Errorf("peer moved to %v after rejected moveTo", pos)
+				}
+			}
+		})
+	}
+}
+
+// event returns params of the next notification of method, other frames are skipped
+func (p *testRPCPeer) event(method string) json.RawMessage {
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.out:
+			resp := rpcResponse{}
+			if err := json.Unmarshal(msg, &resp); err != nil {
+				p.t.Fatalf("bad frame %s: %v", msg, err)
+			}
+			if resp.Method == method {
+				return resp.Params
+			}
+		case <-deadline:
+			p.t.Fatalf("no %v event", method)
+		}
+	}
+}
+
+func TestRPCPeerMessageData(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"text", []byte(`{"hello": "мир"}`)},
+		{"empty", []byte{}},
+		{"invalid UTF-8", []byte{0xff, 0xfe, 0x00, 'a', 0xc3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{Subscriptions: meshpeer.DefaultRPCSubscriptions()})
+			other, _ := p.sim.AddActorWithOptions(p.sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+			received := make(chan meshpeer.NetworkMessage, 1)
+			other.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+				received <- data
+			})
+			step := func() {
+				if _, err := p.sim.Step(1); err != nil {
+					t.Fatalf("Step: %v", err)
+				}
+			}
+			step()
+
+			// client to peer
+			wire := meshpeer.NewRPCMessageData(tt.data)
+			if resp := p.call("sendToPeer", map[string]interface{}{"PeerID": other.GetMyID(), "Data": wire.Data, "Binary": wire.Binary}); resp.Error != nil {
+				t.Fatalf("sendToPeer: %v", resp.Error)
+			}
+			step()
+			select {
+			case data := <-received:
+				if string(data) != string(tt.data) {
+					t.Errorf("peer received %q, want %q", data, tt.data)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("peer received nothing")
+			}
+
+			// peer to client
+			other.SendMessage(p.api.GetMyID(), tt.data)
+			step()
+			ev := struct {
+				PeerID string
+				meshpeer.RPCMessageData
+			}{}
+			if err := json.Unmarshal(p.event("didReceiveFromPeer"), &ev); err != nil {
+				t.Fatalf("bad didReceiveFromPeer: %v", err)
+			}
+			if ev.Binary == utf8.Valid(tt.data) {
+				t.Errorf("Binary = %v for %q", ev.Binary, tt.data)
+			}
+			if data, err := ev.Message(); err != nil || string(data) != string(tt.data) {
+				t.Errorf("client received %q, %v, want %q", data, err, tt.data)
+			}
+		})
+	}
+}
+
+func TestRPCPeerBadBinaryData(t *testing.T) {
+	p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{})
+	for _, method := range []string{"sendToPeer", "broadcast"} {
+		resp := p.call(method, map[string]interface{}{"PeerID": "x", "Data": "not base64!", "Binary": true})
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("%v error = %+v, want invalid params", method, resp.Error)
+		}
+	}
+}
+
+func TestRPCPeerQueries(t *testing.T) {
+	p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{Subscriptions: meshpeer.DefaultRPCSubscriptions()})
+	other, _ := p.sim.AddActorWithOptions(p.sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+	// the first tick is at time 0, the second one 20ms later
+	if _, err := p.sim.Step(2); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	found := struct{ PeerID string }{}
+	if err := json.Unmarshal(p.event("foundPeer"), &found); err != nil || found.PeerID != string(other.GetMyID()) {
+		t.Errorf("foundPeer = %+v, %v", found, err)
+	}
+	coord, _ := json.Marshal(p.api.(meshpeer.LocationAPI).GetPosition())
+
+	tests := []struct {
+		method  string
+		params  interface{}
+		want    string
+		wantErr int
+	}{
+		{"getVersion", nil, `{"Version":"2.0"}`, 0},
+		{"getMyID", nil, `{"PeerID":"` + string(p.api.GetMyID()) + `"}`, 0},
+		{"getPeers", nil, `{"Peers":["` + string(other.GetMyID()) + `"]}`, 0},
+		{"getTime", nil, `{"TS":20000}`, 0},
+		{"getPosition", nil, `{"Coord":` + string(coord) + `}`, 0},
+		{"setDebugData", map[string]string{"Note": "debug"}, `null`, 0},
+		{"subscribe", map[string]string{"Event": "nothing"}, ``, -32602},
+		{"noSuchMethod", nil, ``, -32601},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			resp := p.call(tt.method, tt.params)
+			if tt.wantErr != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantErr {
+					t.Errorf("error = %+v, want code %v", resp.Error, tt.wantErr)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("error %+v", resp.Error)
+			}
+			if string(resp.Result) != tt.want {
+				t.Errorf("result = %s, want %s", resp.Result, tt.want)
+			}
+		})
+	}
+	if info, _ := p.sim.GetActor(p.api.GetMyID()); info.DebugData == nil {
+		t.Error("debug data is not set")
+	}
+}
+
+func TestRPCPeerBroadcast(t *testing.T) {
+	p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{})
+	received := make(chan meshpeer.NetworkID, 3)
+	for i := 0; i < 3; i++ {
+		other, _ := p.sim.AddActorWithOptions(p.sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+		other.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+			if string(data) == "all" {
+				received <- id
+			}
+		})
+	}
+	if _, err := p.sim.Step(1); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if resp := p.call("broadcast", map[string]string{"Data": "all"}); resp.Error != nil {
+		t.Fatalf("broadcast: %+v", resp.Error)
+	}
+	if _, err := p.sim.Step(1); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-received:
+			if id != p.api.GetMyID() {
+				t.Errorf("message from %v", id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v of 3 peers received broadcast", i)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package meshpeer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+	// implementation defined server errors
+	rpcNotSupported = -32001
+)
+
+// RPCError is an error answered to RPC client
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// RPCMessageData is mesh message in ws_rpc frames. JSON strings cannot carry arbitrary bytes,
+// so valid UTF-8 messages travel as text and the rest are base64 encoded with Binary set
+type RPCMessageData struct {
+	Data   string
+	Binary bool `json:",omitempty"`
+}
+
+// NewRPCMessageData returns ws_rpc form of message
+func NewRPCMessageData(m NetworkMessage) RPCMessageData {
+	if utf8.Valid(m) {
+		return RPCMessageData{Data: string(m)}
+	}
+	return RPCMessageData{base64.StdEncoding.EncodeToString(m), true}
+}
+
+// Message decodes message sent over ws_rpc
+func (d RPCMessageData) Message() (NetworkMessage, error) {
+	if !d.Binary {
+		return NetworkMessage(d.Data), nil
+	}
+	b, err := base64.StdEncoding.DecodeString(d.Data)
+	if err != nil {
+		return nil, fmt.Errorf("binary Data is not base64: %v", err)
+	}
+	return b, nil
+}
+
+func toRPCError(err error) *RPCError {
+	if rpcErr, ok := err.(*RPCError); ok {
+		return rpcErr
+	}
+	return &RPCError{rpcInternalError, err.Error(), nil}
+}
+
+func parseParams(params json.RawMessage, obj interface{}) error {
+	if err := json.Unmarshal(params, obj); err != nil {
+		return &RPCError{rpcInvalidParams, err.Error(), nil}
+	}
+	return nil
+}
+
+type rpcExecutor func(method string, params json.RawMessage) (interface{}, error)
+
+// rpcCodec implements wire format of RPCPeer
+type rpcCodec interface {
+	// process handles incoming frame and returns frame to answer with, or nil
+	process(msg []byte, exec rpcExecutor) []byte
+	// event returns frame notifying client about something happened
+	event(method string, params interface{}) []byte
+	version() string
+}
+
+type rpcCommand struct {
+	ID   json.RawMessage `json:",omitempty"`
+	Cmd  string
+	Args json.RawMessage
+}
+
+func (th *rpcCommand) SetData(data interface{}) {
+	th.Args, _ = json.Marshal(data)
+}
+
+func (th *rpcCommand) GetData(obj interface{}) error {
+	return json.Unmarshal(th.Args, obj)
+}
+
+func (th *rpcCommand) Serialise() []byte {
+	b, _ := json.Marshal(th)
+	return b
+}
+
+func (th *rpcCommand) Deserialise(d []byte) error {
+	return json.Unmarshal(d, th)
+}
+
+// legacyRPCCodec is the original {Cmd, Args} protocol
+type legacyRPCCodec struct{}
+
+func (legacyRPCCodec) version() string {
+	return RPCLegacyProtocolVersion
+}
+
+func (legacyRPCCodec) event(method string, params interface{}) []byte {
+	c := rpcCommand{}
+	c.Cmd = method
+	c.SetData(params)
+	return c.Serialise()
+}
+
+func (legacyRPCCodec) process(msg []byte, exec rpcExecutor) []byte {
+	type outputStructure struct {
+		ID    json.RawMessage `json:",omitempty"`
+		Cmd   string
+		Ok    bool
+		Error string
+		Args  interface{}
+	}
+	cmd := rpcCommand{}
+	o := outputStructure{}
+	if err := cmd.Deserialise(msg); err != nil {
+		o.Error = err.Error()
+	} else {
+		o.ID = cmd.ID
+		o.Cmd = cmd.Cmd
+		res, err := exec(cmd.Cmd, cmd.Args)
+		o.Ok = err == nil
+		o.Args = res
+		if err != nil {
+			o.Error = err.Error()
+		}
+	}
+
+	b, _ := json.Marshal(o)
+	return b
+}
+
+// jsonRPCCodec is JSON-RPC 2.0 with batches support
+type jsonRPCCodec struct{}
+
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (jsonRPCCodec) version() string {
+	return RPCProtocolVersion
+}
+
+func (jsonRPCCodec) event(method string, params interface{}) []byte {
+	p, _ := json.Marshal(params)
+	b, _ := json.Marshal(jsonRPCRequest{JSONRPC: "2.0", Method: method, Params: p})
+	return b
+}
+
+func (c jsonRPCCodec) process(msg []byte, exec rpcExecutor) []byte {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) > 0 && msg[0] == '[' {
+		batch := []json.RawMessage{}
+		if err := json.Unmarshal(msg, &batch); err != nil {
+			return c.errorResponse(nil, &RPCError{rpcParseError, err.Error(), nil})
+		}
+		if len(batch) == 0 {
+			return c.errorResponse(nil, &RPCError{rpcInvalidRequest, "empty batch", nil})
+		}
+		responses := []jsonRPCResponse{}
+		for _, m := range batch {
+			if r := c.processSingle(m, exec); r != nil {
+				responses = append(responses, *r)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		b, _ := json.Marshal(responses)
+		return b
+	}
+
+	r := c.processSingle(msg, exec)
+	if r == nil {
+		return nil
+	}
+	b, _ := json.Marshal(r)
+	return b
+}
+
+func (c jsonRPCCodec) errorResponse(id json.RawMessage, err *RPCError) []byte {
+	b, _ := json.Marshal(jsonRPCResponse{JSONRPC: "2.0", Error: err, ID: nullID(id)})
+	return b
+}
+
+// processSingle returns nil for notifications, which are not answered
+func (jsonRPCCodec) processSingle(msg []byte, exec rpcExecutor) *jsonRPCResponse {
+	req := jsonRPCRequest{}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		if _, isSyntax := err.(*json.SyntaxError); isSyntax {
+			return &jsonRPCResponse{JSONRPC: "2.0", Error: &RPCError{rpcParseError, err.Error(), nil}, ID: nullID(nil)}
+		}
+		return &jsonRPCResponse{JSONRPC: "2.0", Error: &RPCError{rpcInvalidRequest, err.Error(), nil}, ID: nullID(nil)}
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return &jsonRPCResponse{JSONRPC: "2.0", Error: &RPCError{rpcInvalidRequest, "jsonrpc must be \"2.0\" and method is required", nil}, ID: nullID(req.ID)}
+	}
+	res, err := exec(req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return &jsonRPCResponse{JSONRPC: "2.0", Error: toRPCError(err), ID: req.ID}
+	}
+	if res == nil {
+		// result member is required on success
+		res = json.RawMessage("null")
+	}
+	return &jsonRPCResponse{JSONRPC: "2.0", Result: res, ID: req.ID}
+}
+
+func nullID(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -0,0 +1,124 @@
+package meshpeer_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"mesh-simulator/meshpeer"
+)
+
+// exchange sends raw frame followed by request with "sentinel" ID and returns frames answered
+// before the sentinel response
+func (p *testRPCPeer) exchange(frame string, sentinel string) [][]byte {
+	p.in <- []byte(frame)
+	p.in <- []byte(sentinel)
+	ret := [][]byte{}
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-p.out:
+			if bytes.Contains(msg, []byte(`"sentinel"`)) {
+				return ret
+			}
+			ret = append(ret, msg)
+		case <-deadline:
+			p.t.Fatalf("no response to sentinel after %s", frame)
+		}
+	}
+}
+
+// summary describes JSON-RPC 2.0 response as "<id> <error code>" or "<id> <result>"
+func summary(t *testing.T, msg []byte) string {
+	r := struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  json.RawMessage `json:"result"`
+		Error   *meshpeer.RPCError
+	}{}
+	if err := json.Unmarshal(msg, &r); err != nil {
+		t.Fatalf("bad response %s: %v", msg, err)
+	}
+	if r.JSONRPC != "2.0" {
+		t.Errorf("jsonrpc = %q in %s", r.JSONRPC, msg)
+	}
+	if r.Error != nil {
+		return fmt.Sprintf("%s %v", r.ID, r.Error.Code)
+	}
+	return fmt.Sprintf("%s %s", r.ID, r.Result)
+}
+
+func TestJSONRPCCodec(t *testing.T) {
+	const sentinel = `{"jsonrpc": "2.0", "method": "getVersion", "id": "sentinel"}`
+	tests := []struct {
+		name  string
+		frame string
+		// want is summary of every response, batch responses are listed in one string
+		want []string
+	}{
+		{"request", `{"jsonrpc": "2.0", "method": "getVersion", "id": 1}`, []string{`1 {"Version":"2.0"}`}},
+		{"string id", `{"jsonrpc": "2.0", "method": "getVersion", "id": "a"}`, []string{`"a" {"Version":"2.0"}`}},
+		{"null result", `{"jsonrpc": "2.0", "method": "setDebugData", "params": {}, "id": 2}`, []string{`2 null`}},
+		{"notification", `{"jsonrpc": "2.0", "method": "getVersion"}`, []string{}},
+		{"failed notification", `{"jsonrpc": "2.0", "method": "noSuchMethod"}`, []string{}},
+		{"parse error", `{"jsonrpc": "2.0", "method"`, []string{`null -32700`}},
+		{"not an object", `42`, []string{`null -32600`}},
+		{"wrong version", `{"jsonrpc": "1.0", "method": "getVersion", "id": 3}`, []string{`3 -32600`}},
+		{"no method", `{"jsonrpc": "2.0", "id": 4}`, []string{`4 -32600`}},
+		{"unknown method", `{"jsonrpc": "2.0", "method": "noSuchMethod", "id": 5}`, []string{`5 -32601`}},
+		{"bad params", `{"jsonrpc": "2.0", "method": "moveTo", "params": {"Coord": "x"}, "id": 6}`, []string{`6 -32602`}},
+		{"batch", `[{"jsonrpc": "2.0", "method": "getVersion", "id": 7}, {"jsonrpc": "2.0", "method": "getVersion"}, {"jsonrpc": "2.0", "method": "noSuchMethod", "id": 8}, 1]`,
+			[]string{`7 {"Version":"2.0"}`, `8 -32601`, `null -32600`}},
+		{"batch of notifications", `[{"jsonrpc": "2.0", "method": "getVersion"}]`, []string{}},
+		{"empty batch", `[]`, []string{`null -32600`}},
+		{"bad batch", `[{"jsonrpc": "2.0",]`, []string{`null -32700`}},
+	}
+	p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, msg := range p.exchange(tt.frame, sentinel) {
+				if bytes.HasPrefix(msg, []byte("[")) {
+					batch := []json.RawMessage{}
+					if err := json.Unmarshal(msg, &batch); err != nil {
+						t.Fatalf("bad batch response %s: %v", msg, err)
+					}
+					for _, r := range batch {
+						got = append(got, summary(t, r))
+					}
+					continue
+				}
+				got = append(got, summary(t, msg))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("responses = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyRPCCodec(t *testing.T) {
+	const sentinel = `{"ID": "sentinel", "Cmd": "getVersion"}`
+	tests := []struct {
+		name  string
+		frame string
+		want  string
+	}{
+		{"command", `{"ID": 1, "Cmd": "getVersion"}`, `{"ID":1,"Cmd":"getVersion","Ok":true,"Error":"","Args":{"Version":"1.0"}}`},
+		{"without ID", `{"Cmd": "getVersion"}`, `{"Cmd":"getVersion","Ok":true,"Error":"","Args":{"Version":"1.0"}}`},
+		{"unknown command", `{"ID": 2, "Cmd": "noSuchCmd"}`, `{"ID":2,"Cmd":"noSuchCmd","Ok":false,"Error":"unknown command \"noSuchCmd\"","Args":null}`},
+		{"bad JSON", `{"Cmd"`, `{"Cmd":"","Ok":false,"Error":"unexpected end of JSON input","Args":null}`},
+	}
+	p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{Protocol: meshpeer.RPCProtocolLegacy})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.exchange(tt.frame, sentinel)
+			if len(got) != 1 || string(got[0]) != tt.want {
+				t.Errorf("responses = %q, want %v", got, tt.want)
+			}
+		})
+	}
+}
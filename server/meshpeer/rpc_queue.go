@@ -0,0 +1,175 @@
+package meshpeer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RPCOverflowPolicy defines what RPCPeer does when client does not read its output fast enough
+type RPCOverflowPolicy string
+
+// Supported overflow policies
+const (
+	// RPCOverflowDropTicks drops tick events first, then the oldest queued messages
+	RPCOverflowDropTicks RPCOverflowPolicy = "drop_ticks"
+	// RPCOverflowDropOldest drops the oldest queued messages
+	RPCOverflowDropOldest RPCOverflowPolicy = "drop_oldest"
+	// RPCOverflowDisconnect disconnects the client. While the client is detached and may come
+	// back, and until it reads messages queued meanwhile, RPCOverflowDropTicks applies instead
+	RPCOverflowDisconnect RPCOverflowPolicy = "disconnect"
+)
+
+// DefaultRPCQueueSize is the default number of messages queued for a RPC client
+const DefaultRPCQueueSize = 256
+
+// ParseRPCOverflowPolicy validates overflow policy name
+func ParseRPCOverflowPolicy(s string) (RPCOverflowPolicy, error) {
+	switch p := RPCOverflowPolicy(s); p {
+	case RPCOverflowDropTicks, RPCOverflowDropOldest, RPCOverflowDisconnect:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy %q", s)
+}
+
+// RPCQueueStats describes RPC client output queue state
+type RPCQueueStats struct {
+	Policy       RPCOverflowPolicy
+	Size         int
+	Queued       int
+	Sent         int64
+	DroppedTicks int64
+	DroppedOther int64
+	Overflowed   bool
+	MaxQueued    int
+}
+
+type rpcOutItem struct {
+	isTick bool
+	// periodic events are superseded by later ones, so they are not kept for detached client
+	periodic bool
+	data     []byte
+}
+
+// rpcOutQueue is a bounded non-blocking queue between simulation and RPC client connection
+type rpcOutQueue struct {
+	mtx    sync.Mutex
+	items  []rpcOutItem
+	stats  RPCQueueStats
+	notify chan struct{}
+	// detached is set while there is no client reading the queue, catchingUp after the client
+	// comes back until the queue gets empty
+	detached   bool
+	catchingUp bool
+
+	overflow     chan struct{}
+	overflowOnce sync.Once
+}
+
+func newRPCOutQueue(size int, policy RPCOverflowPolicy) *rpcOutQueue {
+	if size <= 0 {
+		size = DefaultRPCQueueSize
+	}
+	if policy == "" {
+		policy = RPCOverflowDropTicks
+	}
+	return &rpcOutQueue{
+		stats:    RPCQueueStats{Policy: policy, Size: size},
+		notify:   make(chan struct{}, 1),
+		overflow: make(chan struct{}),
+	}
+}
+
+func (q *rpcOutQueue) countDropped(it rpcOutItem) {
+	if it.isTick {
+		q.stats.DroppedTicks++
+	} else {
+		q.stats.DroppedOther++
+	}
+}
+
+// push never blocks. When queue is full, overflow policy decides what is lost
+func (q *rpcOutQueue) push(it rpcOutItem) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.stats.Overflowed || q.detached && it.periodic {
+		q.countDropped(it)
+		return
+	}
+	if len(q.items) >= q.stats.Size {
+		policy := q.stats.Policy
+		if policy == RPCOverflowDisconnect && (q.detached || q.catchingUp) {
+			// queue fills while client is away, which must not end its session before grace period
+			policy = RPCOverflowDropTicks
+		}
+		switch policy {
+		case RPCOverflowDisconnect:
+			q.countDropped(it)
+			q.stats.Overflowed = true
+			q.overflowOnce.Do(func() { close(q.overflow) })
+			return
+		case RPCOverflowDropTicks:
+			if it.isTick {
+				q.countDropped(it)
+				return
+			}
+			dropped := false
+			for i, queued := range q.items {
+				if queued.isTick {
+					q.countDropped(queued)
+					q.items = append(q.items[:i], q.items[i+1:]...)
+					dropped = true
+					break
+				}
+			}
+			if !dropped {
+				q.countDropped(q.items[0])
+				q.items = q.items[1:]
+			}
+		default:
+			q.countDropped(q.items[0])
+			q.items = q.items[1:]
+		}
+	}
+	q.items = append(q.items, it)
+	if len(q.items) > q.stats.MaxQueued {
+		q.stats.MaxQueued = len(q.items)
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *rpcOutQueue) setDetached(detached bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.detached = detached
+	q.catchingUp = !detached && len(q.items) > 0
+}
+
+func (q *rpcOutQueue) pop() ([]byte, bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	it := q.items[0]
+	q.items = q.items[1:]
+	q.stats.Sent++
+	if len(q.items) == 0 {
+		q.catchingUp = false
+	}
+	return it.data, true
+}
+
+func (q *rpcOutQueue) getStats() RPCQueueStats {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	ret := q.stats
+	ret.Queued = len(q.items)
+	return ret
+}
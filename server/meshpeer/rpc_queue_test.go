@@ -0,0 +1,115 @@
+package meshpeer
+
+import (
+	"strings"
+	"testing"
+)
+
+// pushAll queues items described by pushes: "t1" is tick, "p1" position update, anything else
+// is a message
+func pushAll(q *rpcOutQueue, pushes string) {
+	for _, name := range strings.Fields(pushes) {
+		q.push(rpcOutItem{isTick: name[0] == 't', periodic: name[0] == 't' || name[0] == 'p', data: []byte(name)})
+	}
+}
+
+// popAll returns queued items separated by spaces
+func popAll(q *rpcOutQueue) string {
+	ret := []string{}
+	for {
+		data, ok := q.pop()
+		if !ok {
+			return strings.Join(ret, " ")
+		}
+		ret = append(ret, string(data))
+	}
+}
+
+func TestRPCOutQueueOverflow(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       RPCOverflowPolicy
+		detached     bool
+		pushes       string
+		want         string
+		droppedTicks int64
+		droppedOther int64
+		overflowed   bool
+	}{
+		{"fits", RPCOverflowDropTicks, false, "m1 t1 m2", "m1 t1 m2", 0, 0, false},
+		{"drop ticks: new tick", RPCOverflowDropTicks, false, "m1 m2 m3 t1", "m1 m2 m3", 1, 0, false},
+		{"drop ticks: queued tick", RPCOverflowDropTicks, false, "m1 t1 m2 m3", "m1 m2 m3", 1, 0, false},
+		{"drop ticks: oldest message without ticks", RPCOverflowDropTicks, false, "m1 m2 m3 m4", "m2 m3 m4", 0, 1, false},
+		{"drop ticks: position is not a tick", RPCOverflowDropTicks, false, "p1 m1 m2 m3", "m1 m2 m3", 0, 1, false},
+		{"drop oldest", RPCOverflowDropOldest, false, "t1 m1 m2 t2 m3", "m2 t2 m3", 1, 1, false},
+		{"disconnect", RPCOverflowDisconnect, false, "m1 m2 m3 m4 m5", "m1 m2 m3", 0, 2, true},
+		{"detached drops periodic events", RPCOverflowDropTicks, true, "t1 m1 p1 m2", "m1 m2", 1, 1, false},
+		{"detached client is not disconnected", RPCOverflowDisconnect, true, "m1 m2 m3 m4", "m2 m3 m4", 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newRPCOutQueue(3, tt.policy)
+			q.setDetached(tt.detached)
+			pushAll(q, tt.pushes)
+			stats := q.getStats()
+			if got := popAll(q); got != tt.want {
+				t.Errorf("queued %q, want %q", got, tt.want)
+			}
+			if stats.DroppedTicks != tt.droppedTicks || stats.DroppedOther != tt.droppedOther || stats.Overflowed != tt.overflowed {
+				t.Errorf("stats = %+v, want %v ticks and %v others dropped, overflowed %v", stats, tt.droppedTicks, tt.droppedOther, tt.overflowed)
+			}
+			select {
+			case <-q.overflow:
+				if !tt.overflowed {
+					t.Error("overflow is signalled")
+				}
+			default:
+				if tt.overflowed {
+					t.Error("overflow is not signalled")
+				}
+			}
+		})
+	}
+}
+
+func TestRPCOutQueueCatchingUp(t *testing.T) {
+	q := newRPCOutQueue(2, RPCOverflowDisconnect)
+	q.setDetached(true)
+	pushAll(q, "m1 m2")
+	q.setDetached(false)
+	// messages queued while the client was away do not disconnect it
+	pushAll(q, "m3")
+	if q.getStats().Overflowed {
+		t.Fatal("client catching up is disconnected")
+	}
+	if got := popAll(q); got != "m2 m3" {
+		t.Errorf("queued %q", got)
+	}
+	// once the queue got empty the policy applies again
+	pushAll(q, "m4 m5 m6")
+	if !q.getStats().Overflowed {
+		t.Error("client is not disconnected after catching up")
+	}
+}
+
+func TestParseRPCOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    RPCOverflowPolicy
+		wantErr bool
+	}{
+		{"drop_ticks", RPCOverflowDropTicks, false},
+		{"drop_oldest", RPCOverflowDropOldest, false},
+		{"disconnect", RPCOverflowDisconnect, false},
+		{"", "", true},
+		{"drop_all", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRPCOverflowPolicy(tt.name)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("ParseRPCOverflowPolicy(%q) = %q, %v", tt.name, got, err)
+			}
+		})
+	}
+}
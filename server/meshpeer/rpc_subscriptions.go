@@ -0,0 +1,107 @@
+package meshpeer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RPC event classes clients can subscribe to
+const (
+	RPCEventTick     = "tick"
+	RPCEventPeers    = "peers"
+	RPCEventMessages = "messages"
+	RPCEventPosition = "position"
+	RPCEventUserData = "userData"
+)
+
+// RPCSubscription describes whether and how often client gets events of some class.
+// IntervalMs is only meaningful for periodic classes (tick and position), 0 means every simulation tick
+type RPCSubscription struct {
+	Enabled    bool
+	IntervalMs int64
+}
+
+// DefaultRPCSubscriptions returns subscriptions of a newly connected client
+func DefaultRPCSubscriptions() map[string]RPCSubscription {
+	return map[string]RPCSubscription{
+		RPCEventTick:     {Enabled: true},
+		RPCEventPeers:    {Enabled: true},
+		RPCEventMessages: {Enabled: true},
+		RPCEventPosition: {Enabled: false, IntervalMs: 1000},
+		RPCEventUserData: {Enabled: true},
+	}
+}
+
+// RPCEventClasses returns sorted names of all event classes
+func RPCEventClasses() []string {
+	ret := []string{}
+	for k := range DefaultRPCSubscriptions() {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+type rpcSubscriptionState struct {
+	RPCSubscription
+	lastSent NetworkTime
+	sentOnce bool
+}
+
+func newRPCSubscriptions(initial map[string]RPCSubscription) map[string]*rpcSubscriptionState {
+	ret := map[string]*rpcSubscriptionState{}
+	for k, v := range DefaultRPCSubscriptions() {
+		ret[k] = &rpcSubscriptionState{RPCSubscription: v}
+	}
+	for k, v := range initial {
+		if st, ok := ret[k]; ok {
+			st.RPCSubscription = v
+		}
+	}
+	return ret
+}
+
+// subscribed tells if event of given class is to be sent. For periodic classes it also
+// accounts the interval. th.mtx must be held
+func (th *RPCPeer) subscribed(class string, ts NetworkTime) bool {
+	st, ok := th.subscriptions[class]
+	if !ok || !st.Enabled {
+		return false
+	}
+	if st.IntervalMs > 0 && st.sentOnce && ts-st.lastSent < NetworkTime(st.IntervalMs*1000) {
+		return false
+	}
+	st.lastSent = ts
+	st.sentOnce = true
+	return true
+}
+
+func (th *RPCPeer) setSubscription(class string, sub RPCSubscription) error {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	st, ok := th.subscriptions[class]
+	if !ok {
+		return &RPCError{rpcInvalidParams, fmt.Sprintf("unknown event class %q", class), RPCEventClasses()}
+	}
+	if sub.IntervalMs < 0 {
+		return &RPCError{rpcInvalidParams, "interval must not be negative", nil}
+	}
+	if class != RPCEventTick && class != RPCEventPosition {
+		sub.IntervalMs = 0
+	}
+	st.RPCSubscription = sub
+	st.sentOnce = false
+	return nil
+}
+
+func (th *RPCPeer) getSubscriptions() map[string]RPCSubscription {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	ret := map[string]RPCSubscription{}
+	for k, v := range th.subscriptions {
+		ret[k] = v.RPCSubscription
+	}
+	return ret
+}
@@ -0,0 +1,100 @@
+package meshpeer_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"mesh-simulator/meshpeer"
+)
+
+// drain returns number of notifications of every method sent to the client so far
+func (p *testRPCPeer) drain() map[string]int {
+	ret := map[string]int{}
+	for _, msg := range p.exchange(`{"jsonrpc": "2.0", "method": "getVersion"}`, `{"jsonrpc": "2.0", "method": "getVersion", "id": "sentinel"}`) {
+		n := struct{ Method string }{}
+		if err := json.Unmarshal(msg, &n); err != nil {
+			p.t.Fatalf("bad frame %s: %v", msg, err)
+		}
+		ret[n.Method]++
+	}
+	return ret
+}
+
+func TestRPCSubscriptionIntervals(t *testing.T) {
+	type sub struct {
+		method string
+		params map[string]interface{}
+	}
+	tests := []struct {
+		name string
+		subs []sub
+		// 10 ticks are made, 20ms each
+		wantTicks     int
+		wantPositions int
+	}{
+		{"defaults", nil, 10, 0},
+		{"tick interval", []sub{{"subscribe", map[string]interface{}{"Event": "tick", "IntervalMs": 100}}}, 2, 0},
+		{"unsubscribed ticks", []sub{{"unsubscribe", map[string]interface{}{"Event": "tick"}}}, 0, 0},
+		{"position default interval", []sub{{"subscribe", map[string]interface{}{"Event": "position", "IntervalMs": 1000}}}, 10, 1},
+		{"position every tick", []sub{{"subscribe", map[string]interface{}{"Event": "position"}}}, 10, 10},
+		{"position interval", []sub{
+			{"unsubscribe", map[string]interface{}{"Event": "tick"}},
+			{"subscribe", map[string]interface{}{"Event": "position", "IntervalMs": 50}},
+		}, 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{Subscriptions: meshpeer.DefaultRPCSubscriptions()})
+			for _, s := range tt.subs {
+				if resp := p.call(s.method, s.params); resp.Error != nil {
+					t.Fatalf("%v: %+v", s.method, resp.Error)
+				}
+			}
+			if _, err := p.sim.Step(10); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			got := p.drain()
+			if got["tick"] != tt.wantTicks || got["positionUpdate"] != tt.wantPositions {
+				t.Errorf("events = %v, want %v ticks and %v positions", got, tt.wantTicks, tt.wantPositions)
+			}
+		})
+	}
+}
+
+func TestRPCSubscriptionRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		params  map[string]interface{}
+		wantErr bool
+		want    meshpeer.RPCSubscription
+	}{
+		{"unknown class", "subscribe", map[string]interface{}{"Event": "weather"}, true, meshpeer.RPCSubscription{}},
+		{"negative interval", "subscribe", map[string]interface{}{"Event": "tick", "IntervalMs": -1}, true, meshpeer.RPCSubscription{Enabled: true}},
+		{"interval of periodic class", "subscribe", map[string]interface{}{"Event": "tick", "IntervalMs": 500}, false, meshpeer.RPCSubscription{Enabled: true, IntervalMs: 500}},
+		{"interval of other class is ignored", "subscribe", map[string]interface{}{"Event": "peers", "IntervalMs": 500}, false, meshpeer.RPCSubscription{Enabled: true}},
+		{"unsubscribe", "unsubscribe", map[string]interface{}{"Event": "messages"}, false, meshpeer.RPCSubscription{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestRPCPeer(t, meshpeer.RPCPeerOptions{})
+			resp := p.call(tt.method, tt.params)
+			if gotErr := resp.Error != nil; gotErr != tt.wantErr {
+				t.Fatalf("error = %+v, want error %v", resp.Error, tt.wantErr)
+			}
+			if tt.wantErr && resp.Error.Code != -32602 {
+				t.Errorf("error code = %v, want invalid params", resp.Error.Code)
+			}
+			subs := struct {
+				Subscriptions map[string]meshpeer.RPCSubscription
+			}{}
+			if err := json.Unmarshal(p.call("getSubscriptions", nil).Result, &subs); err != nil {
+				t.Fatalf("bad getSubscriptions result: %v", err)
+			}
+			if got := subs.Subscriptions[tt.params["Event"].(string)]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subscription = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package meshpeer
+
+import "encoding/json"
+
+// StatefulPeer is optionally implemented by peer runtimes whose state can be saved to simulation
+// snapshot. Both methods are called while simulation is held, so they must not block on it
+type StatefulPeer interface {
+	// SerializeState returns JSON state of the peer, nil if there is nothing to save
+	SerializeState() (json.RawMessage, error)
+	// RestoreState brings freshly created peer to the saved state
+	RestoreState(state json.RawMessage) error
+}
@@ -0,0 +1,213 @@
+package meshpeer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultStorageQuota is the default amount of bytes (keys + values) a peer may keep in storage
+const DefaultStorageQuota = 64 * 1024
+
+// StorageFlushDelay is how long modifications of KVStorage mirrored to a file may stay unsaved.
+// Writes are batched, so a peer changing storage every tick does not turn disk I/O into tick latency
+const StorageFlushDelay = time.Second
+
+// Storage is a persistent key-value storage of a peer. It survives peer reboots
+type Storage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+	Delete(key string) error
+	Keys() []string
+}
+
+// StorageProvider is optionally implemented by MeshAPI providers which give peers persistent storage
+type StorageProvider interface {
+	GetStorage() Storage
+}
+
+// KVStorage is a Storage kept in memory and optionally mirrored to a file
+type KVStorage struct {
+	mtx   sync.Mutex
+	path  string
+	quota int
+	used  int
+	data  map[string][]byte
+
+	// fileMtx orders file writes, so the file never goes back to older data
+	fileMtx    sync.Mutex
+	dirty      bool
+	flushTimer *time.Timer
+	closed     bool
+}
+
+// NewKVStorage returns new KVStorage. If path is not empty, existing data is loaded from it
+// and modifications are written back within StorageFlushDelay, on Flush and on Close.
+// quota <= 0 means no limit
+func NewKVStorage(path string, quota int) (*KVStorage, error) {
+	ret := &KVStorage{
+		path:  path,
+		quota: quota,
+		data:  make(map[string][]byte),
+	}
+	if path == "" {
+		return ret, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &ret.data); err != nil {
+		return nil, err
+	}
+	for k, v := range ret.data {
+		ret.used += len(k) + len(v)
+	}
+	return ret, nil
+}
+
+// Get returns value stored by key
+func (th *KVStorage) Get(key string) ([]byte, bool) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	v, ok := th.data[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte{}, v...), true
+}
+
+// Set stores value by key, failing if storage quota would be exceeded
+func (th *KVStorage) Set(key string, value []byte) error {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	used := th.used + len(key) + len(value)
+	if old, ok := th.data[key]; ok {
+		used -= len(key) + len(old)
+	}
+	if th.quota > 0 && used > th.quota {
+		return fmt.Errorf("storage quota of %v bytes exceeded", th.quota)
+	}
+	th.data[key] = append([]byte{}, value...)
+	th.used = used
+	th.modified()
+	return nil
+}
+
+// Delete removes key from storage
+func (th *KVStorage) Delete(key string) error {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	old, ok := th.data[key]
+	if !ok {
+		return nil
+	}
+	delete(th.data, key)
+	th.used -= len(key) + len(old)
+	th.modified()
+	return nil
+}
+
+// Keys returns sorted list of stored keys
+func (th *KVStorage) Keys() []string {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	ret := make([]string, 0, len(th.data))
+	for k := range th.data {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// modified schedules writing data to the file. th.mtx must be held
+func (th *KVStorage) modified() {
+	if th.path == "" || th.closed {
+		return
+	}
+	th.dirty = true
+	if th.flushTimer == nil {
+		th.flushTimer = time.AfterFunc(StorageFlushDelay, func() { th.Flush() })
+	}
+}
+
+// Flush writes pending modifications to the file right away. Data that failed to be written
+// stays pending, so the next Flush or Close retries it
+func (th *KVStorage) Flush() error {
+	th.fileMtx.Lock()
+	defer th.fileMtx.Unlock()
+
+	th.mtx.Lock()
+	if th.flushTimer != nil {
+		th.flushTimer.Stop()
+		th.flushTimer = nil
+	}
+	if !th.dirty {
+		th.mtx.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(th.data)
+	th.dirty = false
+	th.mtx.Unlock()
+
+	if err == nil {
+		err = th.write(b)
+	}
+	if err != nil {
+		th.mtx.Lock()
+		th.dirty = true
+		th.mtx.Unlock()
+	}
+	return err
+}
+
+// Close writes pending modifications, later ones are kept in memory only
+func (th *KVStorage) Close() error {
+	th.mtx.Lock()
+	th.closed = true
+	th.mtx.Unlock()
+
+	return th.Flush()
+}
+
+// Remove deletes the file together with pending modifications, data stays in memory
+func (th *KVStorage) Remove() error {
+	th.fileMtx.Lock()
+	defer th.fileMtx.Unlock()
+
+	th.mtx.Lock()
+	th.closed = true
+	th.dirty = false
+	if th.flushTimer != nil {
+		th.flushTimer.Stop()
+		th.flushTimer = nil
+	}
+	th.mtx.Unlock()
+
+	if th.path == "" {
+		return nil
+	}
+	if err := os.Remove(th.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (th *KVStorage) write(b []byte) error {
+	tmp := th.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, th.path)
+}
@@ -0,0 +1,126 @@
+package meshpeer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mesh-simulator/meshpeer"
+)
+
+func TestKVStorageQuota(t *testing.T) {
+	type op struct {
+		key     string
+		value   string
+		del     bool
+		wantErr bool
+	}
+	tests := []struct {
+		name     string
+		quota    int
+		ops      []op
+		wantKeys []string
+	}{
+		{"fits exactly", 10, []op{{key: "ab", value: "12345678"}}, []string{"ab"}},
+		{"key counts", 10, []op{{key: "abc", value: "12345678", wantErr: true}}, []string{}},
+		{"second key exceeds", 10, []op{{key: "a", value: "1234"}, {key: "b", value: "12345", wantErr: true}}, []string{"a"}},
+		{"overwrite frees old value", 10, []op{{key: "a", value: "123456789"}, {key: "a", value: "987654321"}}, []string{"a"}},
+		{"overwrite too long keeps old value", 10, []op{{key: "a", value: "1"}, {key: "a", value: "1234567890", wantErr: true}}, []string{"a"}},
+		{"delete frees space", 10, []op{{key: "a", value: "123456789"}, {key: "a", del: true}, {key: "b", value: "123456789"}}, []string{"b"}},
+		{"delete of missing key", 10, []op{{key: "a", del: true}}, []string{}},
+		{"no limit", 0, []op{{key: "a", value: string(make([]byte, meshpeer.DefaultStorageQuota*2))}}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := meshpeer.NewKVStorage("", tt.quota)
+			if err != nil {
+				t.Fatalf("NewKVStorage: %v", err)
+			}
+			want := map[string]string{}
+			for i, o := range tt.ops {
+				if o.del {
+					err = s.Delete(o.key)
+				} else {
+					err = s.Set(o.key, []byte(o.value))
+				}
+				if gotErr := err != nil; gotErr != o.wantErr {
+					t.Fatalf("op %v error = %v, want error %v", i, err, o.wantErr)
+				}
+				if err == nil {
+					if o.del {
+						delete(want, o.key)
+					} else {
+						want[o.key] = o.value
+					}
+				}
+			}
+			if keys := s.Keys(); !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+			for k, v := range want {
+				if got, ok := s.Get(k); !ok || string(got) != v {
+					t.Errorf("%v = %q, %v, want %q", k, got, ok, v)
+				}
+			}
+		})
+	}
+}
+
+func TestKVStorageFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peer.json")
+
+	s, err := meshpeer.NewKVStorage(path, 10)
+	if err != nil {
+		t.Fatalf("NewKVStorage: %v", err)
+	}
+	s.Set("a", []byte{0, 255})
+	s.Set("b", []byte("text"))
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// modifications after Close stay in memory
+	s.Set("c", []byte("x"))
+
+	loaded, err := meshpeer.NewKVStorage(path, 10)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if keys := loaded.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("loaded keys = %v", keys)
+	}
+	if v, _ := loaded.Get("a"); string(v) != "\x00\xff" {
+		t.Errorf("a = %v", v)
+	}
+	// loaded data counts against quota
+	if err := loaded.Set("d", []byte("12345")); err == nil {
+		t.Error("quota is not enforced for loaded data")
+	}
+
+	if err := loaded.Remove(); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file exists after Remove: %v", err)
+	}
+}
+
+func TestJSStorageQuotaError(t *testing.T) {
+	_, p := newTestJSPeer(t, `
+		storage.set("small", "x");
+		try {
+			storage.set("big", new Uint8Array(100000));
+			console.log("stored");
+		} catch (e) {
+			console.log("rejected", storage.keys());
+		}`)
+	if logs := p.Logs(0); len(logs) != 1 || logs[0].Message != `rejected ["small"]` {
+		t.Errorf("logs = %+v", logs)
+	}
+}
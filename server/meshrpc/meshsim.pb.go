@@ -0,0 +1,2230 @@
+// gRPC interface of mesh network simulator.
+//
+// Peer service lets a remote program act as a mesh peer, the same way /ws_rpc does:
+// client opens Connect stream, sends Hello and then gets Welcome with its ID followed by
+// mesh events. Simulator service controls the simulation itself.
+//
+// Server runs several named worlds, calls work with the one named in "mesh-world" metadata,
+// or with the default world if there is none.
+//
+// When server auth is enabled, calls carry "authorization" metadata "Bearer <token>", the same
+// as HTTP requests do. Peers get the owner of the token, only the owner or an admin may delete
+// them or send messages on their behalf.
+//
+// Go code is generated with protoc-gen-go of github.com/golang/protobuf v1.4.2:
+//   protoc --go_out=plugins=grpc,paths=source_relative:. meshsim.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0
+// 	protoc        v3.12.4
+// source: meshsim.proto
+
+package meshrpc
+
+import (
+	context "context"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type Empty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{0}
+}
+
+type PeerID struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *PeerID) Reset() {
+	*x = PeerID{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerID) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerID) ProtoMessage() {}
+
+func (x *PeerID) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerID.ProtoReflect.Descriptor instead.
+func (*PeerID) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PeerID) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Coord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon float64 `protobuf:"fixed64,2,opt,name=lon,proto3" json:"lon,omitempty"`
+}
+
+func (x *Coord) Reset() {
+	*x = Coord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Coord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Coord) ProtoMessage() {}
+
+func (x *Coord) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Coord.ProtoReflect.Descriptor instead.
+func (*Coord) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Coord) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *Coord) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+type PeerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Request:
+	//	*PeerRequest_Hello
+	//	*PeerRequest_Send
+	//	*PeerRequest_Broadcast
+	//	*PeerRequest_DebugData
+	//	*PeerRequest_MoveTo
+	Request isPeerRequest_Request `protobuf_oneof:"request"`
+}
+
+func (x *PeerRequest) Reset() {
+	*x = PeerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerRequest) ProtoMessage() {}
+
+func (x *PeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerRequest.ProtoReflect.Descriptor instead.
+func (*PeerRequest) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{3}
+}
+
+func (m *PeerRequest) GetRequest() isPeerRequest_Request {
+	if m != nil {
+		return m.Request
+	}
+	return nil
+}
+
+func (x *PeerRequest) GetHello() *Hello {
+	if x, ok := x.GetRequest().(*PeerRequest_Hello); ok {
+		return x.Hello
+	}
+	return nil
+}
+
+func (x *PeerRequest) GetSend() *Send {
+	if x, ok := x.GetRequest().(*PeerRequest_Send); ok {
+		return x.Send
+	}
+	return nil
+}
+
+func (x *PeerRequest) GetBroadcast() *Broadcast {
+	if x, ok := x.GetRequest().(*PeerRequest_Broadcast); ok {
+		return x.Broadcast
+	}
+	return nil
+}
+
+func (x *PeerRequest) GetDebugData() *DebugData {
+	if x, ok := x.GetRequest().(*PeerRequest_DebugData); ok {
+		return x.DebugData
+	}
+	return nil
+}
+
+func (x *PeerRequest) GetMoveTo() *Coord {
+	if x, ok := x.GetRequest().(*PeerRequest_MoveTo); ok {
+		return x.MoveTo
+	}
+	return nil
+}
+
+type isPeerRequest_Request interface {
+	isPeerRequest_Request()
+}
+
+type PeerRequest_Hello struct {
+	Hello *Hello `protobuf:"bytes,1,opt,name=hello,proto3,oneof"`
+}
+
+type PeerRequest_Send struct {
+	Send *Send `protobuf:"bytes,2,opt,name=send,proto3,oneof"`
+}
+
+type PeerRequest_Broadcast struct {
+	Broadcast *Broadcast `protobuf:"bytes,3,opt,name=broadcast,proto3,oneof"`
+}
+
+type PeerRequest_DebugData struct {
+	DebugData *DebugData `protobuf:"bytes,4,opt,name=debug_data,json=debugData,proto3,oneof"`
+}
+
+type PeerRequest_MoveTo struct {
+	MoveTo *Coord `protobuf:"bytes,5,opt,name=move_to,json=moveTo,proto3,oneof"`
+}
+
+func (*PeerRequest_Hello) isPeerRequest_Request() {}
+
+func (*PeerRequest_Send) isPeerRequest_Request() {}
+
+func (*PeerRequest_Broadcast) isPeerRequest_Request() {}
+
+func (*PeerRequest_DebugData) isPeerRequest_Request() {}
+
+func (*PeerRequest_MoveTo) isPeerRequest_Request() {}
+
+// Hello must be the first request on the stream
+type Hello struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// start position, default one is used when not set
+	Coord *Coord `protobuf:"bytes,1,opt,name=coord,proto3" json:"coord,omitempty"`
+	// minimal interval between Tick events, 0 means every simulation tick
+	TickIntervalMs uint32 `protobuf:"varint,2,opt,name=tick_interval_ms,json=tickIntervalMs,proto3" json:"tick_interval_ms,omitempty"`
+	// disables Tick events
+	NoTicks bool `protobuf:"varint,3,opt,name=no_ticks,json=noTicks,proto3" json:"no_ticks,omitempty"`
+}
+
+func (x *Hello) Reset() {
+	*x = Hello{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Hello) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hello) ProtoMessage() {}
+
+func (x *Hello) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hello.ProtoReflect.Descriptor instead.
+func (*Hello) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Hello) GetCoord() *Coord {
+	if x != nil {
+		return x.Coord
+	}
+	return nil
+}
+
+func (x *Hello) GetTickIntervalMs() uint32 {
+	if x != nil {
+		return x.TickIntervalMs
+	}
+	return 0
+}
+
+func (x *Hello) GetNoTicks() bool {
+	if x != nil {
+		return x.NoTicks
+	}
+	return false
+}
+
+type Send struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PeerId string `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Data   []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Send) Reset() {
+	*x = Send{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Send) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Send) ProtoMessage() {}
+
+func (x *Send) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Send.ProtoReflect.Descriptor instead.
+func (*Send) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Send) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *Send) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// Broadcast sends data to every currently visible peer
+type Broadcast struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Broadcast) Reset() {
+	*x = Broadcast{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Broadcast) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Broadcast) ProtoMessage() {}
+
+func (x *Broadcast) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Broadcast.ProtoReflect.Descriptor instead.
+func (*Broadcast) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Broadcast) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// DebugData is shown by simulator viewer, json must be valid JSON
+type DebugData struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Json string `protobuf:"bytes,1,opt,name=json,proto3" json:"json,omitempty"`
+}
+
+func (x *DebugData) Reset() {
+	*x = DebugData{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DebugData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DebugData) ProtoMessage() {}
+
+func (x *DebugData) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DebugData.ProtoReflect.Descriptor instead.
+func (*DebugData) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DebugData) GetJson() string {
+	if x != nil {
+		return x.Json
+	}
+	return ""
+}
+
+type PeerEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Event:
+	//	*PeerEvent_Welcome
+	//	*PeerEvent_PeerAppeared
+	//	*PeerEvent_PeerDisappeared
+	//	*PeerEvent_Receive
+	//	*PeerEvent_Tick
+	//	*PeerEvent_Error
+	Event isPeerEvent_Event `protobuf_oneof:"event"`
+}
+
+func (x *PeerEvent) Reset() {
+	*x = PeerEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PeerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerEvent) ProtoMessage() {}
+
+func (x *PeerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerEvent.ProtoReflect.Descriptor instead.
+func (*PeerEvent) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{8}
+}
+
+func (m *PeerEvent) GetEvent() isPeerEvent_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetWelcome() *PeerID {
+	if x, ok := x.GetEvent().(*PeerEvent_Welcome); ok {
+		return x.Welcome
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetPeerAppeared() *PeerID {
+	if x, ok := x.GetEvent().(*PeerEvent_PeerAppeared); ok {
+		return x.PeerAppeared
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetPeerDisappeared() *PeerID {
+	if x, ok := x.GetEvent().(*PeerEvent_PeerDisappeared); ok {
+		return x.PeerDisappeared
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetReceive() *Receive {
+	if x, ok := x.GetEvent().(*PeerEvent_Receive); ok {
+		return x.Receive
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetTick() *Tick {
+	if x, ok := x.GetEvent().(*PeerEvent_Tick); ok {
+		return x.Tick
+	}
+	return nil
+}
+
+func (x *PeerEvent) GetError() *Error {
+	if x, ok := x.GetEvent().(*PeerEvent_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+type isPeerEvent_Event interface {
+	isPeerEvent_Event()
+}
+
+type PeerEvent_Welcome struct {
+	Welcome *PeerID `protobuf:"bytes,1,opt,name=welcome,proto3,oneof"`
+}
+
+type PeerEvent_PeerAppeared struct {
+	PeerAppeared *PeerID `protobuf:"bytes,2,opt,name=peer_appeared,json=peerAppeared,proto3,oneof"`
+}
+
+type PeerEvent_PeerDisappeared struct {
+	PeerDisappeared *PeerID `protobuf:"bytes,3,opt,name=peer_disappeared,json=peerDisappeared,proto3,oneof"`
+}
+
+type PeerEvent_Receive struct {
+	Receive *Receive `protobuf:"bytes,4,opt,name=receive,proto3,oneof"`
+}
+
+type PeerEvent_Tick struct {
+	Tick *Tick `protobuf:"bytes,5,opt,name=tick,proto3,oneof"`
+}
+
+type PeerEvent_Error struct {
+	Error *Error `protobuf:"bytes,6,opt,name=error,proto3,oneof"`
+}
+
+func (*PeerEvent_Welcome) isPeerEvent_Event() {}
+
+func (*PeerEvent_PeerAppeared) isPeerEvent_Event() {}
+
+func (*PeerEvent_PeerDisappeared) isPeerEvent_Event() {}
+
+func (*PeerEvent_Receive) isPeerEvent_Event() {}
+
+func (*PeerEvent_Tick) isPeerEvent_Event() {}
+
+func (*PeerEvent_Error) isPeerEvent_Event() {}
+
+type Receive struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PeerId string `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Data   []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Receive) Reset() {
+	*x = Receive{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Receive) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Receive) ProtoMessage() {}
+
+func (x *Receive) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Receive.ProtoReflect.Descriptor instead.
+func (*Receive) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Receive) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *Receive) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type Tick struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// simulation time in microseconds
+	Ts int64 `protobuf:"varint,1,opt,name=ts,proto3" json:"ts,omitempty"`
+}
+
+func (x *Tick) Reset() {
+	*x = Tick{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tick) ProtoMessage() {}
+
+func (x *Tick) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tick.ProtoReflect.Descriptor instead.
+func (*Tick) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Tick) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+// Error reports a failed peer request. It is also sent when events were dropped because the
+// client did not read them fast enough
+type Error struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type CreatePeerRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	StartCoord *Coord `protobuf:"bytes,1,opt,name=start_coord,json=startCoord,proto3" json:"start_coord,omitempty"`
+	// JavaScript code of the peer, see /create_peer
+	Script string `protobuf:"bytes,2,opt,name=script,proto3" json:"script,omitempty"`
+	// peer metainfo as JSON object, e.g. {"color": "red", "label": "1"}
+	MetaJson string `protobuf:"bytes,3,opt,name=meta_json,json=metaJson,proto3" json:"meta_json,omitempty"`
+	// built-in peer type, see /peer_types. Empty or "js" runs script
+	Type string `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	// JSON config of built-in peer
+	ConfigJson string `protobuf:"bytes,5,opt,name=config_json,json=configJson,proto3" json:"config_json,omitempty"`
+	// place peer exactly at start_coord, without random offset and jitter
+	Exact bool `protobuf:"varint,6,opt,name=exact,proto3" json:"exact,omitempty"`
+}
+
+func (x *CreatePeerRequest) Reset() {
+	*x = CreatePeerRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreatePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePeerRequest) ProtoMessage() {}
+
+func (x *CreatePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePeerRequest.ProtoReflect.Descriptor instead.
+func (*CreatePeerRequest) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CreatePeerRequest) GetStartCoord() *Coord {
+	if x != nil {
+		return x.StartCoord
+	}
+	return nil
+}
+
+func (x *CreatePeerRequest) GetScript() string {
+	if x != nil {
+		return x.Script
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetMetaJson() string {
+	if x != nil {
+		return x.MetaJson
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetConfigJson() string {
+	if x != nil {
+		return x.ConfigJson
+	}
+	return ""
+}
+
+func (x *CreatePeerRequest) GetExact() bool {
+	if x != nil {
+		return x.Exact
+	}
+	return false
+}
+
+type CreatePeerResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *CreatePeerResponse) Reset() {
+	*x = CreatePeerResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CreatePeerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreatePeerResponse) ProtoMessage() {}
+
+func (x *CreatePeerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreatePeerResponse.ProtoReflect.Descriptor instead.
+func (*CreatePeerResponse) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CreatePeerResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type SendMessageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// empty to send to all visible peers
+	TargetIds []string `protobuf:"bytes,3,rep,name=target_ids,json=targetIds,proto3" json:"target_ids,omitempty"`
+}
+
+func (x *SendMessageRequest) Reset() {
+	*x = SendMessageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SendMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendMessageRequest) ProtoMessage() {}
+
+func (x *SendMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendMessageRequest) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SendMessageRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SendMessageRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SendMessageRequest) GetTargetIds() []string {
+	if x != nil {
+		return x.TargetIds
+	}
+	return nil
+}
+
+type Overview struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// wall clock time of the overview in milliseconds
+	Ts     int64    `protobuf:"varint,1,opt,name=ts,proto3" json:"ts,omitempty"`
+	Actors []*Actor `protobuf:"bytes,2,rep,name=actors,proto3" json:"actors,omitempty"`
+}
+
+func (x *Overview) Reset() {
+	*x = Overview{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Overview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Overview) ProtoMessage() {}
+
+func (x *Overview) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Overview.ProtoReflect.Descriptor instead.
+func (*Overview) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Overview) GetTs() int64 {
+	if x != nil {
+		return x.Ts
+	}
+	return 0
+}
+
+func (x *Overview) GetActors() []*Actor {
+	if x != nil {
+		return x.Actors
+	}
+	return nil
+}
+
+type Actor struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id               string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Coord            *Coord   `protobuf:"bytes,2,opt,name=coord,proto3" json:"coord,omitempty"`
+	Peers            []string `protobuf:"bytes,3,rep,name=peers,proto3" json:"peers,omitempty"`
+	MetaJson         string   `protobuf:"bytes,4,opt,name=meta_json,json=metaJson,proto3" json:"meta_json,omitempty"`
+	CurrentStateJson string   `protobuf:"bytes,5,opt,name=current_state_json,json=currentStateJson,proto3" json:"current_state_json,omitempty"`
+	DebugDataJson    string   `protobuf:"bytes,6,opt,name=debug_data_json,json=debugDataJson,proto3" json:"debug_data_json,omitempty"`
+	HealthJson       string   `protobuf:"bytes,7,opt,name=health_json,json=healthJson,proto3" json:"health_json,omitempty"`
+	Down             bool     `protobuf:"varint,8,opt,name=down,proto3" json:"down,omitempty"`
+	// owner is the user who created the peer, anonymous@<client address> if it had no token
+	Owner string `protobuf:"bytes,9,opt,name=owner,proto3" json:"owner,omitempty"`
+}
+
+func (x *Actor) Reset() {
+	*x = Actor{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Actor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Actor) ProtoMessage() {}
+
+func (x *Actor) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Actor.ProtoReflect.Descriptor instead.
+func (*Actor) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Actor) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Actor) GetCoord() *Coord {
+	if x != nil {
+		return x.Coord
+	}
+	return nil
+}
+
+func (x *Actor) GetPeers() []string {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *Actor) GetMetaJson() string {
+	if x != nil {
+		return x.MetaJson
+	}
+	return ""
+}
+
+func (x *Actor) GetCurrentStateJson() string {
+	if x != nil {
+		return x.CurrentStateJson
+	}
+	return ""
+}
+
+func (x *Actor) GetDebugDataJson() string {
+	if x != nil {
+		return x.DebugDataJson
+	}
+	return ""
+}
+
+func (x *Actor) GetHealthJson() string {
+	if x != nil {
+		return x.HealthJson
+	}
+	return ""
+}
+
+func (x *Actor) GetDown() bool {
+	if x != nil {
+		return x.Down
+	}
+	return false
+}
+
+func (x *Actor) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type StepRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticks uint32 `protobuf:"varint,1,opt,name=ticks,proto3" json:"ticks,omitempty"`
+}
+
+func (x *StepRequest) Reset() {
+	*x = StepRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StepRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StepRequest) ProtoMessage() {}
+
+func (x *StepRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StepRequest.ProtoReflect.Descriptor instead.
+func (*StepRequest) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StepRequest) GetTicks() uint32 {
+	if x != nil {
+		return x.Ticks
+	}
+	return 0
+}
+
+type SimulationState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Paused bool `protobuf:"varint,1,opt,name=paused,proto3" json:"paused,omitempty"`
+	// simulation time in microseconds
+	SimTime int64 `protobuf:"varint,2,opt,name=sim_time,json=simTime,proto3" json:"sim_time,omitempty"`
+}
+
+func (x *SimulationState) Reset() {
+	*x = SimulationState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_meshsim_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SimulationState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SimulationState) ProtoMessage() {}
+
+func (x *SimulationState) ProtoReflect() protoreflect.Message {
+	mi := &file_meshsim_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SimulationState.ProtoReflect.Descriptor instead.
+func (*SimulationState) Descriptor() ([]byte, []int) {
+	return file_meshsim_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SimulationState) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *SimulationState) GetSimTime() int64 {
+	if x != nil {
+		return x.SimTime
+	}
+	return 0
+}
+
+var File_meshsim_proto protoreflect.FileDescriptor
+
+var file_meshsim_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x07, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x22, 0x07, 0x0a, 0x05, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x22, 0x18, 0x0a, 0x06, 0x50, 0x65, 0x65, 0x72, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x2b, 0x0a, 0x05, 0x43,
+	0x6f, 0x6f, 0x72, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x61, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x03, 0x6c, 0x61, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x03, 0x6c, 0x6f, 0x6e, 0x22, 0xf9, 0x01, 0x0a, 0x0b, 0x50, 0x65, 0x65,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x05, 0x68, 0x65, 0x6c, 0x6c,
+	0x6f, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69,
+	0x6d, 0x2e, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x48, 0x00, 0x52, 0x05, 0x68, 0x65, 0x6c, 0x6c, 0x6f,
+	0x12, 0x23, 0x0a, 0x04, 0x73, 0x65, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53, 0x65, 0x6e, 0x64, 0x48, 0x00, 0x52,
+	0x04, 0x73, 0x65, 0x6e, 0x64, 0x12, 0x32, 0x0a, 0x09, 0x62, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61,
+	0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73,
+	0x69, 0x6d, 0x2e, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x48, 0x00, 0x52, 0x09,
+	0x62, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12, 0x33, 0x0a, 0x0a, 0x64, 0x65, 0x62,
+	0x75, 0x67, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e,
+	0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x44, 0x61, 0x74,
+	0x61, 0x48, 0x00, 0x52, 0x09, 0x64, 0x65, 0x62, 0x75, 0x67, 0x44, 0x61, 0x74, 0x61, 0x12, 0x29,
+	0x0a, 0x07, 0x6d, 0x6f, 0x76, 0x65, 0x5f, 0x74, 0x6f, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x43, 0x6f, 0x6f, 0x72, 0x64, 0x48,
+	0x00, 0x52, 0x06, 0x6d, 0x6f, 0x76, 0x65, 0x54, 0x6f, 0x42, 0x09, 0x0a, 0x07, 0x72, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x72, 0x0a, 0x05, 0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x12, 0x24, 0x0a,
+	0x05, 0x63, 0x6f, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d,
+	0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x43, 0x6f, 0x6f, 0x72, 0x64, 0x52, 0x05, 0x63, 0x6f,
+	0x6f, 0x72, 0x64, 0x12, 0x28, 0x0a, 0x10, 0x74, 0x69, 0x63, 0x6b, 0x5f, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x5f, 0x6d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0e, 0x74,
+	0x69, 0x63, 0x6b, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x4d, 0x73, 0x12, 0x19, 0x0a,
+	0x08, 0x6e, 0x6f, 0x5f, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x07, 0x6e, 0x6f, 0x54, 0x69, 0x63, 0x6b, 0x73, 0x22, 0x33, 0x0a, 0x04, 0x53, 0x65, 0x6e, 0x64,
+	0x12, 0x17, 0x0a, 0x07, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x70, 0x65, 0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x1f, 0x0a,
+	0x09, 0x42, 0x72, 0x6f, 0x61, 0x64, 0x63, 0x61, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x1f,
+	0x0a, 0x09, 0x44, 0x65, 0x62, 0x75, 0x67, 0x44, 0x61, 0x74, 0x61, 0x12, 0x12, 0x0a, 0x04, 0x6a,
+	0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6a, 0x73, 0x6f, 0x6e, 0x22,
+	0xb2, 0x02, 0x0a, 0x09, 0x50, 0x65, 0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x2b, 0x0a,
+	0x07, 0x77, 0x65, 0x6c, 0x63, 0x6f, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x49, 0x44, 0x48,
+	0x00, 0x52, 0x07, 0x77, 0x65, 0x6c, 0x63, 0x6f, 0x6d, 0x65, 0x12, 0x36, 0x0a, 0x0d, 0x70, 0x65,
+	0x65, 0x72, 0x5f, 0x61, 0x70, 0x70, 0x65, 0x61, 0x72, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x50, 0x65, 0x65, 0x72,
+	0x49, 0x44, 0x48, 0x00, 0x52, 0x0c, 0x70, 0x65, 0x65, 0x72, 0x41, 0x70, 0x70, 0x65, 0x61, 0x72,
+	0x65, 0x64, 0x12, 0x3c, 0x0a, 0x10, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x64, 0x69, 0x73, 0x61, 0x70,
+	0x70, 0x65, 0x61, 0x72, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x6d,
+	0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x49, 0x44, 0x48, 0x00, 0x52,
+	0x0f, 0x70, 0x65, 0x65, 0x72, 0x44, 0x69, 0x73, 0x61, 0x70, 0x70, 0x65, 0x61, 0x72, 0x65, 0x64,
+	0x12, 0x2c, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x52, 0x65, 0x63, 0x65,
+	0x69, 0x76, 0x65, 0x48, 0x00, 0x52, 0x07, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x12, 0x23,
+	0x0a, 0x04, 0x74, 0x69, 0x63, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x6d,
+	0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x54, 0x69, 0x63, 0x6b, 0x48, 0x00, 0x52, 0x04, 0x74,
+	0x69, 0x63, 0x6b, 0x12, 0x26, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x42, 0x07, 0x0a, 0x05, 0x65,
+	0x76, 0x65, 0x6e, 0x74, 0x22, 0x36, 0x0a, 0x07, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x12,
+	0x17, 0x0a, 0x07, 0x70, 0x65, 0x65, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x70, 0x65, 0x65, 0x72, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x16, 0x0a, 0x04,
+	0x54, 0x69, 0x63, 0x6b, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x02, 0x74, 0x73, 0x22, 0x21, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x18, 0x0a,
+	0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0xc4, 0x01, 0x0a, 0x11, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2f, 0x0a,
+	0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x63, 0x6f, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x43, 0x6f, 0x6f,
+	0x72, 0x64, 0x52, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x43, 0x6f, 0x6f, 0x72, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x74, 0x61, 0x5f, 0x6a,
+	0x73, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x4a,
+	0x73, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x66, 0x69,
+	0x67, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x78, 0x61, 0x63,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x65, 0x78, 0x61, 0x63, 0x74, 0x22, 0x24,
+	0x0a, 0x12, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x22, 0x57, 0x0a, 0x12, 0x53, 0x65, 0x6e, 0x64, 0x4d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
+	0x74, 0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x1d,
+	0x0a, 0x0a, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x09, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x49, 0x64, 0x73, 0x22, 0x42, 0x0a,
+	0x08, 0x4f, 0x76, 0x65, 0x72, 0x76, 0x69, 0x65, 0x77, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x74, 0x73, 0x12, 0x26, 0x0a, 0x06, 0x61, 0x63, 0x74,
+	0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68,
+	0x73, 0x69, 0x6d, 0x2e, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x06, 0x61, 0x63, 0x74, 0x6f, 0x72,
+	0x73, 0x22, 0x91, 0x02, 0x0a, 0x05, 0x41, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x24, 0x0a, 0x05, 0x63,
+	0x6f, 0x6f, 0x72, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x65, 0x73,
+	0x68, 0x73, 0x69, 0x6d, 0x2e, 0x43, 0x6f, 0x6f, 0x72, 0x64, 0x52, 0x05, 0x63, 0x6f, 0x6f, 0x72,
+	0x64, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x05, 0x70, 0x65, 0x65, 0x72, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x74, 0x61, 0x5f,
+	0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61,
+	0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x12, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x5f,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x10, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x4a, 0x73,
+	0x6f, 0x6e, 0x12, 0x26, 0x0a, 0x0f, 0x64, 0x65, 0x62, 0x75, 0x67, 0x5f, 0x64, 0x61, 0x74, 0x61,
+	0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x64, 0x65, 0x62,
+	0x75, 0x67, 0x44, 0x61, 0x74, 0x61, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x68, 0x65,
+	0x61, 0x6c, 0x74, 0x68, 0x5f, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x4a, 0x73, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x64,
+	0x6f, 0x77, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x64, 0x6f, 0x77, 0x6e, 0x12,
+	0x14, 0x0a, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x6f, 0x77, 0x6e, 0x65, 0x72, 0x22, 0x23, 0x0a, 0x0b, 0x53, 0x74, 0x65, 0x70, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x22, 0x44, 0x0a, 0x0f, 0x53, 0x69,
+	0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x70, 0x61, 0x75, 0x73, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x70,
+	0x61, 0x75, 0x73, 0x65, 0x64, 0x12, 0x19, 0x0a, 0x08, 0x73, 0x69, 0x6d, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x73, 0x69, 0x6d, 0x54, 0x69, 0x6d, 0x65,
+	0x32, 0x3f, 0x0a, 0x04, 0x50, 0x65, 0x65, 0x72, 0x12, 0x37, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x6e,
+	0x65, 0x63, 0x74, 0x12, 0x14, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x50, 0x65,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x6d, 0x65, 0x73, 0x68,
+	0x73, 0x69, 0x6d, 0x2e, 0x50, 0x65, 0x65, 0x72, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x28, 0x01, 0x30,
+	0x01, 0x32, 0xc4, 0x03, 0x0a, 0x09, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x12,
+	0x45, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x12, 0x1a, 0x2e,
+	0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x50, 0x65,
+	0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x6d, 0x65, 0x73, 0x68,
+	0x73, 0x69, 0x6d, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x50, 0x65, 0x65, 0x72, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2d, 0x0a, 0x0a, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x50, 0x65, 0x65, 0x72, 0x12, 0x0f, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x50,
+	0x65, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e,
+	0x45, 0x6d, 0x70, 0x74, 0x79, 0x12, 0x3a, 0x0a, 0x0b, 0x53, 0x65, 0x6e, 0x64, 0x4d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x12, 0x1b, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53,
+	0x65, 0x6e, 0x64, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x6d, 0x70, 0x74,
+	0x79, 0x12, 0x30, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x4f, 0x76, 0x65, 0x72, 0x76, 0x69, 0x65, 0x77,
+	0x12, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x11, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x76,
+	0x69, 0x65, 0x77, 0x12, 0x31, 0x0a, 0x05, 0x50, 0x61, 0x75, 0x73, 0x65, 0x12, 0x0e, 0x2e, 0x6d,
+	0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x18, 0x2e, 0x6d,
+	0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x32, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6d, 0x65,
+	0x12, 0x0e, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79,
+	0x1a, 0x18, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53, 0x69, 0x6d, 0x75, 0x6c,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x36, 0x0a, 0x04, 0x53, 0x74,
+	0x65, 0x70, 0x12, 0x14, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53, 0x74, 0x65,
+	0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73,
+	0x69, 0x6d, 0x2e, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0e,
+	0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x18,
+	0x2e, 0x6d, 0x65, 0x73, 0x68, 0x73, 0x69, 0x6d, 0x2e, 0x53, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x42, 0x27, 0x0a, 0x0b, 0x6d, 0x65, 0x73, 0x68,
+	0x73, 0x69, 0x6d, 0x2e, 0x72, 0x70, 0x63, 0x50, 0x01, 0x5a, 0x16, 0x6d, 0x65, 0x73, 0x68, 0x2d,
+	0x73, 0x69, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x6f, 0x72, 0x2f, 0x6d, 0x65, 0x73, 0x68, 0x72, 0x70,
+	0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_meshsim_proto_rawDescOnce sync.Once
+	file_meshsim_proto_rawDescData = file_meshsim_proto_rawDesc
+)
+
+func file_meshsim_proto_rawDescGZIP() []byte {
+	file_meshsim_proto_rawDescOnce.Do(func() {
+		file_meshsim_proto_rawDescData = protoimpl.X.CompressGZIP(file_meshsim_proto_rawDescData)
+	})
+	return file_meshsim_proto_rawDescData
+}
+
+var file_meshsim_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_meshsim_proto_goTypes = []interface{}{
+	(*Empty)(nil),              // 0: meshsim.Empty
+	(*PeerID)(nil),             // 1: meshsim.PeerID
+	(*Coord)(nil),              // 2: meshsim.Coord
+	(*PeerRequest)(nil),        // 3: meshsim.PeerRequest
+	(*Hello)(nil),              // 4: meshsim.Hello
+	(*Send)(nil),               // 5: meshsim.Send
+	(*Broadcast)(nil),          // 6: meshsim.Broadcast
+	(*DebugData)(nil),          // 7: meshsim.DebugData
+	(*PeerEvent)(nil),          // 8: meshsim.PeerEvent
+	(*Receive)(nil),            // 9: meshsim.Receive
+	(*Tick)(nil),               // 10: meshsim.Tick
+	(*Error)(nil),              // 11: meshsim.Error
+	(*CreatePeerRequest)(nil),  // 12: meshsim.CreatePeerRequest
+	(*CreatePeerResponse)(nil), // 13: meshsim.CreatePeerResponse
+	(*SendMessageRequest)(nil), // 14: meshsim.SendMessageRequest
+	(*Overview)(nil),           // 15: meshsim.Overview
+	(*Actor)(nil),              // 16: meshsim.Actor
+	(*StepRequest)(nil),        // 17: meshsim.StepRequest
+	(*SimulationState)(nil),    // 18: meshsim.SimulationState
+}
+var file_meshsim_proto_depIdxs = []int32{
+	4,  // 0: meshsim.PeerRequest.hello:type_name -> meshsim.Hello
+	5,  // 1: meshsim.PeerRequest.send:type_name -> meshsim.Send
+	6,  // 2: meshsim.PeerRequest.broadcast:type_name -> meshsim.Broadcast
+	7,  // 3: meshsim.PeerRequest.debug_data:type_name -> meshsim.DebugData
+	2,  // 4: meshsim.PeerRequest.move_to:type_name -> meshsim.Coord
+	2,  // 5: meshsim.Hello.coord:type_name -> meshsim.Coord
+	1,  // 6: meshsim.PeerEvent.welcome:type_name -> meshsim.PeerID
+	1,  // 7: meshsim.PeerEvent.peer_appeared:type_name -> meshsim.PeerID
+	1,  // 8: meshsim.PeerEvent.peer_disappeared:type_name -> meshsim.PeerID
+	9,  // 9: meshsim.PeerEvent.receive:type_name -> meshsim.Receive
+	10, // 10: meshsim.PeerEvent.tick:type_name -> meshsim.Tick
+	11, // 11: meshsim.PeerEvent.error:type_name -> meshsim.Error
+	2,  // 12: meshsim.CreatePeerRequest.start_coord:type_name -> meshsim.Coord
+	16, // 13: meshsim.Overview.actors:type_name -> meshsim.Actor
+	2,  // 14: meshsim.Actor.coord:type_name -> meshsim.Coord
+	3,  // 15: meshsim.Peer.Connect:input_type -> meshsim.PeerRequest
+	12, // 16: meshsim.Simulator.CreatePeer:input_type -> meshsim.CreatePeerRequest
+	1,  // 17: meshsim.Simulator.DeletePeer:input_type -> meshsim.PeerID
+	14, // 18: meshsim.Simulator.SendMessage:input_type -> meshsim.SendMessageRequest
+	0,  // 19: meshsim.Simulator.GetOverview:input_type -> meshsim.Empty
+	0,  // 20: meshsim.Simulator.Pause:input_type -> meshsim.Empty
+	0,  // 21: meshsim.Simulator.Resume:input_type -> meshsim.Empty
+	17, // 22: meshsim.Simulator.Step:input_type -> meshsim.StepRequest
+	0,  // 23: meshsim.Simulator.GetState:input_type -> meshsim.Empty
+	8,  // 24: meshsim.Peer.Connect:output_type -> meshsim.PeerEvent
+	13, // 25: meshsim.Simulator.CreatePeer:output_type -> meshsim.CreatePeerResponse
+	0,  // 26: meshsim.Simulator.DeletePeer:output_type -> meshsim.Empty
+	0,  // 27: meshsim.Simulator.SendMessage:output_type -> meshsim.Empty
+	15, // 28: meshsim.Simulator.GetOverview:output_type -> meshsim.Overview
+	18, // 29: meshsim.Simulator.Pause:output_type -> meshsim.SimulationState
+	18, // 30: meshsim.Simulator.Resume:output_type -> meshsim.SimulationState
+	18, // 31: meshsim.Simulator.Step:output_type -> meshsim.SimulationState
+	18, // 32: meshsim.Simulator.GetState:output_type -> meshsim.SimulationState
+	24, // [24:33] is the sub-list for method output_type
+	15, // [15:24] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_meshsim_proto_init() }
+func file_meshsim_proto_init() {
+	if File_meshsim_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_meshsim_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Empty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerID); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Coord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Hello); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Send); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Broadcast); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DebugData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PeerEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Receive); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Tick); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Error); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreatePeerRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreatePeerResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SendMessageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Overview); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Actor); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StepRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_meshsim_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SimulationState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_meshsim_proto_msgTypes[3].OneofWrappers = []interface{}{
+		(*PeerRequest_Hello)(nil),
+		(*PeerRequest_Send)(nil),
+		(*PeerRequest_Broadcast)(nil),
+		(*PeerRequest_DebugData)(nil),
+		(*PeerRequest_MoveTo)(nil),
+	}
+	file_meshsim_proto_msgTypes[8].OneofWrappers = []interface{}{
+		(*PeerEvent_Welcome)(nil),
+		(*PeerEvent_PeerAppeared)(nil),
+		(*PeerEvent_PeerDisappeared)(nil),
+		(*PeerEvent_Receive)(nil),
+		(*PeerEvent_Tick)(nil),
+		(*PeerEvent_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_meshsim_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_meshsim_proto_goTypes,
+		DependencyIndexes: file_meshsim_proto_depIdxs,
+		MessageInfos:      file_meshsim_proto_msgTypes,
+	}.Build()
+	File_meshsim_proto = out.File
+	file_meshsim_proto_rawDesc = nil
+	file_meshsim_proto_goTypes = nil
+	file_meshsim_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// PeerClient is the client API for Peer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type PeerClient interface {
+	// Connect joins simulation as a peer which lives until the stream is closed
+	Connect(ctx context.Context, opts ...grpc.CallOption) (Peer_ConnectClient, error)
+}
+
+type peerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPeerClient(cc grpc.ClientConnInterface) PeerClient {
+	return &peerClient{cc}
+}
+
+func (c *peerClient) Connect(ctx context.Context, opts ...grpc.CallOption) (Peer_ConnectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Peer_serviceDesc.Streams[0], "/meshsim.Peer/Connect", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &peerConnectClient{stream}
+	return x, nil
+}
+
+type Peer_ConnectClient interface {
+	Send(*PeerRequest) error
+	Recv() (*PeerEvent, error)
+	grpc.ClientStream
+}
+
+type peerConnectClient struct {
+	grpc.ClientStream
+}
+
+func (x *peerConnectClient) Send(m *PeerRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *peerConnectClient) Recv() (*PeerEvent, error) {
+	m := new(PeerEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PeerServer is the server API for Peer service.
+type PeerServer interface {
+	// Connect joins simulation as a peer which lives until the stream is closed
+	Connect(Peer_ConnectServer) error
+}
+
+// UnimplementedPeerServer can be embedded to have forward compatible implementations.
+type UnimplementedPeerServer struct {
+}
+
+func (*UnimplementedPeerServer) Connect(Peer_ConnectServer) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+
+func RegisterPeerServer(s *grpc.Server, srv PeerServer) {
+	s.RegisterService(&_Peer_serviceDesc, srv)
+}
+
+func _Peer_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PeerServer).Connect(&peerConnectServer{stream})
+}
+
+type Peer_ConnectServer interface {
+	Send(*PeerEvent) error
+	Recv() (*PeerRequest, error)
+	grpc.ServerStream
+}
+
+type peerConnectServer struct {
+	grpc.ServerStream
+}
+
+func (x *peerConnectServer) Send(m *PeerEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *peerConnectServer) Recv() (*PeerRequest, error) {
+	m := new(PeerRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _Peer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "meshsim.Peer",
+	HandlerType: (*PeerServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       _Peer_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "meshsim.proto",
+}
+
+// SimulatorClient is the client API for Simulator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SimulatorClient interface {
+	CreatePeer(ctx context.Context, in *CreatePeerRequest, opts ...grpc.CallOption) (*CreatePeerResponse, error)
+	DeletePeer(ctx context.Context, in *PeerID, opts ...grpc.CallOption) (*Empty, error)
+	SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetOverview(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Overview, error)
+	Pause(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error)
+	Resume(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error)
+	// Step advances paused simulation by given number of ticks, each one is Dt simulation
+	// parameter long, 20ms by default.
+	// Ticks must be from 1 to 10000
+	Step(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*SimulationState, error)
+	GetState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error)
+}
+
+type simulatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSimulatorClient(cc grpc.ClientConnInterface) SimulatorClient {
+	return &simulatorClient{cc}
+}
+
+func (c *simulatorClient) CreatePeer(ctx context.Context, in *CreatePeerRequest, opts ...grpc.CallOption) (*CreatePeerResponse, error) {
+	out := new(CreatePeerResponse)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/CreatePeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) DeletePeer(ctx context.Context, in *PeerID, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/DeletePeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) SendMessage(ctx context.Context, in *SendMessageRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/SendMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) GetOverview(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Overview, error) {
+	out := new(Overview)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/GetOverview", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) Pause(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error) {
+	out := new(SimulationState)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/Pause", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) Resume(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error) {
+	out := new(SimulationState)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/Resume", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) Step(ctx context.Context, in *StepRequest, opts ...grpc.CallOption) (*SimulationState, error) {
+	out := new(SimulationState)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/Step", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *simulatorClient) GetState(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SimulationState, error) {
+	out := new(SimulationState)
+	err := c.cc.Invoke(ctx, "/meshsim.Simulator/GetState", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SimulatorServer is the server API for Simulator service.
+type SimulatorServer interface {
+	CreatePeer(context.Context, *CreatePeerRequest) (*CreatePeerResponse, error)
+	DeletePeer(context.Context, *PeerID) (*Empty, error)
+	SendMessage(context.Context, *SendMessageRequest) (*Empty, error)
+	GetOverview(context.Context, *Empty) (*Overview, error)
+	Pause(context.Context, *Empty) (*SimulationState, error)
+	Resume(context.Context, *Empty) (*SimulationState, error)
+	// Step advances paused simulation by given number of ticks, each one is Dt simulation
+	// parameter long, 20ms by default.
+	// Ticks must be from 1 to 10000
+	Step(context.Context, *StepRequest) (*SimulationState, error)
+	GetState(context.Context, *Empty) (*SimulationState, error)
+}
+
+// UnimplementedSimulatorServer can be embedded to have forward compatible implementations.
+type UnimplementedSimulatorServer struct {
+}
+
+func (*UnimplementedSimulatorServer) CreatePeer(context.Context, *CreatePeerRequest) (*CreatePeerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePeer not implemented")
+}
+func (*UnimplementedSimulatorServer) DeletePeer(context.Context, *PeerID) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePeer not implemented")
+}
+func (*UnimplementedSimulatorServer) SendMessage(context.Context, *SendMessageRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMessage not implemented")
+}
+func (*UnimplementedSimulatorServer) GetOverview(context.Context, *Empty) (*Overview, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOverview not implemented")
+}
+func (*UnimplementedSimulatorServer) Pause(context.Context, *Empty) (*SimulationState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Pause not implemented")
+}
+func (*UnimplementedSimulatorServer) Resume(context.Context, *Empty) (*SimulationState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resume not implemented")
+}
+func (*UnimplementedSimulatorServer) Step(context.Context, *StepRequest) (*SimulationState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Step not implemented")
+}
+func (*UnimplementedSimulatorServer) GetState(context.Context, *Empty) (*SimulationState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+
+func RegisterSimulatorServer(s *grpc.Server, srv SimulatorServer) {
+	s.RegisterService(&_Simulator_serviceDesc, srv)
+}
+
+func _Simulator_CreatePeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).CreatePeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/CreatePeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).CreatePeer(ctx, req.(*CreatePeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_DeletePeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeerID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).DeletePeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/DeletePeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).DeletePeer(ctx, req.(*PeerID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_SendMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).SendMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/SendMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).SendMessage(ctx, req.(*SendMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_GetOverview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).GetOverview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/GetOverview",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).GetOverview(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/Pause",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).Pause(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/Resume",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).Resume(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_Step_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StepRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).Step(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/Step",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).Step(ctx, req.(*StepRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Simulator_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SimulatorServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/meshsim.Simulator/GetState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SimulatorServer).GetState(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Simulator_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "meshsim.Simulator",
+	HandlerType: (*SimulatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreatePeer",
+			Handler:    _Simulator_CreatePeer_Handler,
+		},
+		{
+			MethodName: "DeletePeer",
+			Handler:    _Simulator_DeletePeer_Handler,
+		},
+		{
+			MethodName: "SendMessage",
+			Handler:    _Simulator_SendMessage_Handler,
+		},
+		{
+			MethodName: "GetOverview",
+			Handler:    _Simulator_GetOverview_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _Simulator_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _Simulator_Resume_Handler,
+		},
+		{
+			MethodName: "Step",
+			Handler:    _Simulator_Step_Handler,
+		},
+		{
+			MethodName: "GetState",
+			Handler:    _Simulator_GetState_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "meshsim.proto",
+}
@@ -2,6 +2,7 @@ package meshsim
 
 import (
 	"mesh-simulator/meshpeer"
+	"sort"
 	"sync"
 )
 
@@ -16,55 +17,189 @@ type actorPhysics struct {
 	randomFreq  [3]float64
 	randomPhase [3]float64
 
+	speed   float64
+	heading float64
+	frozen  bool
+
 	outgoingMsgQueue map[meshpeer.NetworkID][]meshpeer.NetworkMessage
 
 	mtx *sync.Mutex
 
 	metainfo map[string]interface{}
+	owner    string
 
 	sender func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)
 
-	peerAppearedHandler    func(id meshpeer.NetworkID)
-	peerDisappearedHandler func(id meshpeer.NetworkID)
-	messageHandler         func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)
-	timeTickHandler        func(ts meshpeer.NetworkTime)
-
-	debugData interface{}
+	// handlers are registered by peer runtime from its own goroutines, so they are guarded by mtx
+	handlers actorHandlers
+	// announcePeers makes the next tick report every neighbour in range as appeared, it is set
+	// when runtime registers peer appeared handler, which may happen after the actor got links
+	announcePeers bool
 
-	userDataSetter func(interface{})
+	debugData     interface{}
+	peerDebugData interface{}
+	health        *meshpeer.PeerHealth
+	storage       *meshpeer.KVStorage
 
 	nextUserSimulationSentTime float64
 	userInterestingEventTime   float64
+
+	crashed   bool
+	restartAt float64
+
+	lifecycle meshpeer.Lifecycle
+	removed   bool
+}
+
+// actorHandlers are callbacks of peer runtime
+type actorHandlers struct {
+	peerAppeared    func(id meshpeer.NetworkID)
+	peerDisappeared func(id meshpeer.NetworkID)
+	message         func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)
+	timeTick        func(ts meshpeer.NetworkTime)
+	userData        func(interface{})
+}
+
+func (th *actorPhysics) resetHandlers() {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers = actorHandlers{
+		peerAppeared:    func(meshpeer.NetworkID) {},
+		peerDisappeared: func(meshpeer.NetworkID) {},
+		message:         func(meshpeer.NetworkID, meshpeer.NetworkMessage) {},
+		timeTick:        func(meshpeer.NetworkTime) {},
+		userData:        func(interface{}) {},
+	}
+}
+
+// callbacks returns current handlers of peer runtime. They must be called without th.mtx
+// held, as they use actor API
+func (th *actorPhysics) callbacks() actorHandlers {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	return th.handlers
+}
+
+// info returns actor state, simulator mutex must be held
+func (th *actorPhysics) info() ActorInfo {
+	prs := []string{}
+	for p := range th.currentPeers {
+		prs = append(prs, string(p))
+	}
+	sort.Strings(prs)
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+	return ActorInfo{string(th.ID), th.Coord, prs, th.metainfo, th.debugData, th.peerDebugData, th.health, th.crashed, th.motion(), th.owner}
 }
 
 func (th *actorPhysics) GetMyID() meshpeer.NetworkID {
 	return th.ID
 }
+
+// RegisterPeerAppearedHandler sets peer appeared handler, it is told about every neighbour
+// already in range on the next tick
 func (th *actorPhysics) RegisterPeerAppearedHandler(h func(id meshpeer.NetworkID)) {
-	th.peerAppearedHandler = h
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers.peerAppeared = h
+	th.announcePeers = true
 }
 func (th *actorPhysics) RegisterPeerDisappearedHandler(h func(id meshpeer.NetworkID)) {
-	th.peerDisappearedHandler = h
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers.peerDisappeared = h
 }
 func (th *actorPhysics) RegisterMessageHandler(h func(id meshpeer.NetworkID, data meshpeer.NetworkMessage)) {
-	th.messageHandler = h
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers.message = h
 }
 func (th *actorPhysics) SendMessage(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
 	th.sender(id, data)
 }
 func (th *actorPhysics) RegisterTimeTickHandler(h func(ts meshpeer.NetworkTime)) {
-	th.timeTickHandler = h
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers.timeTick = h
 }
 func (th *actorPhysics) SendDebugData(d interface{}) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
 
+	th.peerDebugData = d
 }
 
 func (th *actorPhysics) HandleUpdate(update meshpeer.FrontEndUpdateObject) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
 
 	th.debugData = update
 }
+func (th *actorPhysics) GetPosition() [2]float64 {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	return th.Coord
+}
+func (th *actorPhysics) MoveTo(coord [2]float64) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.startCoord = coord
+}
+func (th *actorPhysics) GetStorage() meshpeer.Storage {
+	return th.storage
+}
+
+// ReportHealth is called by peer code outside of simulator lock, so health is guarded by th.mtx
+func (th *actorPhysics) ReportHealth(h meshpeer.PeerHealth) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.health = &h
+}
+func (th *actorPhysics) BindLifecycle(l meshpeer.Lifecycle) {
+	th.mtx.Lock()
+	old := th.lifecycle
+	removed := th.removed
+	if !removed {
+		th.lifecycle = l
+	}
+	th.mtx.Unlock()
+
+	if old != nil {
+		old.Stop()
+		old.Close()
+	}
+	if removed {
+		l.Stop()
+		l.Close()
+		return
+	}
+	l.Start()
+}
+
+// unbindLifecycle marks actor removed and returns its runtime to be stopped and closed
+func (th *actorPhysics) unbindLifecycle() meshpeer.Lifecycle {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.removed = true
+	l := th.lifecycle
+	th.lifecycle = nil
+	return l
+}
 func (th *actorPhysics) RegisterUserDataUpdateHandler(h func(meshpeer.FrontendUserDataType)) {
-	th.userDataSetter = func(d interface{}) {
+	th.mtx.Lock()
+	defer th.mtx.Unlock()
+
+	th.handlers.userData = func(d interface{}) {
 		h(meshpeer.FrontendUserDataType(d))
 	}
 }
@@ -0,0 +1,129 @@
+package meshsim
+
+import (
+	"fmt"
+	"math/rand"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+)
+
+// AllActorsGroup is a fault schedule group matching actors without own group schedule
+const AllActorsGroup = "*"
+
+// FaultSchedule is mean time between failures and mean time to repair in seconds of simulation time
+type FaultSchedule struct {
+	MTBF float64
+	MTTR float64
+}
+
+// SetRestartHandler sets function to be called when crashed actor is restarted. It gets APIs
+// for the fresh peer runtime. The handler is called from a separate goroutine
+func (s *Simulator) SetRestartHandler(h func(id meshpeer.NetworkID, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI)) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.restartHandler = h
+}
+
+// SetFaultSchedule enables random crashes of actors whose "group" meta value equals group,
+// AllActorsGroup matches the rest. Time between failures and time to repair are exponentially
+// distributed with given means in seconds of simulation time. mtbf <= 0 disables the schedule
+func (s *Simulator) SetFaultSchedule(group string, mtbf float64, mttr float64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if mtbf <= 0 {
+		delete(s.faultSchedules, group)
+		return
+	}
+	s.faultSchedules[group] = FaultSchedule{mtbf, mttr}
+}
+
+// CrashActor simulates device crash: peer callbacks are stopped, its links are dropped and
+// queued messages are discarded. If restartAfter > 0, actor is restarted after this number
+// of seconds of simulation time, otherwise it stays down until RestartActor is called
+func (s *Simulator) CrashActor(id meshpeer.NetworkID, restartAfter float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	a, ok := s.actors[id]
+	if !ok {
+		return fmt.Errorf("Actor not found")
+	}
+	if a.crashed {
+		return fmt.Errorf("Actor is already down")
+	}
+	s.crashActor(a, restartAfter)
+	return nil
+}
+
+// RestartActor brings crashed actor back with a fresh runtime
+func (s *Simulator) RestartActor(id meshpeer.NetworkID) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	a, ok := s.actors[id]
+	if !ok {
+		return fmt.Errorf("Actor not found")
+	}
+	if !a.crashed {
+		return fmt.Errorf("Actor is not down")
+	}
+	s.restartActor(a)
+	return nil
+}
+
+func (s *Simulator) crashActor(a *actorPhysics, restartAfter float64) {
+	a.mtx.Lock()
+	a.crashed = true
+	a.outgoingMsgQueue = make(map[meshpeer.NetworkID][]meshpeer.NetworkMessage)
+	a.debugData = nil
+	a.peerDebugData = nil
+	a.health = nil
+	a.mtx.Unlock()
+
+	a.restartAt = 0
+	if restartAfter > 0 {
+		a.restartAt = s.simTime + restartAfter
+	}
+	a.resetHandlers()
+	a.currentPeers = make(map[meshpeer.NetworkID]struct{})
+
+	s.dropLinksTo(a.ID)
+	s.logger.Info("Actor crashed", meshlog.KeyPeer, a.ID)
+}
+
+func (s *Simulator) restartActor(a *actorPhysics) {
+	a.mtx.Lock()
+	a.crashed = false
+	a.mtx.Unlock()
+
+	a.restartAt = 0
+	s.logger.Info("Actor restarted", meshlog.KeyPeer, a.ID)
+	if s.restartHandler != nil {
+		go s.restartHandler(a.ID, a, a)
+	}
+}
+
+func (s *Simulator) processFaults(dt float64) {
+	for _, a := range s.actors {
+		if a.crashed {
+			if a.restartAt > 0 && s.simTime >= a.restartAt {
+				s.restartActor(a)
+			}
+			continue
+		}
+		group, _ := a.metainfo["group"].(string)
+		sch, ok := s.faultSchedules[group]
+		if !ok {
+			if sch, ok = s.faultSchedules[AllActorsGroup]; !ok {
+				continue
+			}
+		}
+		if rand.Float64() < dt/sch.MTBF {
+			// restart time must be positive, otherwise the actor would stay down forever
+			s.crashActor(a, rand.ExpFloat64()*sch.MTTR+dt)
+		}
+	}
+}
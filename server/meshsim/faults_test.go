@@ -0,0 +1,242 @@
+package meshsim_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// neighbourRecorder is a peer runtime which only keeps track of neighbours it is told about
+type neighbourRecorder struct {
+	mtx   sync.Mutex
+	peers map[meshpeer.NetworkID]struct{}
+}
+
+func bindNeighbourRecorder(api meshpeer.MeshAPI) *neighbourRecorder {
+	r := &neighbourRecorder{peers: map[meshpeer.NetworkID]struct{}{}}
+	api.RegisterPeerAppearedHandler(func(id meshpeer.NetworkID) {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		r.peers[id] = struct{}{}
+	})
+	api.RegisterPeerDisappearedHandler(func(id meshpeer.NetworkID) {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		delete(r.peers, id)
+	})
+	return r
+}
+
+func (r *neighbourRecorder) count() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.peers)
+}
+
+// newPausedSim returns paused simulation of count actors at the same place, each of them
+// is in range of the rest
+func newPausedSim(t *testing.T, count int) (*meshsim.Simulator, []meshpeer.MeshAPI) {
+	sim := meshsim.New(meshlog.Discard())
+	p := meshsim.DefaultParams()
+	p.MaxPeers = count
+	if err := sim.SetParams(p); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	sim.Pause()
+	apis := []meshpeer.MeshAPI{}
+	for i := 0; i < count; i++ {
+		api, _ := sim.AddActorWithOptions(p.DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+		apis = append(apis, api)
+	}
+	return sim, apis
+}
+
+func step(t *testing.T, sim *meshsim.Simulator) {
+	if _, err := sim.Step(1); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+}
+
+func TestRuntimeBoundLateSeesNeighbours(t *testing.T) {
+	const actors = 5
+	tests := []struct {
+		name string
+		// rebind takes the actor down and returns APIs its new runtime binds to, the
+		// simulation ticks before it does
+		rebind func(t *testing.T, sim *meshsim.Simulator, id meshpeer.NetworkID, restarted <-chan meshpeer.MeshAPI) meshpeer.MeshAPI
+	}{
+		{"restart after crash", func(t *testing.T, sim *meshsim.Simulator, id meshpeer.NetworkID, restarted <-chan meshpeer.MeshAPI) meshpeer.MeshAPI {
+			if err := sim.CrashActor(id, 0); err != nil {
+				t.Fatalf("CrashActor: %v", err)
+			}
+			step(t, sim)
+			if err := sim.RestartActor(id); err != nil {
+				t.Fatalf("RestartActor: %v", err)
+			}
+			select {
+			case api := <-restarted:
+				return api
+			case <-time.After(5 * time.Second):
+				t.Fatal("restart handler is not called")
+			}
+			return nil
+		}},
+		{"reboot", func(t *testing.T, sim *meshsim.Simulator, id meshpeer.NetworkID, restarted <-chan meshpeer.MeshAPI) meshpeer.MeshAPI {
+			api, _, err := sim.RebootActor(id)
+			if err != nil {
+				t.Fatalf("RebootActor: %v", err)
+			}
+			return api
+		}},
+		{"fresh actor", func(t *testing.T, sim *meshsim.Simulator, id meshpeer.NetworkID, restarted <-chan meshpeer.MeshAPI) meshpeer.MeshAPI {
+			sim.RemoveActor(id)
+			api, _ := sim.AddActorWithOptions(sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+			return api
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, apis := newPausedSim(t, actors)
+			defer sim.Stop()
+			restarted := make(chan meshpeer.MeshAPI, 1)
+			sim.SetRestartHandler(func(id meshpeer.NetworkID, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) {
+				restarted <- meshAPI
+			})
+			for _, api := range apis {
+				bindNeighbourRecorder(api)
+			}
+			step(t, sim)
+
+			api := tt.rebind(t, sim, apis[0].GetMyID(), restarted)
+			// links are made before the new runtime registers its handlers
+			step(t, sim)
+			r := bindNeighbourRecorder(api)
+			step(t, sim)
+			if n := r.count(); n != actors-1 {
+				t.Errorf("runtime sees %v neighbours, want %v", n, actors-1)
+			}
+		})
+	}
+}
+
+func TestCrashActor(t *testing.T) {
+	tests := []struct {
+		name         string
+		restartAfter float64
+		steps        int
+		wantDown     bool
+	}{
+		{"stays down", 0, 20, true},
+		{"down until restart time", 1, 20, true},
+		// 0.1 second is 5 ticks of 20ms
+		{"restarted after restart time", 0.1, 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, apis := newPausedSim(t, 3)
+			defer sim.Stop()
+			restarted := make(chan meshpeer.NetworkID, 1)
+			sim.SetRestartHandler(func(id meshpeer.NetworkID, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) {
+				restarted <- id
+			})
+			neighbour := bindNeighbourRecorder(apis[1])
+			step(t, sim)
+			id := apis[0].GetMyID()
+
+			if err := sim.CrashActor(id, tt.restartAfter); err != nil {
+				t.Fatalf("CrashActor: %v", err)
+			}
+			if n := neighbour.count(); n != 1 {
+				t.Errorf("neighbour sees %v peers after crash, want 1", n)
+			}
+			if err := sim.CrashActor(id, 0); err == nil {
+				t.Error("crashed actor crashed again")
+			}
+			if _, err := sim.Step(tt.steps); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			if info, _ := sim.GetActor(id); info.Down != tt.wantDown {
+				t.Errorf("Down = %v, want %v", info.Down, tt.wantDown)
+			}
+			if tt.wantDown {
+				select {
+				case <-restarted:
+					t.Error("restart handler is called for actor which is down")
+				default:
+				}
+				return
+			}
+			select {
+			case got := <-restarted:
+				if got != id {
+					t.Errorf("restart handler got %v, want %v", got, id)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("restart handler is not called")
+			}
+			if err := sim.RestartActor(id); err == nil {
+				t.Error("actor which is up restarted")
+			}
+		})
+	}
+}
+
+func TestFaultErrors(t *testing.T) {
+	sim, apis := newPausedSim(t, 1)
+	defer sim.Stop()
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"crash unknown actor", func() error { return sim.CrashActor("nobody", 0) }},
+		{"restart unknown actor", func() error { return sim.RestartActor("nobody") }},
+		{"restart actor which is up", func() error { return sim.RestartActor(apis[0].GetMyID()) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
+
+func TestFaultSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		group    string
+		unset    bool
+		wantDown []bool
+	}{
+		{"group", "fragile", false, []bool{true, false}},
+		{"all actors", meshsim.AllActorsGroup, false, []bool{true, true}},
+		{"disabled", "fragile", true, []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := meshsim.New(meshlog.Discard())
+			defer sim.Stop()
+			sim.Pause()
+			ids := []meshpeer.NetworkID{}
+			for _, group := range []string{"fragile", "solid"} {
+				api, _ := sim.AddActor(sim.Params().DefaultCoord, map[string]interface{}{"group": group})
+				ids = append(ids, api.GetMyID())
+			}
+			// every actor of the group crashes on the first tick and stays down for long
+			sim.SetFaultSchedule(tt.group, 1e-9, 1e9)
+			if tt.unset {
+				sim.SetFaultSchedule(tt.group, 0, 0)
+			}
+			step(t, sim)
+			for i, id := range ids {
+				if info, _ := sim.GetActor(id); info.Down != tt.wantDown[i] {
+					t.Errorf("actor %v Down = %v, want %v", i, info.Down, tt.wantDown[i])
+				}
+			}
+		})
+	}
+}
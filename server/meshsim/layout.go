@@ -0,0 +1,104 @@
+package meshsim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// layoutMaxAttempts limits random point picks per placed point in LayoutPolygon
+const layoutMaxAttempts = 1000
+
+// offsetCoord returns point given meters away from coord along heading in degrees clockwise from north
+func offsetCoord(coord [2]float64, meters float64, heading float64) [2]float64 {
+	h := heading * math.Pi / 180
+	return [2]float64{
+		coord[0] + meters*math.Cos(h)/earthRadius*180/math.Pi,
+		coord[1] + meters*math.Sin(h)/(earthRadius*math.Cos(coord[0]*math.Pi/180))*180/math.Pi,
+	}
+}
+
+// LayoutLine places count points starting at start, spacing meters apart along heading
+// in degrees clockwise from north
+func LayoutLine(start [2]float64, count int, spacing float64, heading float64) [][2]float64 {
+	ret := [][2]float64{}
+	for i := 0; i < count; i++ {
+		ret = append(ret, offsetCoord(start, float64(i)*spacing, heading))
+	}
+	return ret
+}
+
+// LayoutGrid places rows*cols points spacing meters apart, origin is the north-west corner,
+// rows go south and columns go east
+func LayoutGrid(origin [2]float64, rows int, cols int, spacing float64) [][2]float64 {
+	ret := [][2]float64{}
+	for r := 0; r < rows; r++ {
+		rowStart := offsetCoord(origin, float64(r)*spacing, 180)
+		ret = append(ret, LayoutLine(rowStart, cols, spacing, 90)...)
+	}
+	return ret
+}
+
+// LayoutRing places count points around center, neighbouring points are spacing meters apart.
+// The first point is north of center
+func LayoutRing(center [2]float64, count int, spacing float64) [][2]float64 {
+	ret := [][2]float64{}
+	if count == 1 {
+		return append(ret, center)
+	}
+	radius := spacing / (2 * math.Sin(math.Pi/float64(count)))
+	for i := 0; i < count; i++ {
+		ret = append(ret, offsetCoord(center, radius, 360*float64(i)/float64(count)))
+	}
+	return ret
+}
+
+// LayoutPolygon places count points uniformly at random inside polygon given by its vertices,
+// at least minSpacing meters from each other. It fails if the polygon is too small to fit them
+func LayoutPolygon(polygon [][2]float64, count int, minSpacing float64, rnd *rand.Rand) ([][2]float64, error) {
+	if len(polygon) < 3 {
+		return nil, fmt.Errorf("Polygon needs at least 3 vertices")
+	}
+	min, max := polygon[0], polygon[0]
+	for _, v := range polygon {
+		min = [2]float64{math.Min(min[0], v[0]), math.Min(min[1], v[1])}
+		max = [2]float64{math.Max(max[0], v[0]), math.Max(max[1], v[1])}
+	}
+
+	ret := [][2]float64{}
+	for len(ret) < count {
+		placed := false
+		for attempt := 0; attempt < layoutMaxAttempts && !placed; attempt++ {
+			p := [2]float64{min[0] + rnd.Float64()*(max[0]-min[0]), min[1] + rnd.Float64()*(max[1]-min[1])}
+			if !insidePolygon(p, polygon) {
+				continue
+			}
+			placed = true
+			for _, q := range ret {
+				if distance(p, q) < minSpacing {
+					placed = false
+					break
+				}
+			}
+			if placed {
+				ret = append(ret, p)
+			}
+		}
+		if !placed {
+			return nil, fmt.Errorf("Cannot fit %v points %v m apart into polygon, placed %v", count, minSpacing, len(ret))
+		}
+	}
+	return ret, nil
+}
+
+// insidePolygon is the even-odd ray casting test, fine for the small areas simulated here
+func insidePolygon(p [2]float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a[0] > p[0]) != (b[0] > p[0]) && p[1] < (b[1]-a[1])*(p[0]-a[0])/(b[0]-a[0])+a[1] {
+			inside = !inside
+		}
+	}
+	return inside
+}
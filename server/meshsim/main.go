@@ -2,13 +2,15 @@ package meshsim
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"math/rand"
+	"path/filepath"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"mesh-simulator/meshlog"
 	"mesh-simulator/meshpeer"
 
 	"github.com/google/uuid"
@@ -16,87 +18,239 @@ import (
 
 // Simulator provides the core for mesh network simulator
 type Simulator struct {
-	logger *log.Logger
+	// simTimeBits mirrors simTime for SimTime, which is read without locking. It comes first
+	// to be aligned for atomic access
+	simTimeBits uint64
+
+	logger *meshlog.Logger
 	mtx    *sync.RWMutex
 
 	actors map[meshpeer.NetworkID]*actorPhysics
 
 	simTime float64
 
-	timeRatio float64
+	params Params
 
-	totalMsgSendCounter int
+	totalMsgSendCounter int64
 
 	lastStatusTime float64
+
+	storageDir   string
+	storageQuota int
+
+	paused  bool
+	stopped bool
+	// stopCh is closed by Stop and paramsChanged is signalled by SetParams, both wake tick loop
+	// waiting for the next tick
+	stopCh        chan struct{}
+	paramsChanged chan struct{}
+	// runDone is closed when tick loop started by Run returns
+	runDone chan struct{}
+
+	faultSchedules map[string]FaultSchedule
+	restartHandler func(id meshpeer.NetworkID, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI)
 }
 
-// AddActor adds generic peer to simulation and returns it's id
+// ActorOptions tunes actor placement
+type ActorOptions struct {
+	// Exact puts actor right at the given place, without random offset and jitter,
+	// so it stays there unless moved
+	Exact bool
+	// Owner is the user who created the actor, empty for actors nobody owns
+	Owner string
+}
+
+// AddActor adds generic peer to simulation near given place and returns it's id
 func (s *Simulator) AddActor(placeToAdd [2]float64, metainfo map[string]interface{}) (meshpeer.MeshAPI, meshpeer.FrontendAPI) {
+	return s.AddActorWithOptions(placeToAdd, metainfo, ActorOptions{})
+}
+
+// AddActorWithOptions adds generic peer to simulation and returns it's id
+func (s *Simulator) AddActorWithOptions(placeToAdd [2]float64, metainfo map[string]interface{}, options ActorOptions) (meshpeer.MeshAPI, meshpeer.FrontendAPI) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	rndLat := rand.NormFloat64() * 0.00045
-	rndLon := rand.NormFloat64() * 0.00045
+	rndLat, rndLon := 0.0, 0.0
+	if !options.Exact {
+		rndLat = rand.NormFloat64() * s.params.SpawnSpread
+		rndLon = rand.NormFloat64() * s.params.SpawnSpread
+	}
+
+	na := s.newActor(meshpeer.NetworkID(uuid.New().String())[0:8], metainfo)
+	na.owner = options.Owner
+	na.Coord = [2]float64{placeToAdd[0] + rndLat, placeToAdd[1] + rndLon}
+	for i := 0; i < 3 && !options.Exact; i++ {
+		na.randomAmpl[i] = rand.Float64() * s.params.JitterAmplitude
+		na.randomFreq[i] = rand.Float64() * s.params.JitterFrequency
+		na.randomPhase[i] = rand.Float64() * 2 * math.Pi
+	}
+	na.startCoord[0] = na.Coord[0]
+	na.startCoord[1] = na.Coord[1]
 
-	newID := meshpeer.NetworkID(uuid.New().String())[0:8]
-	na := actorPhysics{
-		ID:               newID,
-		Coord:            [2]float64{placeToAdd[0] + rndLat, placeToAdd[1] + rndLon},
+	s.actors[na.ID] = na
+	return na, na
+}
+
+// newActor returns actor with given ID standing at zero coordinates, it is not added to simulation
+func (s *Simulator) newActor(id meshpeer.NetworkID, metainfo map[string]interface{}) *actorPhysics {
+	na := &actorPhysics{
+		ID:               id,
 		currentPeers:     make(map[meshpeer.NetworkID]struct{}),
 		outgoingMsgQueue: make(map[meshpeer.NetworkID][]meshpeer.NetworkMessage),
 		mtx:              &sync.Mutex{},
 		metainfo:         metainfo,
+		storage:          s.newStorage(id),
 	}
 	na.sender = func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
 		na.mtx.Lock()
 		defer na.mtx.Unlock()
+		if na.crashed {
+			return
+		}
 
-		s.totalMsgSendCounter++
+		atomic.AddInt64(&s.totalMsgSendCounter, 1)
 		if _, ok := na.outgoingMsgQueue[id]; !ok {
 			na.outgoingMsgQueue[id] = []meshpeer.NetworkMessage{}
 		}
 		na.outgoingMsgQueue[id] = append(na.outgoingMsgQueue[id], data)
 	}
-	for i := 0; i < 3; i++ {
-		na.randomAmpl[i] = rand.Float64() * 0.0002
-		na.randomFreq[i] = rand.Float64() * 0.01
-		na.randomPhase[i] = rand.Float64() * 2 * math.Pi
+	na.resetHandlers()
+	return na
+}
+
+func (s *Simulator) newStorage(id meshpeer.NetworkID) *meshpeer.KVStorage {
+	path := ""
+	if s.storageDir != "" {
+		path = filepath.Join(s.storageDir, string(id)+".json")
 	}
-	na.startCoord[0] = na.Coord[0]
-	na.startCoord[1] = na.Coord[1]
+	st, err := meshpeer.NewKVStorage(path, s.storageQuota)
+	if err != nil {
+		s.logger.Warn("Cannot load storage", meshlog.KeyPeer, id, meshlog.KeyError, err)
+		st, _ = meshpeer.NewKVStorage("", s.storageQuota)
+	}
+	return st
+}
 
-	s.actors[na.ID] = &na
+// SetStorage configures storage of actors added after this call. If dir is not empty,
+// storage of every actor is persisted to a file in it. quota limits storage size in bytes
+func (s *Simulator) SetStorage(dir string, quota int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 
-	na.peerAppearedHandler = func(meshpeer.NetworkID) {}
-	na.peerDisappearedHandler = func(meshpeer.NetworkID) {}
-	na.messageHandler = func(meshpeer.NetworkID, meshpeer.NetworkMessage) {}
-	na.timeTickHandler = func(meshpeer.NetworkTime) {}
-	na.userDataSetter = func(interface{}) {}
-	return &na, &na
+	s.storageDir = dir
+	s.storageQuota = quota
 }
 
-// RemoveActor removes peer from simulation by it's ID
-func (s *Simulator) RemoveActor(id meshpeer.NetworkID) {
+// RebootActor simulates peer reboot: all handlers registered by peer code are dropped together
+// with current links and queued messages, while position and storage are kept. Returned APIs
+// are to be passed to the new peer runtime
+func (s *Simulator) RebootActor(id meshpeer.NetworkID) (meshpeer.MeshAPI, meshpeer.FrontendAPI, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	if _, ok := s.actors[id]; ok {
+	a, ok := s.actors[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("Actor not found")
+	}
+	a.mtx.Lock()
+	a.outgoingMsgQueue = make(map[meshpeer.NetworkID][]meshpeer.NetworkMessage)
+	a.debugData = nil
+	a.peerDebugData = nil
+	a.health = nil
+	a.mtx.Unlock()
+	a.currentPeers = make(map[meshpeer.NetworkID]struct{})
+	a.resetHandlers()
+	return a, a, nil
+}
+
+// RemoveActor removes peer from simulation by it's ID. Its neighbours get disappeared event
+// right away, peer runtime bound with BindLifecycle is stopped and closed and its storage
+// file is deleted
+func (s *Simulator) RemoveActor(id meshpeer.NetworkID) {
+	s.mtx.Lock()
+	a, ok := s.actors[id]
+	if !ok {
+		s.mtx.Unlock()
+		return
+	}
+	delete(s.actors, id)
+	s.dropLinksTo(id)
+	for _, b := range s.actors {
+		b.mtx.Lock()
+		delete(b.outgoingMsgQueue, id)
+		b.mtx.Unlock()
+	}
+	a.resetHandlers()
+	l := a.unbindLifecycle()
+	if l != nil {
+		l.Stop()
+	}
+	s.mtx.Unlock()
+
+	if l != nil {
+		if err := l.Close(); err != nil {
+			s.logger.Warn("Closing actor runtime failed", meshlog.KeyPeer, id, meshlog.KeyError, err)
+		}
+	}
+	if err := a.storage.Remove(); err != nil {
+		s.logger.Warn("Cannot delete storage", meshlog.KeyPeer, id, meshlog.KeyError, err)
+	}
+}
+
+// removeAllActors removes every actor without notifying anyone and returns their stopped runtimes
+// to be closed with closeRuntimes once s.mtx is released, together with their storages.
+// s.mtx must be held
+func (s *Simulator) removeAllActors() ([]meshpeer.Lifecycle, []*meshpeer.KVStorage) {
+	runtimes := []meshpeer.Lifecycle{}
+	storages := []*meshpeer.KVStorage{}
+	for id, a := range s.actors {
+		a.resetHandlers()
+		if l := a.unbindLifecycle(); l != nil {
+			l.Stop()
+			runtimes = append(runtimes, l)
+		}
+		storages = append(storages, a.storage)
 		delete(s.actors, id)
 	}
+	return runtimes, storages
+}
+
+func (s *Simulator) closeRuntimes(runtimes []meshpeer.Lifecycle) {
+	for _, l := range runtimes {
+		if err := l.Close(); err != nil {
+			s.logger.Warn("Closing actor runtime failed", meshlog.KeyError, err)
+		}
+	}
+}
+
+// dropLinksTo removes actor from peers of all other actors, notifying them. s.mtx must be held
+func (s *Simulator) dropLinksTo(id meshpeer.NetworkID) {
+	for _, b := range s.actors {
+		if _, ok := b.currentPeers[id]; ok {
+			delete(b.currentPeers, id)
+			b.callbacks().peerDisappeared(id)
+		}
+	}
 }
 
 // Overview stores high level information about current simulation state
 type Overview struct {
 	TS     int64
-	Actors map[string]actorInfo
+	Actors map[string]ActorInfo
 }
 
-type actorInfo struct {
+// ActorInfo describes state of one actor
+type ActorInfo struct {
 	ID           string
 	Coord        [2]float64
 	Peers        []string
 	Meta         map[string]interface{}
 	CurrentState interface{}
+	DebugData    interface{}
+	Health       *meshpeer.PeerHealth
+	Down         bool
+	Motion       ActorMotion
+	Owner        string `json:",omitempty"`
 }
 
 // GetOverview return current state overview
@@ -105,19 +259,69 @@ func (s *Simulator) GetOverview() Overview {
 	defer s.mtx.RUnlock()
 
 	ret := Overview{}
-	ret.Actors = make(map[string]actorInfo)
+	ret.Actors = make(map[string]ActorInfo)
 	ret.TS = time.Now().UnixNano() / 1000000
 	for _, e := range s.actors {
-		prs := []string{}
-		for p := range e.currentPeers {
-			prs = append(prs, string(p))
-		}
-		ret.Actors[string(e.ID)] = actorInfo{string(e.ID), e.Coord, prs, e.metainfo, e.debugData}
+		ret.Actors[string(e.ID)] = e.info()
 	}
 
 	return ret
 }
 
+// CountActors returns number of actors in simulation and how many of them owner has
+func (s *Simulator) CountActors(owner string) (int, int) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	owned := 0
+	for _, a := range s.actors {
+		if owner != "" && a.owner == owner {
+			owned++
+		}
+	}
+	return len(s.actors), owned
+}
+
+// GetActor returns state of actor with given ID
+func (s *Simulator) GetActor(id meshpeer.NetworkID) (ActorInfo, bool) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	a, ok := s.actors[id]
+	if !ok {
+		return ActorInfo{}, false
+	}
+	return a.info(), true
+}
+
+// UpdateActorMeta merges meta into actor metainfo, keys with nil values are deleted
+func (s *Simulator) UpdateActorMeta(id meshpeer.NetworkID, meta map[string]interface{}) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	a, ok := s.actors[id]
+	if !ok {
+		return fmt.Errorf("Actor not found")
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	// metainfo map may be shared with overview readers, so it is replaced, not modified
+	newMeta := make(map[string]interface{}, len(a.metainfo)+len(meta))
+	for k, v := range a.metainfo {
+		newMeta[k] = v
+	}
+	for k, v := range meta {
+		if v == nil {
+			delete(newMeta, k)
+		} else {
+			newMeta[k] = v
+		}
+	}
+	a.metainfo = newMeta
+	return nil
+}
+
 func hsin(theta float64) float64 {
 	return math.Pow(math.Sin(theta/2), 2)
 }
@@ -169,7 +373,7 @@ func (s *Simulator) findPeerActorsIDs(id meshpeer.NetworkID, maxDist float64, ma
 
 	dists := peerDists{}
 	for pID, a := range s.actors {
-		if pID == id {
+		if pID == id || a.crashed {
 			continue
 		}
 		dist := distance(s.actors[id].Coord, a.Coord)
@@ -187,98 +391,232 @@ func (s *Simulator) findPeerActorsIDs(id meshpeer.NetworkID, maxDist float64, ma
 
 	return ret
 }
-func (s *Simulator) run() {
-	var dt float64 = 0.020
 
+func (s *Simulator) run() {
+	s.mtx.RLock()
+	interval := s.tickInterval()
+	s.mtx.RUnlock()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	last := time.Now()
 	for {
-		time.Sleep(time.Duration(dt*1000.0*s.timeRatio) * time.Millisecond)
+		select {
+		case <-s.stopCh:
+			return
+		case <-s.paramsChanged:
+			// new interval counts from the last tick rather than from now
+			if !timer.Stop() {
+				<-timer.C
+			}
+			s.mtx.RLock()
+			interval = s.tickInterval()
+			s.mtx.RUnlock()
+			timer.Reset(interval - time.Since(last))
+			continue
+		case <-timer.C:
+		}
 		s.mtx.Lock()
+		if s.stopped {
+			s.mtx.Unlock()
+			return
+		}
+		if !s.paused {
+			s.tick(s.params.Dt)
+		}
+		interval = s.tickInterval()
+		s.mtx.Unlock()
+		last = time.Now()
+		timer.Reset(interval)
+	}
+}
 
-		for _, a := range s.actors {
-
-			a.Coord[0] = a.startCoord[0]
-			a.Coord[1] = a.startCoord[1]
-
-			for i := 0; i < 3; i++ {
-				a.Coord[0] += math.Sin(2*math.Pi*a.randomFreq[i]*s.simTime+a.randomPhase[i]) * a.randomAmpl[i]
-				a.Coord[1] += math.Cos(2*math.Pi*a.randomFreq[i]*s.simTime+a.randomPhase[i]) * a.randomAmpl[i]
-			}
+// tick advances simulation by dt seconds. s.mtx must be held
+func (s *Simulator) tick(dt float64) {
+	s.processFaults(dt)
+	for _, a := range s.actors {
+		a.mtx.Lock()
+		a.move(s.simTime, dt)
+		h := a.handlers
+		announce := a.announcePeers && !a.crashed
+		if announce {
+			a.announcePeers = false
+		}
+		a.mtx.Unlock()
+		if a.crashed {
+			continue
+		}
+		if announce {
+			// links the handler has not been told about are made anew
+			a.currentPeers = make(map[meshpeer.NetworkID]struct{})
+		}
 
-			newPeers := s.findPeerActorsIDs(a.ID, 50, 5)
-			appeared, disappeared := difference(a.currentPeers, newPeers)
-			a.timeTickHandler(meshpeer.NetworkTime(s.simTime * 1000000))
+		newPeers := s.findPeerActorsIDs(a.ID, s.params.RadioRange, s.params.MaxPeers)
+		appeared, disappeared := difference(a.currentPeers, newPeers)
+		h.timeTick(meshpeer.NetworkTime(s.simTime * 1000000))
 
-			type PeerUserState struct {
-				Coordinates []float64
-				Message     string
-			}
+		type PeerUserState struct {
+			Coordinates []float64
+			Message     string
+		}
 
-			if s.simTime-a.userInterestingEventTime > 10 && s.simTime >= a.nextUserSimulationSentTime {
-				a.nextUserSimulationSentTime = s.simTime
-				a.userDataSetter(PeerUserState{
-					Coordinates: []float64(a.Coord[:]),
-					Message:     fmt.Sprintf("It's boring for %vs", int(s.simTime-a.userInterestingEventTime)),
-				})
-				a.nextUserSimulationSentTime += rand.Float64()*8.0 + 3.0
-			}
-			for _, app := range appeared {
-				a.userInterestingEventTime = s.simTime
-				a.peerAppearedHandler(app)
-				a.userDataSetter(PeerUserState{
-					Coordinates: []float64(a.Coord[:]),
-					Message:     fmt.Sprintf("Hi, %v!", app),
-				})
-			}
+		if s.simTime-a.userInterestingEventTime > 10 && s.simTime >= a.nextUserSimulationSentTime {
+			a.nextUserSimulationSentTime = s.simTime
+			h.userData(PeerUserState{
+				Coordinates: []float64(a.Coord[:]),
+				Message:     fmt.Sprintf("It's boring for %vs", int(s.simTime-a.userInterestingEventTime)),
+			})
+			a.nextUserSimulationSentTime += rand.Float64()*8.0 + 3.0
+		}
+		for _, app := range appeared {
+			a.userInterestingEventTime = s.simTime
+			h.peerAppeared(app)
+			h.userData(PeerUserState{
+				Coordinates: []float64(a.Coord[:]),
+				Message:     fmt.Sprintf("Hi, %v!", app),
+			})
+		}
 
-			for _, dis := range disappeared {
-				a.userInterestingEventTime = s.simTime
-				a.peerDisappearedHandler(dis)
-				a.userDataSetter(PeerUserState{
-					Coordinates: []float64(a.Coord[:]),
-					Message:     fmt.Sprintf("Bye, %v!", dis),
-				})
-			}
-			a.currentPeers = newPeers
-
-			for trgID, msgList := range a.outgoingMsgQueue {
-				if peer, found := s.actors[trgID]; found {
-					if _, found := a.currentPeers[trgID]; found {
-						for _, msg := range msgList {
-							peer.messageHandler(a.ID, msg)
-						}
+		for _, dis := range disappeared {
+			a.userInterestingEventTime = s.simTime
+			h.peerDisappeared(dis)
+			h.userData(PeerUserState{
+				Coordinates: []float64(a.Coord[:]),
+				Message:     fmt.Sprintf("Bye, %v!", dis),
+			})
+		}
+		a.currentPeers = newPeers
+
+		// peers send messages outside of simulator lock, so the queue is guarded by a.mtx
+		a.mtx.Lock()
+		outgoing := a.outgoingMsgQueue
+		a.outgoingMsgQueue = make(map[meshpeer.NetworkID][]meshpeer.NetworkMessage)
+		a.mtx.Unlock()
+		for trgID, msgList := range outgoing {
+			if peer, found := s.actors[trgID]; found {
+				if _, found := a.currentPeers[trgID]; found {
+					handler := peer.callbacks().message
+					for _, msg := range msgList {
+						handler(a.ID, msg)
 					}
 				}
 			}
-			a.outgoingMsgQueue = make(map[meshpeer.NetworkID][]meshpeer.NetworkMessage)
 		}
-		s.simTime += dt
+	}
+	s.setSimTime(s.simTime + dt)
 
-		if s.simTime-s.lastStatusTime > 1 {
-			s.lastStatusTime = s.simTime
-			s.logger.Println("Total messages sent: ", s.totalMsgSendCounter)
-		}
-		s.mtx.Unlock()
+	if s.simTime-s.lastStatusTime > 1 {
+		s.lastStatusTime = s.simTime
+		s.logger.Debug("Simulation status", "actors", len(s.actors), "messages_sent", atomic.LoadInt64(&s.totalMsgSendCounter))
 	}
 }
 
-// New creates and start new simulation
-func New(logger *log.Logger) *Simulator {
-	n := Simulator{
-		logger:              logger,
+// setSimTime changes simulation time. s.mtx must be held
+func (s *Simulator) setSimTime(t float64) {
+	s.simTime = t
+	atomic.StoreUint64(&s.simTimeBits, math.Float64bits(t))
+}
+
+// SimTime returns simulation time in seconds. Unlike State it does not lock simulation, so it
+// may be called from actor handlers
+func (s *Simulator) SimTime() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.simTimeBits))
+}
+
+// New creates and start new simulation, its records are logged with sim_time
+func New(logger *meshlog.Logger) *Simulator {
+	n := &Simulator{
 		mtx:                 &sync.RWMutex{},
 		actors:              map[meshpeer.NetworkID]*actorPhysics{},
 		simTime:             0,
-		timeRatio:           1,
+		params:              DefaultParams(),
 		totalMsgSendCounter: 0,
 		lastStatusTime:      0,
+		storageQuota:        meshpeer.DefaultStorageQuota,
+		faultSchedules:      map[string]FaultSchedule{},
+		stopCh:              make(chan struct{}),
+		paramsChanged:       make(chan struct{}, 1),
 	}
+	n.logger = logger.WithClock(n.SimTime)
 
-	return &n
+	return n
 }
 
 // Run starts simulation
 func (s *Simulator) Run() {
-	go s.run()
+	s.mtx.Lock()
+	done := make(chan struct{})
+	s.runDone = done
+	s.mtx.Unlock()
+
+	go func() {
+		defer close(done)
+		s.run()
+	}()
+}
+
+// Stop ends simulation: time stops for good and all actors are removed, their runtimes bound
+// with BindLifecycle are stopped and closed and pending storage modifications are saved. It
+// returns once tick loop has exited, so it must not be called from peer callbacks
+func (s *Simulator) Stop() {
+	s.mtx.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopCh)
+	}
+	closing, storages := s.removeAllActors()
+	done := s.runDone
+	s.mtx.Unlock()
+
+	s.closeRuntimes(closing)
+	for _, st := range storages {
+		if err := st.Close(); err != nil {
+			s.logger.Warn("Cannot save storage", meshlog.KeyError, err)
+		}
+	}
+	if done != nil {
+		<-done
+	}
+}
+
+// Pause stops simulation time, peers get no ticks, links and messages until Resume or Step
+func (s *Simulator) Pause() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.paused = true
+}
+
+// Resume continues paused simulation
+func (s *Simulator) Resume() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.paused = false
+}
+
+// Step advances paused simulation by given number of ticks and returns simulation time in seconds
+func (s *Simulator) Step(ticks int) (float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stopped {
+		return s.simTime, fmt.Errorf("Simulation is stopped")
+	}
+	if !s.paused {
+		return s.simTime, fmt.Errorf("Simulation is not paused")
+	}
+	for i := 0; i < ticks; i++ {
+		s.tick(s.params.Dt)
+	}
+	return s.simTime, nil
+}
+
+// State returns whether simulation is paused and current simulation time in seconds
+func (s *Simulator) State() (paused bool, simTime float64) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.paused, s.simTime
 }
 
 // SendMessage send message from given peer to given peers. If target peers are empty, sends to all available peers(broadcast)
@@ -286,6 +624,9 @@ func (s *Simulator) SendMessage(ID meshpeer.NetworkID, targets []meshpeer.Networ
 	if srcPeer, ok := s.actors[ID]; ok {
 		srcPeer.mtx.Lock()
 		defer srcPeer.mtx.Unlock()
+		if srcPeer.crashed {
+			return fmt.Errorf("Source peer is down")
+		}
 		if len(targets) == 0 {
 			for trg := range srcPeer.currentPeers {
 				targets = append(targets, trg)
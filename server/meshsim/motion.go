@@ -0,0 +1,104 @@
+package meshsim
+
+import (
+	"fmt"
+	"math"
+
+	"mesh-simulator/meshpeer"
+)
+
+// earthRadius in meters, the same one distance uses
+const earthRadius = 6378100
+
+// ActorMotion describes how actor moves. Actor position is its anchor plus small random jitter,
+// the anchor drifts with Speed along Heading. Frozen actor does not move at all
+type ActorMotion struct {
+	Anchor [2]float64
+	// Speed in meters per second
+	Speed float64
+	// Heading in degrees clockwise from north
+	Heading float64
+	Frozen  bool
+}
+
+// jitter returns random walk offset of the actor from its anchor at given time
+func (th *actorPhysics) jitter(simTime float64) [2]float64 {
+	ret := [2]float64{}
+	for i := 0; i < 3; i++ {
+		ret[0] += math.Sin(2*math.Pi*th.randomFreq[i]*simTime+th.randomPhase[i]) * th.randomAmpl[i]
+		ret[1] += math.Cos(2*math.Pi*th.randomFreq[i]*simTime+th.randomPhase[i]) * th.randomAmpl[i]
+	}
+	return ret
+}
+
+// move advances actor position by dt seconds, th.mtx must be held
+func (th *actorPhysics) move(simTime float64, dt float64) {
+	if th.frozen {
+		return
+	}
+	if th.speed != 0 {
+		th.startCoord = offsetCoord(th.startCoord, th.speed*dt, th.heading)
+	}
+	j := th.jitter(simTime)
+	th.Coord[0] = th.startCoord[0] + j[0]
+	th.Coord[1] = th.startCoord[1] + j[1]
+}
+
+func (th *actorPhysics) motion() ActorMotion {
+	return ActorMotion{th.startCoord, th.speed, th.heading, th.frozen}
+}
+
+// withActor runs f on actor under its mutex
+func (s *Simulator) withActor(id meshpeer.NetworkID, f func(a *actorPhysics)) error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	a, ok := s.actors[id]
+	if !ok {
+		return fmt.Errorf("Actor not found")
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	f(a)
+	return nil
+}
+
+// TeleportActor puts actor exactly at coord, it keeps moving from there
+func (s *Simulator) TeleportActor(id meshpeer.NetworkID, coord [2]float64) error {
+	return s.withActor(id, func(a *actorPhysics) {
+		j := a.jitter(s.simTime)
+		a.startCoord = [2]float64{coord[0] - j[0], coord[1] - j[1]}
+		a.Coord = coord
+	})
+}
+
+// SetActorAnchor sets the point actor jitters around, it jumps there on the next tick
+func (s *Simulator) SetActorAnchor(id meshpeer.NetworkID, coord [2]float64) error {
+	return s.withActor(id, func(a *actorPhysics) {
+		a.startCoord = coord
+	})
+}
+
+// SetActorVelocity makes actor anchor drift with speed in meters per second along heading
+// in degrees clockwise from north. Zero speed stops it
+func (s *Simulator) SetActorVelocity(id meshpeer.NetworkID, speed float64, heading float64) error {
+	if speed < 0 || math.IsNaN(speed) || math.IsInf(speed, 0) || math.IsNaN(heading) || math.IsInf(heading, 0) {
+		return fmt.Errorf("Bad velocity")
+	}
+	return s.withActor(id, func(a *actorPhysics) {
+		a.speed = speed
+		a.heading = math.Mod(heading, 360)
+	})
+}
+
+// FreezeActor stops or resumes all actor movement. Unfrozen actor continues from where it stood
+func (s *Simulator) FreezeActor(id meshpeer.NetworkID, frozen bool) error {
+	return s.withActor(id, func(a *actorPhysics) {
+		if a.frozen && !frozen {
+			j := a.jitter(s.simTime)
+			a.startCoord = [2]float64{a.Coord[0] - j[0], a.Coord[1] - j[1]}
+		}
+		a.frozen = frozen
+	})
+}
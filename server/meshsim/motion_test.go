@@ -0,0 +1,175 @@
+package meshsim_test
+
+import (
+	"math"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// metersPerDegree of latitude, the same earth radius simulator uses
+const metersPerDegree = 6378100 * math.Pi / 180
+
+// newStillSim returns paused simulation with one actor which does not jitter
+func newStillSim(t *testing.T) (*meshsim.Simulator, meshpeer.NetworkID) {
+	sim := meshsim.New(meshlog.Discard())
+	p := meshsim.DefaultParams()
+	p.JitterAmplitude = 0
+	if err := sim.SetParams(p); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	sim.Pause()
+	api, _ := sim.AddActorWithOptions([2]float64{50, 20}, nil, meshsim.ActorOptions{Exact: true})
+	return sim, api.GetMyID()
+}
+
+func TestActorMotion(t *testing.T) {
+	tests := []struct {
+		name string
+		// act changes actor motion, then ticks steps are made
+		act        func(sim *meshsim.Simulator, id meshpeer.NetworkID) error
+		ticks      int
+		wantCoord  [2]float64
+		wantMotion meshsim.ActorMotion
+	}{
+		{
+			name:       "still",
+			act:        func(sim *meshsim.Simulator, id meshpeer.NetworkID) error { return nil },
+			ticks:      5,
+			wantCoord:  [2]float64{50, 20},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{50, 20}},
+		},
+		{
+			name: "teleport",
+			act: func(sim *meshsim.Simulator, id meshpeer.NetworkID) error {
+				return sim.TeleportActor(id, [2]float64{10, 30})
+			},
+			ticks:      5,
+			wantCoord:  [2]float64{10, 30},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{10, 30}},
+		},
+		{
+			name: "anchor",
+			act: func(sim *meshsim.Simulator, id meshpeer.NetworkID) error {
+				return sim.SetActorAnchor(id, [2]float64{-10, 5})
+			},
+			ticks:      1,
+			wantCoord:  [2]float64{-10, 5},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{-10, 5}},
+		},
+		{
+			// 10 ticks of 20ms at 100 m/s are 20 meters
+			name: "velocity north",
+			act: func(sim *meshsim.Simulator, id meshpeer.NetworkID) error {
+				return sim.SetActorVelocity(id, 100, 360)
+			},
+			ticks:      10,
+			wantCoord:  [2]float64{50 + 20/metersPerDegree, 20},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{50 + 20/metersPerDegree, 20}, Speed: 100},
+		},
+		{
+			name: "frozen",
+			act: func(sim *meshsim.Simulator, id meshpeer.NetworkID) error {
+				if err := sim.SetActorVelocity(id, 100, 90); err != nil {
+					return err
+				}
+				return sim.FreezeActor(id, true)
+			},
+			ticks:      10,
+			wantCoord:  [2]float64{50, 20},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{50, 20}, Speed: 100, Heading: 90, Frozen: true},
+		},
+		{
+			name: "teleport frozen",
+			act: func(sim *meshsim.Simulator, id meshpeer.NetworkID) error {
+				if err := sim.FreezeActor(id, true); err != nil {
+					return err
+				}
+				return sim.TeleportActor(id, [2]float64{1, 2})
+			},
+			ticks:      3,
+			wantCoord:  [2]float64{1, 2},
+			wantMotion: meshsim.ActorMotion{Anchor: [2]float64{1, 2}, Frozen: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, id := newStillSim(t)
+			if err := tt.act(sim, id); err != nil {
+				t.Fatalf("act: %v", err)
+			}
+			if _, err := sim.Step(tt.ticks); err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			info, _ := sim.GetActor(id)
+			if !closeCoord(info.Coord, tt.wantCoord) {
+				t.Errorf("Coord = %v, want %v", info.Coord, tt.wantCoord)
+			}
+			got := info.Motion
+			if !closeCoord(got.Anchor, tt.wantMotion.Anchor) {
+				t.Errorf("Anchor = %v, want %v", got.Anchor, tt.wantMotion.Anchor)
+			}
+			got.Anchor = tt.wantMotion.Anchor
+			if got != tt.wantMotion {
+				t.Errorf("Motion = %+v, want %+v", got, tt.wantMotion)
+			}
+		})
+	}
+}
+
+// closeCoord reports whether coordinates are within a centimeter
+func closeCoord(a [2]float64, b [2]float64) bool {
+	return math.Abs(a[0]-b[0]) < 1e-7 && math.Abs(a[1]-b[1]) < 1e-7
+}
+
+func TestUnfreezeContinuesFromPlace(t *testing.T) {
+	sim, id := newStillSim(t)
+	if err := sim.SetActorVelocity(id, 100, 0); err != nil {
+		t.Fatalf("SetActorVelocity: %v", err)
+	}
+	if _, err := sim.Step(5); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if err := sim.FreezeActor(id, true); err != nil {
+		t.Fatalf("FreezeActor: %v", err)
+	}
+	if _, err := sim.Step(5); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	if err := sim.FreezeActor(id, false); err != nil {
+		t.Fatalf("FreezeActor: %v", err)
+	}
+	if _, err := sim.Step(5); err != nil {
+		t.Fatalf("Step: %v", err)
+	}
+	// frozen ticks do not count, so the actor went 10 ticks of 2 meters
+	info, _ := sim.GetActor(id)
+	if want := [2]float64{50 + 20/metersPerDegree, 20}; !closeCoord(info.Coord, want) {
+		t.Errorf("Coord = %v, want %v", info.Coord, want)
+	}
+}
+
+func TestActorMotionErrors(t *testing.T) {
+	sim, id := newStillSim(t)
+	tests := []struct {
+		name string
+		act  func() error
+	}{
+		{"teleport unknown", func() error { return sim.TeleportActor("nobody", [2]float64{}) }},
+		{"anchor unknown", func() error { return sim.SetActorAnchor("nobody", [2]float64{}) }},
+		{"freeze unknown", func() error { return sim.FreezeActor("nobody", true) }},
+		{"velocity unknown", func() error { return sim.SetActorVelocity("nobody", 1, 0) }},
+		{"negative speed", func() error { return sim.SetActorVelocity(id, -1, 0) }},
+		{"NaN speed", func() error { return sim.SetActorVelocity(id, math.NaN(), 0) }},
+		{"infinite heading", func() error { return sim.SetActorVelocity(id, 1, math.Inf(1)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.act(); err == nil {
+				t.Errorf("no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package meshsim
+
+import (
+	"fmt"
+	"time"
+)
+
+// Params are tunable parameters of simulation, they may be changed while it runs
+type Params struct {
+	// Dt is simulation time advanced by one tick, in seconds
+	Dt float64
+	// TimeRatio is wall clock time per simulation time, 1 runs simulation in real time
+	TimeRatio float64
+	// RadioRange is distance in meters within which actors see each other
+	RadioRange float64
+	// MaxPeers is how many of the nearest actors in range an actor sees
+	MaxPeers int
+	// DefaultCoord is where actors are placed when no position is given. Simulator does not use it
+	// itself, it keeps it for the code creating actors
+	DefaultCoord [2]float64
+	// SpawnSpread is standard deviation in degrees of random offset of actors added near a place
+	SpawnSpread float64
+	// JitterAmplitude in degrees and JitterFrequency in Hz are upper bounds of random walk
+	// components of newly added actors
+	JitterAmplitude float64
+	JitterFrequency float64
+}
+
+// DefaultParams returns parameters simulation starts with
+func DefaultParams() Params {
+	return Params{
+		Dt:              0.020,
+		TimeRatio:       1,
+		RadioRange:      50,
+		MaxPeers:        5,
+		DefaultCoord:    [2]float64{53.904153, 27.556925},
+		SpawnSpread:     0.00045,
+		JitterAmplitude: 0.0002,
+		JitterFrequency: 0.01,
+	}
+}
+
+// Validate checks that simulation can run with p. Comparisons are written so that NaN fails them
+func (p Params) Validate() error {
+	if !(p.Dt > 0 && p.Dt <= 1) {
+		return fmt.Errorf("Dt must be in (0, 1] seconds, got %v", p.Dt)
+	}
+	if !(p.TimeRatio > 0 && p.TimeRatio <= 1000) {
+		return fmt.Errorf("TimeRatio must be in (0, 1000], got %v", p.TimeRatio)
+	}
+	if !(p.RadioRange > 0) {
+		return fmt.Errorf("RadioRange must be positive, got %v", p.RadioRange)
+	}
+	if p.MaxPeers < 0 {
+		return fmt.Errorf("MaxPeers must not be negative, got %v", p.MaxPeers)
+	}
+	if !(p.DefaultCoord[0] >= -90 && p.DefaultCoord[0] <= 90 && p.DefaultCoord[1] >= -180 && p.DefaultCoord[1] <= 180) {
+		return fmt.Errorf("DefaultCoord %v,%v is not a place on Earth", p.DefaultCoord[0], p.DefaultCoord[1])
+	}
+	if !(p.SpawnSpread >= 0 && p.JitterAmplitude >= 0 && p.JitterFrequency >= 0) {
+		return fmt.Errorf("SpawnSpread, JitterAmplitude and JitterFrequency must not be negative")
+	}
+	return nil
+}
+
+// tickInterval is wall clock time between ticks. s.mtx must be held
+func (s *Simulator) tickInterval() time.Duration {
+	return time.Duration(s.params.Dt * s.params.TimeRatio * float64(time.Second))
+}
+
+// Params returns current simulation parameters
+func (s *Simulator) Params() Params {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return s.params
+}
+
+// SetParams replaces simulation parameters, invalid ones are rejected. Jitter and spawn
+// parameters apply to actors added afterwards
+func (s *Simulator) SetParams(p Params) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.params = p
+	s.notifyParamsChanged()
+	return nil
+}
+
+// notifyParamsChanged wakes tick loop to apply new TimeRatio and Dt without waiting for the
+// tick it has scheduled with the old ones
+func (s *Simulator) notifyParamsChanged() {
+	select {
+	case s.paramsChanged <- struct{}{}:
+	default:
+	}
+}
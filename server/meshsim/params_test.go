@@ -0,0 +1,104 @@
+package meshsim_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+func TestParamsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(p *meshsim.Params)
+		wantErr bool
+	}{
+		{"default", func(p *meshsim.Params) {}, false},
+		{"longest Dt", func(p *meshsim.Params) { p.Dt = 1 }, false},
+		{"zero Dt", func(p *meshsim.Params) { p.Dt = 0 }, true},
+		{"too long Dt", func(p *meshsim.Params) { p.Dt = 1.5 }, true},
+		{"NaN Dt", func(p *meshsim.Params) { p.Dt = math.NaN() }, true},
+		{"fast TimeRatio", func(p *meshsim.Params) { p.TimeRatio = 0.001 }, false},
+		{"zero TimeRatio", func(p *meshsim.Params) { p.TimeRatio = 0 }, true},
+		{"too slow TimeRatio", func(p *meshsim.Params) { p.TimeRatio = 1001 }, true},
+		{"zero RadioRange", func(p *meshsim.Params) { p.RadioRange = 0 }, true},
+		{"no MaxPeers", func(p *meshsim.Params) { p.MaxPeers = 0 }, false},
+		{"negative MaxPeers", func(p *meshsim.Params) { p.MaxPeers = -1 }, true},
+		{"DefaultCoord off Earth", func(p *meshsim.Params) { p.DefaultCoord = [2]float64{0, 181} }, true},
+		{"negative JitterAmplitude", func(p *meshsim.Params) { p.JitterAmplitude = -1 }, true},
+		{"NaN SpawnSpread", func(p *meshsim.Params) { p.SpawnSpread = math.NaN() }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := meshsim.DefaultParams()
+			tt.change(&p)
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetParamsRejectsInvalid(t *testing.T) {
+	sim := meshsim.New(meshlog.Discard())
+	p := meshsim.DefaultParams()
+	p.Dt = 0
+	if err := sim.SetParams(p); err == nil {
+		t.Fatalf("no error")
+	}
+	if got := sim.Params(); got != meshsim.DefaultParams() {
+		t.Errorf("params = %+v, want default ones", got)
+	}
+}
+
+func TestDtAdvancesTime(t *testing.T) {
+	tests := []struct {
+		name string
+		dt   float64
+	}{
+		{"default", meshsim.DefaultParams().Dt},
+		{"long", 0.5},
+		{"short", 0.001},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, apis := newPausedSim(t, 1)
+			p := sim.Params()
+			p.Dt = tt.dt
+			if err := sim.SetParams(p); err != nil {
+				t.Fatalf("SetParams: %v", err)
+			}
+			var mtx sync.Mutex
+			ticks := []meshpeer.NetworkTime{}
+			apis[0].RegisterTimeTickHandler(func(ts meshpeer.NetworkTime) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				ticks = append(ticks, ts)
+			})
+			start, err := sim.Step(1)
+			if err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			end, err := sim.Step(3)
+			if err != nil {
+				t.Fatalf("Step: %v", err)
+			}
+			if math.Abs(end-start-3*tt.dt) > 1e-9 {
+				t.Errorf("3 ticks took %v seconds, want %v", end-start, 3*tt.dt)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if len(ticks) != 4 {
+				t.Fatalf("peer got %v ticks, want 4", len(ticks))
+			}
+			// peers get time in microseconds
+			if got, want := ticks[3]-ticks[0], meshpeer.NetworkTime(math.Round(3*tt.dt*1e6)); got < want-1 || got > want+1 {
+				t.Errorf("peer time advanced by %v, want %v", got, want)
+			}
+		})
+	}
+}
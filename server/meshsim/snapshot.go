@@ -0,0 +1,234 @@
+package meshsim
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+)
+
+// SnapshotVersion is the version of Snapshot format written by this simulator
+const SnapshotVersion = 1
+
+// Snapshot is saved simulation state, it is JSON serialisable
+type Snapshot struct {
+	Version int
+	SimTime float64
+	Paused  bool
+	// Params are absent in snapshots of older servers, current ones are kept on restore then
+	Params         *Params `json:",omitempty"`
+	FaultSchedules map[string]FaultSchedule
+	Actors         []ActorSnapshot
+}
+
+// ActorSnapshot is saved state of one actor. Links are not saved, they are established again
+// on the first tick after restore, so peers get appeared events for their neighbours
+type ActorSnapshot struct {
+	ID     meshpeer.NetworkID
+	Coord  [2]float64
+	Motion ActorMotion
+	// Jitter is amplitude, frequency and phase of random walk components
+	Jitter    [3][3]float64
+	Meta      map[string]interface{}
+	Owner     string `json:",omitempty"`
+	Down      bool
+	RestartAt float64
+	// Queued are messages sent by actor but not delivered yet, by target
+	Queued  map[meshpeer.NetworkID][]meshpeer.NetworkMessage
+	Storage map[string][]byte
+	// Peer is opaque state of peer runtime, see Simulator.Snapshot
+	Peer json.RawMessage
+}
+
+// Snapshot saves simulation state. peerState is called for every actor while simulation is held,
+// so peer runtimes may be accessed from it safely. It returns state of actor runtime to be
+// stored in ActorSnapshot.Peer, actors it returns false for are not saved
+func (s *Simulator) Snapshot(peerState func(id meshpeer.NetworkID) (json.RawMessage, bool)) Snapshot {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	params := s.params
+	ret := Snapshot{
+		Version:        SnapshotVersion,
+		SimTime:        s.simTime,
+		Paused:         s.paused,
+		Params:         &params,
+		FaultSchedules: map[string]FaultSchedule{},
+		Actors:         []ActorSnapshot{},
+	}
+	for group, sch := range s.faultSchedules {
+		ret.FaultSchedules[group] = sch
+	}
+	ids := []string{}
+	for id := range s.actors {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		a := s.actors[meshpeer.NetworkID(id)]
+		peer, ok := peerState(a.ID)
+		if !ok {
+			continue
+		}
+		info := a.info()
+		as := ActorSnapshot{
+			ID:        a.ID,
+			Coord:     info.Coord,
+			Motion:    info.Motion,
+			Jitter:    [3][3]float64{a.randomAmpl, a.randomFreq, a.randomPhase},
+			Meta:      info.Meta,
+			Owner:     info.Owner,
+			Down:      info.Down,
+			RestartAt: a.restartAt,
+			Queued:    map[meshpeer.NetworkID][]meshpeer.NetworkMessage{},
+			Storage:   map[string][]byte{},
+			Peer:      peer,
+		}
+		a.mtx.Lock()
+		for trg, msgs := range a.outgoingMsgQueue {
+			as.Queued[trg] = append([]meshpeer.NetworkMessage{}, msgs...)
+		}
+		a.mtx.Unlock()
+		for _, k := range a.storage.Keys() {
+			if v, ok := a.storage.Get(k); ok {
+				as.Storage[k] = v
+			}
+		}
+		ret.Actors = append(ret.Actors, as)
+	}
+	return ret
+}
+
+// Restore replaces the whole simulation with saved one. All current actors are removed and their
+// runtimes stopped, then actors of the snapshot are recreated with the same IDs. restorePeer is
+// called for each of them outside of simulator lock, like peers are created for new actors, to
+// start its runtime from ActorSnapshot.Peer. Simulation stays paused meanwhile, actors it fails
+// for are dropped
+func (s *Simulator) Restore(snap Snapshot, restorePeer func(id meshpeer.NetworkID, peer json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error) error {
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("Unsupported snapshot version %v", snap.Version)
+	}
+	if snap.Params != nil {
+		if err := snap.Params.Validate(); err != nil {
+			return fmt.Errorf("Snapshot has bad params: %v", err)
+		}
+	}
+	seen := map[meshpeer.NetworkID]struct{}{}
+	for _, as := range snap.Actors {
+		if as.ID == "" {
+			return fmt.Errorf("Snapshot has actor without ID")
+		}
+		if _, ok := seen[as.ID]; ok {
+			return fmt.Errorf("Snapshot has duplicate actor %v", as.ID)
+		}
+		seen[as.ID] = struct{}{}
+	}
+
+	s.mtx.Lock()
+	closing, storages := s.removeAllActors()
+	// restored actors may reuse IDs and so storage files of removed ones, which must not be
+	// written to afterwards
+	for _, st := range storages {
+		if err := st.Remove(); err != nil {
+			s.logger.Warn("Cannot delete storage", meshlog.KeyError, err)
+		}
+	}
+
+	s.setSimTime(snap.SimTime)
+	s.lastStatusTime = snap.SimTime
+	// runtimes are bound while no ticks come, snap.Paused is applied afterwards
+	s.paused = true
+	if snap.Params != nil {
+		s.params = *snap.Params
+		s.notifyParamsChanged()
+	}
+	s.faultSchedules = map[string]FaultSchedule{}
+	for group, sch := range snap.FaultSchedules {
+		s.faultSchedules[group] = sch
+	}
+
+	restored := make([]*actorPhysics, 0, len(snap.Actors))
+	for _, as := range snap.Actors {
+		a := s.newActor(as.ID, as.Meta)
+		a.Coord = as.Coord
+		a.owner = as.Owner
+		a.startCoord = as.Motion.Anchor
+		a.speed = as.Motion.Speed
+		a.heading = as.Motion.Heading
+		a.frozen = as.Motion.Frozen
+		a.randomAmpl, a.randomFreq, a.randomPhase = as.Jitter[0], as.Jitter[1], as.Jitter[2]
+		a.crashed = as.Down
+		a.restartAt = as.RestartAt
+		a.userInterestingEventTime = s.simTime
+		a.nextUserSimulationSentTime = s.simTime
+		for trg, msgs := range as.Queued {
+			a.outgoingMsgQueue[trg] = append([]meshpeer.NetworkMessage{}, msgs...)
+		}
+		s.restoreStorage(a, as.Storage)
+		s.actors[a.ID] = a
+		restored = append(restored, a)
+	}
+	s.mtx.Unlock()
+	s.closeRuntimes(closing)
+
+	failed := map[*actorPhysics]bool{}
+	for i, a := range restored {
+		if err := restorePeer(a.ID, snap.Actors[i].Peer, a, a); err != nil {
+			s.logger.Warn("Cannot restore peer", meshlog.KeyPeer, a.ID, meshlog.KeyError, err)
+			failed[a] = true
+		}
+	}
+
+	s.mtx.Lock()
+	closing = nil
+	for _, a := range restored {
+		if s.actors[a.ID] != a {
+			// removed while its runtime was starting
+			if l := a.unbindLifecycle(); l != nil {
+				l.Stop()
+				closing = append(closing, l)
+			}
+			continue
+		}
+		if failed[a] {
+			a.resetHandlers()
+			if l := a.unbindLifecycle(); l != nil {
+				l.Stop()
+				closing = append(closing, l)
+			}
+			delete(s.actors, a.ID)
+			s.dropLinksTo(a.ID)
+			continue
+		}
+		if a.crashed {
+			// crashed actor gets no callbacks and shows nothing until it is restarted with a fresh runtime
+			a.resetHandlers()
+			a.mtx.Lock()
+			a.debugData = nil
+			a.peerDebugData = nil
+			a.health = nil
+			a.mtx.Unlock()
+		}
+	}
+	s.paused = snap.Paused
+	s.mtx.Unlock()
+
+	s.closeRuntimes(closing)
+	return nil
+}
+
+// restoreStorage makes actor storage contain exactly the given data
+func (s *Simulator) restoreStorage(a *actorPhysics, data map[string][]byte) {
+	for _, k := range a.storage.Keys() {
+		if _, ok := data[k]; !ok {
+			a.storage.Delete(k)
+		}
+	}
+	for k, v := range data {
+		if err := a.storage.Set(k, v); err != nil {
+			s.logger.Warn("Cannot restore storage", meshlog.KeyPeer, a.ID, meshlog.KeyError, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package meshsim_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// peerState saves the same runtime state for every actor
+func peerState(id meshpeer.NetworkID) (json.RawMessage, bool) {
+	return json.RawMessage(`{"peer":"` + string(id) + `"}`), true
+}
+
+// savedSim returns snapshot of paused simulation with two linked actors, one of them crashed,
+// a queued message, storage and custom params
+func savedSim(t *testing.T) meshsim.Snapshot {
+	sim, apis := newPausedSim(t, 2)
+	p := sim.Params()
+	p.RadioRange = 70
+	if err := sim.SetParams(p); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	a, b := apis[0].GetMyID(), apis[1].GetMyID()
+	step(t, sim)
+	step(t, sim)
+	if err := sim.UpdateActorMeta(a, map[string]interface{}{"label": "a"}); err != nil {
+		t.Fatalf("UpdateActorMeta: %v", err)
+	}
+	if err := sim.SetActorVelocity(a, 5, 45); err != nil {
+		t.Fatalf("SetActorVelocity: %v", err)
+	}
+	if err := apis[0].(meshpeer.StorageProvider).GetStorage().Set("key", []byte("value")); err != nil {
+		t.Fatalf("storage Set: %v", err)
+	}
+	apis[0].SendMessage(b, meshpeer.NetworkMessage("hello"))
+	if err := sim.CrashActor(b, 10); err != nil {
+		t.Fatalf("CrashActor: %v", err)
+	}
+	sim.SetFaultSchedule("g", 100, 5)
+	return sim.Snapshot(peerState)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	snap := savedSim(t)
+	// snapshots are stored as JSON
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	snap = meshsim.Snapshot{}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(snap.Actors) != 2 || len(snap.Actors[0].Queued)+len(snap.Actors[1].Queued) != 1 {
+		t.Fatalf("snapshot = %+v, want 2 actors and a queued message", snap)
+	}
+
+	sim, apis := newPausedSim(t, 3)
+	restored := map[meshpeer.NetworkID]string{}
+	err = sim.Restore(snap, func(id meshpeer.NetworkID, peer json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error {
+		if meshAPI.GetMyID() != id {
+			t.Errorf("MeshAPI of %v is %v", id, meshAPI.GetMyID())
+		}
+		restored[id] = string(peer)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	for _, api := range apis {
+		if _, ok := sim.GetActor(api.GetMyID()); ok {
+			t.Errorf("actor %v which was there before restore is left", api.GetMyID())
+		}
+	}
+	for _, as := range snap.Actors {
+		if want := fmt.Sprintf(`{"peer":"%v"}`, as.ID); restored[as.ID] != want {
+			t.Errorf("restored peer %v = %v, want %v", as.ID, restored[as.ID], want)
+		}
+	}
+	if again := sim.Snapshot(peerState); !reflect.DeepEqual(again, snap) {
+		t.Errorf("snapshot after restore = %+v, want %+v", again, snap)
+	}
+}
+
+func TestRestoreDropsFailedPeers(t *testing.T) {
+	snap := savedSim(t)
+	sim := meshsim.New(meshlog.Discard())
+	failing := snap.Actors[0].ID
+	err := sim.Restore(snap, func(id meshpeer.NetworkID, peer json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error {
+		if id == failing {
+			return fmt.Errorf("broken")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if _, ok := sim.GetActor(failing); ok {
+		t.Errorf("actor %v which failed to restore is left", failing)
+	}
+	if _, ok := sim.GetActor(snap.Actors[1].ID); !ok {
+		t.Errorf("actor %v is not restored", snap.Actors[1].ID)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	badParams := meshsim.DefaultParams()
+	badParams.Dt = 0
+	tests := []struct {
+		name string
+		snap meshsim.Snapshot
+	}{
+		{"unknown version", meshsim.Snapshot{Version: meshsim.SnapshotVersion + 1}},
+		{"bad params", meshsim.Snapshot{Version: meshsim.SnapshotVersion, Params: &badParams}},
+		{"actor without ID", meshsim.Snapshot{Version: meshsim.SnapshotVersion, Actors: []meshsim.ActorSnapshot{{}}}},
+		{"duplicate actor", meshsim.Snapshot{Version: meshsim.SnapshotVersion, Actors: []meshsim.ActorSnapshot{{ID: "a"}, {ID: "a"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, apis := newPausedSim(t, 1)
+			err := sim.Restore(tt.snap, func(id meshpeer.NetworkID, peer json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error {
+				return nil
+			})
+			if err == nil {
+				t.Fatalf("no error")
+			}
+			// rejected snapshot leaves simulation as it was
+			if _, ok := sim.GetActor(apis[0].GetMyID()); !ok {
+				t.Errorf("actor is removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"mesh-simulator/meshpeer"
+)
+
+// peerLogsQueue is how many console entries may wait to be sent to a log stream client, later ones are dropped
+const peerLogsQueue = 256
+
+// jsPeerOf returns JS peer of given ID, nil if there is no such peer or it does not capture logs
+func (w *world) jsPeerOf(id meshpeer.NetworkID) (jsPeer *meshpeer.JSPeer, found bool) {
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	npc, found := w.npcList[id]
+	jsPeer, _ = npc.(*meshpeer.JSPeer)
+	return jsPeer, found
+}
+
+// streamPeerLogs sends console entries of the peer with sequence number greater than since as
+// JSON messages until the client goes away or the peer is removed. A rebooted or restarted peer
+// gets a new runtime, its entries are streamed from the beginning
+func streamPeerLogs(conn *websocket.Conn, w *world, id meshpeer.NetworkID, since int64) {
+	defer conn.Close()
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var last *meshpeer.JSPeer
+	for {
+		jsPeer, found := w.jsPeerOf(id)
+		if !found || jsPeer == nil {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "peer not found"))
+			return
+		}
+		if jsPeer == last {
+			// closed runtime is not replaced yet
+			select {
+			case <-gone:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if last != nil {
+			since = 0
+		}
+		last = jsPeer
+
+		backlog, entries, cancel := jsPeer.SubscribeLogs(since, peerLogsQueue)
+		if !sendPeerLogs(conn, backlog, &since) {
+			cancel()
+			return
+		}
+	stream:
+		for {
+			select {
+			case <-gone:
+				cancel()
+				return
+			case e, ok := <-entries:
+				if !ok {
+					break stream
+				}
+				if !sendPeerLogs(conn, []meshpeer.JSLogEntry{e}, &since) {
+					cancel()
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendPeerLogs writes entries newer than since and advances it
+func sendPeerLogs(conn *websocket.Conn, entries []meshpeer.JSLogEntry, since *int64) bool {
+	for _, e := range entries {
+		if e.Seq <= *since {
+			continue
+		}
+		if err := conn.WriteJSON(e); err != nil {
+			return false
+		}
+		*since = e.Seq
+	}
+	return true
+}
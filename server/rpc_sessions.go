@@ -0,0 +1,236 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// rpcSession keeps ws_rpc peer alive between client connections, so a client reconnecting
+// within grace period resumes the same actor and gets events queued while it was away
+type rpcSession struct {
+	token      string
+	meshPeerID meshpeer.NetworkID
+	meshPeer   *meshpeer.RPCPeer
+	inChannel  chan []byte
+	outChannel chan []byte
+
+	mtx         sync.Mutex
+	client      *wsClient
+	pending     []byte
+	expireTimer *time.Timer
+	closed      bool
+}
+
+type rpcSessions struct {
+	mtx      sync.RWMutex
+	sessions map[string]*rpcSession
+	grace    time.Duration
+	logger   *meshlog.Logger
+	onClose  func(s *rpcSession)
+}
+
+func newRPCSessions(grace time.Duration, logger *meshlog.Logger, onClose func(s *rpcSession)) *rpcSessions {
+	return &rpcSessions{
+		sessions: make(map[string]*rpcSession),
+		grace:    grace,
+		logger:   logger,
+		onClose:  onClose,
+	}
+}
+
+// create registers new session, peer is created by newPeer using session channels
+func (ss *rpcSessions) create(newPeer func(in chan []byte, out chan []byte) (meshpeer.NetworkID, *meshpeer.RPCPeer)) *rpcSession {
+	s := &rpcSession{
+		token:      uuid.New().String(),
+		inChannel:  make(chan []byte),
+		outChannel: make(chan []byte),
+	}
+	s.meshPeerID, s.meshPeer = newPeer(s.inChannel, s.outChannel)
+
+	ss.mtx.Lock()
+	ss.sessions[s.token] = s
+	ss.logger.Debug("WS session created", meshlog.KeyPeer, s.meshPeerID, "sessions", len(ss.sessions))
+	ss.mtx.Unlock()
+	return s
+}
+
+func (ss *rpcSessions) get(token string) *rpcSession {
+	ss.mtx.RLock()
+	defer ss.mtx.RUnlock()
+
+	return ss.sessions[token]
+}
+
+func (ss *rpcSessions) forEach(f func(s *rpcSession)) {
+	ss.mtx.RLock()
+	defer ss.mtx.RUnlock()
+
+	for _, s := range ss.sessions {
+		f(s)
+	}
+}
+
+// disconnect sends close frame with reason to every connected client, so clients know they
+// should not try to resume. Sessions end as usual once their peers leave simulation
+func (ss *rpcSessions) disconnect(reason string) {
+	ss.forEach(func(s *rpcSession) {
+		s.mtx.Lock()
+		cl := s.client
+		s.mtx.Unlock()
+		if cl != nil {
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, reason)
+			cl.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		}
+	})
+}
+
+// attach binds connection to session, replacing connection the client may have left behind.
+// It fails if session is already closed
+func (ss *rpcSessions) attach(s *rpcSession, cl *wsClient) bool {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return false
+	}
+	if s.expireTimer != nil {
+		s.expireTimer.Stop()
+		s.expireTimer = nil
+	}
+	old := s.client
+	s.client = cl
+	s.meshPeer.SetDetached(false)
+	s.mtx.Unlock()
+
+	if old != nil {
+		old.conn.Close()
+		<-old.finished
+	}
+	return true
+}
+
+// detach unbinds connection and closes session after grace period unless client comes back,
+// or right away if endSession is set
+func (ss *rpcSessions) detach(s *rpcSession, cl *wsClient, endSession bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.client != cl {
+		// already replaced by a newer connection
+		return
+	}
+	s.client = nil
+	if endSession || ss.grace <= 0 {
+		go ss.close(s)
+		return
+	}
+	s.meshPeer.SetDetached(true)
+	s.expireTimer = time.AfterFunc(ss.grace, func() {
+		ss.close(s)
+	})
+}
+
+func (ss *rpcSessions) close(s *rpcSession) {
+	s.mtx.Lock()
+	if s.closed || s.client != nil {
+		s.mtx.Unlock()
+		return
+	}
+	s.closed = true
+	s.mtx.Unlock()
+
+	ss.mtx.Lock()
+	delete(ss.sessions, s.token)
+	ss.logger.Debug("WS session closed", meshlog.KeyPeer, s.meshPeerID, "sessions", len(ss.sessions))
+	ss.mtx.Unlock()
+
+	close(s.inChannel)
+	ss.onClose(s)
+}
+
+type wsClient struct {
+	conn     *websocket.Conn
+	session  *rpcSession
+	finished chan struct{}
+}
+
+func newWSClient(conn *websocket.Conn, session *rpcSession) *wsClient {
+	return &wsClient{
+		conn:     conn,
+		session:  session,
+		finished: make(chan struct{}),
+	}
+}
+
+// run serves connection until it is closed. It returns true if session must not wait for client
+// to come back: client could not keep up with its output, or the peer left simulation
+func (cl *wsClient) run(logger *meshlog.Logger) (endSession bool) {
+	done := make(chan bool)
+	writerDone := make(chan bool)
+	defer func() {
+		cl.conn.Close()
+		close(done)
+		<-writerDone
+		close(cl.finished)
+	}()
+
+	go func() {
+		defer close(writerDone)
+		write := func(n []byte) bool {
+			if err := cl.conn.WriteMessage(websocket.TextMessage, n); err != nil {
+				// keep it to be sent first after reconnect
+				cl.session.mtx.Lock()
+				cl.session.pending = n
+				cl.session.mtx.Unlock()
+				return false
+			}
+			return true
+		}
+
+		cl.session.mtx.Lock()
+		pending := cl.session.pending
+		cl.session.pending = nil
+		cl.session.mtx.Unlock()
+		if pending != nil && !write(pending) {
+			return
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case n := <-cl.session.outChannel:
+				if !write(n) {
+					return
+				}
+			case <-cl.session.meshPeer.Overflowed():
+				logger.Warn("WS client is too slow, disconnecting", meshlog.KeyPeer, cl.session.meshPeerID, "remote", cl.conn.RemoteAddr().String())
+				endSession = true
+				cl.conn.Close()
+				return
+			case <-cl.session.meshPeer.Done():
+				logger.Info("WS peer left simulation, disconnecting", meshlog.KeyPeer, cl.session.meshPeerID, "remote", cl.conn.RemoteAddr().String())
+				endSession = true
+				cl.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "peer removed"))
+				cl.conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := cl.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		select {
+		case cl.session.inChannel <- msg:
+		case <-cl.session.meshPeer.Done():
+			return
+		}
+	}
+}
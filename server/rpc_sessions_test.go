@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// rpcSessionMsg is params of ws_rpc session event
+type rpcSessionMsg struct {
+	Token   string
+	PeerID  string
+	Resumed bool
+}
+
+// dialRPC connects to /ws_rpc with given query
+func (ts *testServer) dialRPC(query string) (*websocket.Conn, *http.Response, error) {
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.http.URL, "http")+"/ws_rpc?"+query, nil)
+}
+
+// readEvents reads notifications from conn until every method of want is seen and returns
+// params of the last notification of each method
+func readEvents(t *testing.T, conn *websocket.Conn, want ...string) map[string]json.RawMessage {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	ret := map[string]json.RawMessage{}
+	for {
+		seen := 0
+		for _, m := range want {
+			if _, ok := ret[m]; ok {
+				seen++
+			}
+		}
+		if seen == len(want) {
+			return ret
+		}
+		msg := struct {
+			Method string
+			Params json.RawMessage
+		}{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %v: %v", want, err)
+		}
+		ret[msg.Method] = msg.Params
+	}
+}
+
+func readSession(t *testing.T, conn *websocket.Conn) rpcSessionMsg {
+	s := rpcSessionMsg{}
+	if err := json.Unmarshal(readEvents(t, conn, "session")["session"], &s); err != nil {
+		t.Fatalf("bad session event: %v", err)
+	}
+	return s
+}
+
+func TestRPCSessionResume(t *testing.T) {
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		away        time.Duration
+		token       string
+		wantResumed bool
+		// wantGone is set if the peer leaves simulation
+		wantGone bool
+	}{
+		{"resume within grace", time.Minute, 0, "", true, false},
+		{"grace expired", 100 * time.Millisecond, 300 * time.Millisecond, "", false, true},
+		{"no grace", 0, 0, "", false, true},
+		{"unknown session", time.Minute, 0, "no-such-session", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t)
+			ts.world.sim.Pause()
+			ts.world.rpcSessions.grace = tt.grace
+			other, _ := ts.world.sim.AddActorWithOptions(ts.world.sim.Params().DefaultCoord, nil, meshsim.ActorOptions{Exact: true})
+			step := func() {
+				if _, err := ts.world.sim.Step(1); err != nil {
+					t.Fatalf("Step: %v", err)
+				}
+			}
+
+			conn, _, err := ts.dialRPC("")
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			s := readSession(t, conn)
+			id := meshpeer.NetworkID(s.PeerID)
+			step()
+			conn.Close()
+			ts.waitFor("server to notice disconnect", func() bool {
+				session := ts.world.rpcSessions.get(s.Token)
+				if session == nil {
+					return true
+				}
+				session.mtx.Lock()
+				defer session.mtx.Unlock()
+				return session.client == nil
+			})
+			// the message is queued while the client is away
+			other.SendMessage(id, []byte("while away"))
+			step()
+			time.Sleep(tt.away)
+
+			token := s.Token
+			if tt.token != "" {
+				token = tt.token
+			}
+			conn, resp, err := ts.dialRPC("session=" + token)
+			if !tt.wantResumed {
+				if err == nil {
+					conn.Close()
+					t.Fatal("session is resumed")
+				}
+				if resp == nil || resp.StatusCode != http.StatusNotFound {
+					t.Errorf("response = %v, want 404", resp)
+				}
+				if tt.wantGone {
+					ts.waitFor("peer to leave simulation", func() bool {
+						_, ok := ts.world.sim.GetActor(id)
+						return !ok
+					})
+				} else if _, ok := ts.world.sim.GetActor(id); !ok {
+					t.Error("peer left simulation")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resume: %v", err)
+			}
+			defer conn.Close()
+			events := readEvents(t, conn, "session", "didReceiveFromPeer")
+			resumed := rpcSessionMsg{}
+			json.Unmarshal(events["session"], &resumed)
+			if !resumed.Resumed || resumed.PeerID != s.PeerID || resumed.Token != s.Token {
+				t.Errorf("session = %+v, want resumed %+v", resumed, s)
+			}
+			msg := struct{ PeerID, Data string }{}
+			json.Unmarshal(events["didReceiveFromPeer"], &msg)
+			if msg.PeerID != string(other.GetMyID()) || msg.Data != "while away" {
+				t.Errorf("message = %+v", msg)
+			}
+		})
+	}
+}
+
+func TestRPCSessionTakeover(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.rpcSessions.grace = time.Minute
+	first, _, err := ts.dialRPC("")
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer first.Close()
+	s := readSession(t, first)
+
+	second, _, err := ts.dialRPC("session=" + s.Token)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	defer second.Close()
+	if resumed := readSession(t, second); !resumed.Resumed || resumed.PeerID != s.PeerID {
+		t.Errorf("session = %+v", resumed)
+	}
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := first.ReadMessage(); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Errorf("replaced connection is not closed: %v", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mesh-simulator/meshsim"
+)
+
+// snapshotNameRe limits snapshot names, so they are safe to use as file names
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,63}$`)
+
+// savedPeer is the peer runtime part of actor snapshot, see meshsim.ActorSnapshot.Peer
+type savedPeer struct {
+	Type   string
+	Script string          `json:",omitempty"`
+	Config json.RawMessage `json:",omitempty"`
+	// State is returned by meshpeer.StatefulPeer
+	State json.RawMessage `json:",omitempty"`
+}
+
+// snapshotInfo describes snapshot file
+type snapshotInfo struct {
+	Name     string
+	Size     int64
+	Modified time.Time
+}
+
+// snapshotStore saves world snapshots to files in dir and serves them under /api/v1. Snapshot
+// files are shared by all worlds, so a world saved in one of them may be restored in another
+type snapshotStore struct {
+	dir string
+}
+
+func newSnapshotStore(dir string) *snapshotStore {
+	return &snapshotStore{
+		dir: dir,
+	}
+}
+
+func (st *snapshotStore) path(name string) (string, error) {
+	if !snapshotNameRe.MatchString(name) {
+		return "", fmt.Errorf("bad snapshot name %q", name)
+	}
+	return filepath.Join(st.dir, name+".json"), nil
+}
+
+// save writes current world state to snapshot file with given name
+func (st *snapshotStore) save(w *world, name string) error {
+	path, err := st.path(name)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(w.takeSnapshot())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0777); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadFile replaces world simulation with snapshot read from file
+func (st *snapshotStore) loadFile(w *world, path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	snap := meshsim.Snapshot{}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("bad snapshot %v: %v", path, err)
+	}
+	return w.restoreSnapshot(snap)
+}
+
+func (st *snapshotStore) list() ([]snapshotInfo, error) {
+	ret := []snapshotInfo{}
+	files, err := ioutil.ReadDir(st.dir)
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), ".json")
+		if f.IsDir() || name == f.Name() || !snapshotNameRe.MatchString(name) {
+			continue
+		}
+		ret = append(ret, snapshotInfo{name, f.Size(), f.ModTime()})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// register adds snapshot routes to r, which scopes them to a world. Snapshots hold scripts and
+// storage of every peer, so only the list of them is not for admins only
+func (st *snapshotStore) register(r *gin.RouterGroup, au *authenticator) {
+	g := r.Group("/api/v1")
+	g.GET("/snapshot", au.require(roleAdmin), st.getCurrent)
+	g.PUT("/snapshot", au.require(roleAdmin), st.putCurrent)
+	g.GET("/snapshots", au.require(roleViewer), st.listSnapshots)
+	g.GET("/snapshots/:name", au.require(roleAdmin), st.getSnapshot)
+	g.PUT("/snapshots/:name", au.require(roleAdmin), st.saveSnapshot)
+	g.DELETE("/snapshots/:name", au.require(roleAdmin), st.deleteSnapshot)
+	g.POST("/snapshots/:name/restore", au.require(roleAdmin), st.restoreSnapshot)
+}
+
+func (st *snapshotStore) getCurrent(c *gin.Context) {
+	c.JSON(http.StatusOK, worldOf(c).takeSnapshot())
+}
+
+func (st *snapshotStore) putCurrent(c *gin.Context) {
+	snap := meshsim.Snapshot{}
+	if err := c.ShouldBindJSON(&snap); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	if err := worldOf(c).restoreSnapshot(snap); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (st *snapshotStore) listSnapshots(c *gin.Context) {
+	snapshots, err := st.list()
+	if err != nil {
+		apiV1Fail(c, http.StatusInternalServerError, apiV1Internal, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"Snapshots": snapshots})
+}
+
+// existing returns path of existing snapshot file, failing the request if there is none
+func (st *snapshotStore) existing(c *gin.Context) (string, bool) {
+	path, err := st.path(c.Param("name"))
+	if err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return "", false
+	}
+	if _, err := os.Stat(path); err != nil {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "snapshot not found")
+		return "", false
+	}
+	return path, true
+}
+
+func (st *snapshotStore) getSnapshot(c *gin.Context) {
+	if path, ok := st.existing(c); ok {
+		c.Header("Content-Type", "application/json")
+		c.File(path)
+	}
+}
+
+func (st *snapshotStore) saveSnapshot(c *gin.Context) {
+	name := c.Param("name")
+	if _, err := st.path(name); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	if err := st.save(worldOf(c), name); err != nil {
+		apiV1Fail(c, http.StatusInternalServerError, apiV1Internal, err.Error())
+		return
+	}
+	c.Header("Location", "/api/v1/snapshots/"+name)
+	c.Status(http.StatusCreated)
+}
+
+func (st *snapshotStore) deleteSnapshot(c *gin.Context) {
+	path, ok := st.existing(c)
+	if !ok {
+		return
+	}
+	if err := os.Remove(path); err != nil {
+		apiV1Fail(c, http.StatusInternalServerError, apiV1Internal, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (st *snapshotStore) restoreSnapshot(c *gin.Context) {
+	path, ok := st.existing(c)
+	if !ok {
+		return
+	}
+	if err := st.loadFile(worldOf(c), path); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
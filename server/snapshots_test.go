@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"mesh-simulator/meshsim"
+)
+
+func TestSnapshotSaveRestore(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+	saved := ts.connectJS()
+	if status := ts.do("PUT", "/api/v1/snapshots/one", nil, nil); status != http.StatusCreated {
+		t.Fatalf("save status = %v", status)
+	}
+	added := ts.connectBuiltin()
+
+	list := struct{ Snapshots []snapshotInfo }{}
+	if status := ts.do("GET", "/api/v1/snapshots", nil, &list); status != http.StatusOK || len(list.Snapshots) != 1 || list.Snapshots[0].Name != "one" {
+		t.Fatalf("list = %v %+v, want snapshot one", status, list)
+	}
+	file := meshsim.Snapshot{}
+	if status := ts.do("GET", "/api/v1/snapshots/one", nil, &file); status != http.StatusOK || len(file.Actors) != 1 || file.Actors[0].ID != saved {
+		t.Fatalf("snapshot = %v %+v, want one actor %v", status, file, saved)
+	}
+
+	if status := ts.do("POST", "/api/v1/snapshots/one/restore", nil, nil); status != http.StatusNoContent {
+		t.Fatalf("restore status = %v", status)
+	}
+	if _, ok := ts.world.sim.GetActor(saved); !ok {
+		t.Errorf("saved actor %v is not restored", saved)
+	}
+	if _, ok := ts.world.sim.GetActor(added); ok {
+		t.Errorf("actor %v added after save is left", added)
+	}
+	current := meshsim.Snapshot{}
+	if status := ts.do("GET", "/api/v1/snapshot", nil, &current); status != http.StatusOK || len(current.Actors) != 1 {
+		t.Fatalf("current snapshot = %v %+v, want one actor", status, current)
+	}
+	peer := savedPeer{}
+	if err := json.Unmarshal(current.Actors[0].Peer, &peer); err != nil || peer.Type != jsPeerType || peer.Script == "" {
+		t.Errorf("restored peer = %s, want JS one with script", current.Actors[0].Peer)
+	}
+
+	if status := ts.do("DELETE", "/api/v1/snapshots/one", nil, nil); status != http.StatusNoContent {
+		t.Fatalf("delete status = %v", status)
+	}
+	if status := ts.do("GET", "/api/v1/snapshots", nil, &list); status != http.StatusOK || len(list.Snapshots) != 0 {
+		t.Errorf("list after delete = %v %+v, want none", status, list)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{"save bad name", "PUT", "/api/v1/snapshots/bad!name", nil, http.StatusBadRequest},
+		{"save hidden name", "PUT", "/api/v1/snapshots/.hidden", nil, http.StatusBadRequest},
+		{"get missing", "GET", "/api/v1/snapshots/missing", nil, http.StatusNotFound},
+		{"restore missing", "POST", "/api/v1/snapshots/missing/restore", nil, http.StatusNotFound},
+		{"delete missing", "DELETE", "/api/v1/snapshots/missing", nil, http.StatusNotFound},
+		{"put unknown version", "PUT", "/api/v1/snapshot", meshsim.Snapshot{Version: meshsim.SnapshotVersion + 1}, http.StatusBadRequest},
+		{"put not a snapshot", "PUT", "/api/v1/snapshot", []int{1}, http.StatusBadRequest},
+		{"put empty", "PUT", "/api/v1/snapshot", meshsim.Snapshot{Version: meshsim.SnapshotVersion}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do(tt.method, tt.path, tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+}
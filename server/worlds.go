@@ -0,0 +1,601 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mesh-simulator/meshlog"
+	"mesh-simulator/meshpeer"
+	"mesh-simulator/meshsim"
+)
+
+// defaultWorldName is the world served by unscoped routes, the binary gateway and gRPC calls
+// without world metadata
+const defaultWorldName = "default"
+
+// worldContextKey is gin context key of the world request is scoped to
+const worldContextKey = "world"
+
+var worldNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// worldSettings are the part of config each world has its own copy of. Worlds created over
+// the API get server config values for settings they omit
+type worldSettings struct {
+	JSMaxErrors       int
+	JSRemoveDisabled  bool
+	StorageQuota      int
+	RPCQueueSize      int
+	RPCOverflowPolicy string
+	RPCSessionGrace   int
+	// ExamplePeers is the number of ExampleScript peers the world starts with
+	ExamplePeers int
+	// ExampleScript is a server file path, so it comes from config only and is not exposed
+	ExampleScript string `json:"-"`
+	// limits of peer creation, zero means no limit
+	MaxActors        int
+	MaxActorsPerUser int
+	MaxScriptSize    int
+	MaxMetaSize      int
+	// Simulation is what the world starts with, info reports current values, which may have
+	// been changed at runtime
+	Simulation meshsim.Params
+}
+
+// world is a named simulation together with runtimes of peers the server runs in it
+type world struct {
+	name           string
+	settings       worldSettings
+	overflowPolicy meshpeer.RPCOverflowPolicy
+	created        time.Time
+	// logger adds world name and sim_time to records
+	logger *meshlog.Logger
+	sim    *meshsim.Simulator
+
+	npcListMtx sync.Mutex
+	npcList    map[meshpeer.NetworkID]interface{}
+
+	rpcSessions *rpcSessions
+	grpc        *grpcService
+	// gw is set in the default world only, the binary protocol has no means to choose a world
+	gw *gateway
+}
+
+func newWorld(name string, settings worldSettings, storageDir string, logger *meshlog.Logger) (*world, error) {
+	policy, err := meshpeer.ParseRPCOverflowPolicy(settings.RPCOverflowPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateParams(settings.Simulation); err != nil {
+		return nil, err
+	}
+	logger = logger.With(meshlog.KeyWorld, name)
+	w := &world{
+		name:           name,
+		settings:       settings,
+		overflowPolicy: policy,
+		created:        time.Now(),
+		sim:            meshsim.New(logger),
+		npcList:        map[meshpeer.NetworkID]interface{}{},
+	}
+	w.logger = logger.WithClock(w.sim.SimTime)
+	if storageDir != "" {
+		if err := os.MkdirAll(storageDir, 0777); err != nil {
+			w.logger.Error("Cannot create storage dir", "dir", storageDir, meshlog.KeyError, err)
+		}
+	}
+	if err := w.sim.SetParams(settings.Simulation); err != nil {
+		return nil, err
+	}
+	w.sim.SetStorage(storageDir, settings.StorageQuota)
+	w.rpcSessions = newRPCSessions(time.Duration(settings.RPCSessionGrace)*time.Second, w.logger, func(s *rpcSession) {
+		w.sim.RemoveActor(s.meshPeerID)
+	})
+	w.grpc = newGRPCService(w.sim, w.logger, w.createPeer, w.deletePeer, w.checkCapacity)
+	w.sim.SetRestartHandler(w.restartActor)
+	return w, nil
+}
+
+// jsConsoleLevels maps JS console functions to log levels
+var jsConsoleLevels = map[string]meshlog.Level{
+	"debug": meshlog.LevelDebug,
+	"log":   meshlog.LevelInfo,
+	"info":  meshlog.LevelInfo,
+	"warn":  meshlog.LevelWarn,
+	"error": meshlog.LevelError,
+}
+
+// peerLogger returns logger of peer code, the lines it prints are mostly failures
+func (w *world) peerLogger(id meshpeer.NetworkID) *log.Logger {
+	return w.logger.With(meshlog.KeyPeer, id).Std(meshlog.LevelWarn)
+}
+
+func (w *world) jsPeerOptions(id meshpeer.NetworkID) meshpeer.JSPeerOptions {
+	logger := w.logger.With(meshlog.KeyPeer, id)
+	opts := meshpeer.JSPeerOptions{
+		MaxErrors: w.settings.JSMaxErrors,
+		OnLog: func(e meshpeer.JSLogEntry) {
+			logger.Log(jsConsoleLevels[e.Level], e.Message, "source", "js_console")
+		},
+	}
+	if w.settings.JSRemoveDisabled {
+		opts.OnDisabled = func() {
+			// called from simulation tick, so removal has to be done asynchronously
+			go func() {
+				w.sim.RemoveActor(id)
+				w.npcListMtx.Lock()
+				delete(w.npcList, id)
+				w.npcListMtx.Unlock()
+			}()
+		}
+	}
+	return opts
+}
+
+// newPeer starts peer code of given type on top of the actor, empty type means JS script
+func (w *world) newPeer(peerType string, script string, config json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) (interface{}, error) {
+	if peerType == "" || peerType == jsPeerType {
+		jsPeer, err := meshpeer.NewJSPeer(script, w.peerLogger(meshAPI.GetMyID()), meshAPI, frontendAPI, w.jsPeerOptions(meshAPI.GetMyID()))
+		if err != nil {
+			return nil, err
+		}
+		return jsPeer, nil
+	}
+	peer, err := meshpeer.NewPeerOfType(peerType, meshAPI, w.peerLogger(meshAPI.GetMyID()), config)
+	if err != nil {
+		return nil, err
+	}
+	return &builtinPeer{peerType, config, peer}, nil
+}
+
+// restartPeer replaces peer runtime with a fresh one of the same type and code. npcListMtx must be held
+func (w *world) restartPeer(npc interface{}, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error {
+	id := meshAPI.GetMyID()
+	var restarted interface{}
+	var err error
+	switch p := npc.(type) {
+	case *meshpeer.JSPeer:
+		restarted, err = w.newPeer(jsPeerType, p.Script(), nil, meshAPI, frontendAPI)
+	case *builtinPeer:
+		restarted, err = w.newPeer(p.peerType, "", p.config, meshAPI, frontendAPI)
+	default:
+		return fmt.Errorf("peer cannot be restarted")
+	}
+	if err != nil {
+		w.sim.RemoveActor(id)
+		delete(w.npcList, id)
+		return err
+	}
+	w.npcList[id] = restarted
+	return nil
+}
+
+// restartActor is the simulator restart handler, it gives crashed actor a fresh runtime
+func (w *world) restartActor(id meshpeer.NetworkID, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) {
+	w.npcListMtx.Lock()
+	if npc, ok := w.npcList[id]; ok {
+		if err := w.restartPeer(npc, meshAPI, frontendAPI); err != nil {
+			w.logger.Warn("Cannot restart peer", meshlog.KeyPeer, id, meshlog.KeyError, err)
+		}
+	}
+	w.npcListMtx.Unlock()
+
+	w.rpcSessions.forEach(func(s *rpcSession) {
+		if s.meshPeerID == id {
+			s.meshPeer.Restart()
+		}
+	})
+	if w.gw != nil {
+		w.gw.restart(id)
+	}
+	w.grpc.restart(id)
+}
+
+// createPeer adds actor running peer of the given spec, requests breaking world limits are
+// rejected with requestError
+func (w *world) createPeer(spec peerSpec) (meshpeer.NetworkID, error) {
+	if err := w.validatePeer(spec); err != nil {
+		return "", err
+	}
+	meshAPI, frontendAPI := w.sim.AddActorWithOptions(spec.Coord, spec.Meta, meshsim.ActorOptions{Exact: spec.Exact, Owner: spec.Owner})
+	npc, err := w.newPeer(spec.Type, spec.Script, spec.Config, meshAPI, frontendAPI)
+	if err != nil {
+		w.sim.RemoveActor(meshAPI.GetMyID())
+		return "", err
+	}
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+	w.npcList[meshAPI.GetMyID()] = npc
+	return meshAPI.GetMyID(), nil
+}
+
+func (w *world) deletePeer(id meshpeer.NetworkID) error {
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	if _, ok := w.npcList[id]; !ok {
+		return fmt.Errorf("peer not found")
+	}
+	w.sim.RemoveActor(id)
+	delete(w.npcList, id)
+	return nil
+}
+
+// npc returns runtime of peer created by the server
+func (w *world) npc(id meshpeer.NetworkID) (interface{}, bool) {
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	npc, ok := w.npcList[id]
+	return npc, ok
+}
+
+// addExamplePeers creates count peers running ExampleScript at DefaultCoord
+func (w *world) addExamplePeers(count int) {
+	if count <= 0 {
+		return
+	}
+	jsCode, err := ioutil.ReadFile(w.settings.ExampleScript)
+	if err != nil {
+		w.logger.Error("Example js peer script is not found", meshlog.KeyError, err)
+		return
+	}
+	coord := w.sim.Params().DefaultCoord
+	for i := 0; i < count; i++ {
+		meta := map[string]interface{}{"color": "red", "label": strconv.Itoa(i)}
+		if _, err := w.createPeer(peerSpec{Coord: coord, Meta: meta, Script: string(jsCode)}); err != nil {
+			w.logger.Error("Cannot create example peer", meshlog.KeyError, err)
+		}
+	}
+}
+
+// takeSnapshot saves simulation together with runtimes of peers created by the server.
+// Remote peers cannot be brought back, so they are not saved
+func (w *world) takeSnapshot() meshsim.Snapshot {
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	return w.sim.Snapshot(func(id meshpeer.NetworkID) (json.RawMessage, bool) {
+		saved := savedPeer{}
+		var runtime interface{}
+		switch p := w.npcList[id].(type) {
+		case *meshpeer.JSPeer:
+			saved.Type, saved.Script, runtime = jsPeerType, p.Script(), p
+		case *builtinPeer:
+			saved.Type, saved.Config, runtime = p.peerType, p.config, p.peer
+		default:
+			return nil, false
+		}
+		if sp, ok := runtime.(meshpeer.StatefulPeer); ok {
+			if state, err := sp.SerializeState(); err != nil {
+				w.logger.Warn("Cannot save peer state", meshlog.KeyPeer, id, meshlog.KeyError, err)
+			} else {
+				saved.State = state
+			}
+		}
+		b, err := json.Marshal(saved)
+		if err != nil {
+			w.logger.Warn("Cannot save peer", meshlog.KeyPeer, id, meshlog.KeyError, err)
+			return nil, false
+		}
+		return b, true
+	})
+}
+
+func (w *world) restoreSnapshot(snap meshsim.Snapshot) error {
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	restored := map[meshpeer.NetworkID]interface{}{}
+	err := w.sim.Restore(snap, func(id meshpeer.NetworkID, peer json.RawMessage, meshAPI meshpeer.MeshAPI, frontendAPI meshpeer.FrontendAPI) error {
+		saved := savedPeer{}
+		if err := json.Unmarshal(peer, &saved); err != nil {
+			return fmt.Errorf("bad peer data: %v", err)
+		}
+		npc, err := w.newPeer(saved.Type, saved.Script, saved.Config, meshAPI, frontendAPI)
+		if err != nil {
+			return err
+		}
+		runtime := npc
+		if bp, ok := npc.(*builtinPeer); ok {
+			runtime = bp.peer
+		}
+		if sp, ok := runtime.(meshpeer.StatefulPeer); ok && len(saved.State) > 0 {
+			if err := sp.RestoreState(saved.State); err != nil {
+				w.logger.Warn("Cannot restore peer state", meshlog.KeyPeer, id, meshlog.KeyError, err)
+			}
+		}
+		restored[id] = npc
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	w.npcList = restored
+	return nil
+}
+
+// stop ends world simulation, its gateway stops listening, all its peers are removed and
+// remote ones disconnected, ws_rpc clients are told reason
+func (w *world) stop(reason string) {
+	if w.gw != nil {
+		w.gw.close()
+	}
+	w.rpcSessions.disconnect(reason)
+
+	w.npcListMtx.Lock()
+	defer w.npcListMtx.Unlock()
+
+	w.sim.Stop()
+	w.npcList = map[meshpeer.NetworkID]interface{}{}
+}
+
+// worldInfo describes world in /api/v1/worlds
+type worldInfo struct {
+	Name     string
+	Created  time.Time
+	Paused   bool
+	SimTime  float64
+	Actors   int
+	Settings worldSettings
+}
+
+func (w *world) info() worldInfo {
+	paused, simTime := w.sim.State()
+	settings := w.settings
+	settings.Simulation = w.sim.Params()
+	return worldInfo{w.name, w.created, paused, simTime, len(w.sim.GetOverview().Actors), settings}
+}
+
+// worldRegistry keeps named worlds of the server
+type worldRegistry struct {
+	conf   *config
+	logger *meshlog.Logger
+
+	mtx    sync.RWMutex
+	worlds map[string]*world
+	// creating holds names of worlds being populated, they are not served yet
+	creating map[string]struct{}
+}
+
+func newWorldRegistry(conf *config, logger *meshlog.Logger) *worldRegistry {
+	return &worldRegistry{
+		conf:     conf,
+		logger:   logger,
+		worlds:   map[string]*world{},
+		creating: map[string]struct{}{},
+	}
+}
+
+// defaultSettings returns world settings from server config
+func (wr *worldRegistry) defaultSettings() worldSettings {
+	return worldSettings{
+		JSMaxErrors:       wr.conf.JSMaxErrors,
+		JSRemoveDisabled:  wr.conf.JSRemoveDisabled,
+		StorageQuota:      wr.conf.StorageQuota,
+		RPCQueueSize:      wr.conf.RPCQueueSize,
+		RPCOverflowPolicy: wr.conf.RPCOverflowPolicy,
+		RPCSessionGrace:   wr.conf.RPCSessionGrace,
+		MaxActors:         wr.conf.MaxActors,
+		MaxActorsPerUser:  wr.conf.MaxActorsPerUser,
+		MaxScriptSize:     wr.conf.MaxScriptSize,
+		MaxMetaSize:       wr.conf.MaxMetaSize,
+		ExampleScript:     wr.conf.ExampleScript,
+		Simulation:        wr.conf.simParams(),
+	}
+}
+
+// storageDir returns directory peers storage of the world is kept in. The default world uses
+// StorageDir itself, so its data stays where it was before worlds were introduced
+func (wr *worldRegistry) storageDir(name string) string {
+	if wr.conf.StorageDir == "" || name == defaultWorldName {
+		return wr.conf.StorageDir
+	}
+	return filepath.Join(wr.conf.StorageDir, "worlds", name)
+}
+
+// create starts new world with given name
+func (wr *worldRegistry) create(name string, settings worldSettings) (*world, error) {
+	if !worldNameRe.MatchString(name) {
+		return nil, fmt.Errorf("bad world name %q", name)
+	}
+	wr.mtx.Lock()
+	_, exists := wr.worlds[name]
+	_, creating := wr.creating[name]
+	if exists || creating {
+		wr.mtx.Unlock()
+		return nil, fmt.Errorf("world %q already exists", name)
+	}
+	wr.creating[name] = struct{}{}
+	wr.mtx.Unlock()
+
+	// example peers read the script file and run JS, requests to other worlds must not wait for it
+	w, err := newWorld(name, settings, wr.storageDir(name), wr.logger)
+	if err == nil {
+		if name == defaultWorldName {
+			w.gw = newGateway(w.sim, time.Duration(wr.conf.UDPGatewayTimeout)*time.Second, w.logger, w.checkCapacity)
+		}
+		w.addExamplePeers(settings.ExamplePeers)
+		w.sim.Run()
+	}
+
+	wr.mtx.Lock()
+	defer wr.mtx.Unlock()
+
+	delete(wr.creating, name)
+	if err != nil {
+		return nil, err
+	}
+	wr.worlds[name] = w
+	wr.logger.Info("World created", meshlog.KeyWorld, name)
+	return w, nil
+}
+
+func (wr *worldRegistry) get(name string) *world {
+	wr.mtx.RLock()
+	defer wr.mtx.RUnlock()
+
+	return wr.worlds[name]
+}
+
+// list returns worlds sorted by name
+func (wr *worldRegistry) list() []*world {
+	wr.mtx.RLock()
+	defer wr.mtx.RUnlock()
+
+	ret := []*world{}
+	for _, w := range wr.worlds {
+		ret = append(ret, w)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].name < ret[j].name })
+	return ret
+}
+
+// remove stops world and forgets it, the default world cannot be removed
+func (wr *worldRegistry) remove(name string) error {
+	if name == defaultWorldName {
+		return fmt.Errorf("default world cannot be deleted")
+	}
+	wr.mtx.Lock()
+	w, ok := wr.worlds[name]
+	delete(wr.worlds, name)
+	wr.mtx.Unlock()
+
+	if !ok {
+		return fmt.Errorf("world not found")
+	}
+	w.stop("world deleted")
+	wr.logger.Info("World deleted", meshlog.KeyWorld, name)
+	return nil
+}
+
+// pauseAll freezes time in every world
+func (wr *worldRegistry) pauseAll() {
+	for _, w := range wr.list() {
+		w.sim.Pause()
+	}
+}
+
+// stopAll stops every world, ws_rpc clients are told reason
+func (wr *worldRegistry) stopAll(reason string) {
+	for _, w := range wr.list() {
+		w.stop(reason)
+	}
+}
+
+// writeOverview saves state overview of every world to file as {"<world>": overview}
+func (wr *worldRegistry) writeOverview(path string) error {
+	overviews := map[string]meshsim.Overview{}
+	for _, w := range wr.list() {
+		overviews[w.name] = w.sim.GetOverview()
+	}
+	b, err := json.MarshalIndent(overviews, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0666)
+}
+
+// useDefault scopes requests to the default world
+func (wr *worldRegistry) useDefault(c *gin.Context) {
+	c.Set(worldContextKey, wr.get(defaultWorldName))
+}
+
+// useNamed scopes requests to the world named in :world path parameter
+func (wr *worldRegistry) useNamed(c *gin.Context) {
+	w := wr.get(c.Param("world"))
+	if w == nil {
+		failRequest(c, http.StatusNotFound, apiV1NotFound, "world not found")
+		return
+	}
+	c.Set(worldContextKey, w)
+}
+
+// worldOf returns the world request is scoped to
+func worldOf(c *gin.Context) *world {
+	return c.MustGet(worldContextKey).(*world)
+}
+
+func (wr *worldRegistry) register(r *gin.Engine, au *authenticator) {
+	g := r.Group("/api/v1")
+	g.GET("/worlds", au.require(roleViewer), wr.listWorlds)
+	g.POST("/worlds", au.require(roleAdmin), wr.createWorld)
+	g.GET("/worlds/:name", au.require(roleViewer), wr.getWorld)
+	g.DELETE("/worlds/:name", au.require(roleAdmin), wr.deleteWorld)
+}
+
+func (wr *worldRegistry) listWorlds(c *gin.Context) {
+	ret := []worldInfo{}
+	for _, w := range wr.list() {
+		ret = append(ret, w.info())
+	}
+	c.JSON(http.StatusOK, gin.H{"Worlds": ret})
+}
+
+func (wr *worldRegistry) createWorld(c *gin.Context) {
+	type createRequest struct {
+		Name string
+		// Settings overrides server config values, omitted ones are kept
+		Settings json.RawMessage
+	}
+	req := &createRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	settings := wr.defaultSettings()
+	if len(req.Settings) > 0 {
+		if err := json.Unmarshal(req.Settings, &settings); err != nil {
+			apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, "bad settings: "+err.Error())
+			return
+		}
+	}
+	if !worldNameRe.MatchString(req.Name) {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, fmt.Sprintf("bad world name %q", req.Name))
+		return
+	}
+	if wr.get(req.Name) != nil {
+		apiV1Fail(c, http.StatusConflict, apiV1Conflict, "world already exists")
+		return
+	}
+	w, err := wr.create(req.Name, settings)
+	if err != nil {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, err.Error())
+		return
+	}
+	c.Header("Location", "/api/v1/worlds/"+w.name)
+	c.JSON(http.StatusCreated, w.info())
+}
+
+func (wr *worldRegistry) getWorld(c *gin.Context) {
+	w := wr.get(c.Param("name"))
+	if w == nil {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, "world not found")
+		return
+	}
+	c.JSON(http.StatusOK, w.info())
+}
+
+func (wr *worldRegistry) deleteWorld(c *gin.Context) {
+	name := c.Param("name")
+	if name == defaultWorldName {
+		apiV1Fail(c, http.StatusBadRequest, apiV1BadRequest, "default world cannot be deleted")
+		return
+	}
+	if err := wr.remove(name); err != nil {
+		apiV1Fail(c, http.StatusNotFound, apiV1NotFound, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
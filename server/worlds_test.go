@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"mesh-simulator/meshsim"
+)
+
+func TestWorldRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{"create", "POST", "/api/v1/worlds", map[string]interface{}{"Name": "alpha", "Settings": map[string]int{"MaxActors": 2}}, http.StatusCreated},
+		{"create existing", "POST", "/api/v1/worlds", map[string]interface{}{"Name": "alpha"}, http.StatusConflict},
+		{"create default", "POST", "/api/v1/worlds", map[string]interface{}{"Name": defaultWorldName}, http.StatusConflict},
+		{"create bad name", "POST", "/api/v1/worlds", map[string]interface{}{"Name": "a/b"}, http.StatusBadRequest},
+		{"create without name", "POST", "/api/v1/worlds", map[string]interface{}{}, http.StatusBadRequest},
+		{"create bad settings", "POST", "/api/v1/worlds", map[string]interface{}{"Name": "beta", "Settings": "fast"}, http.StatusBadRequest},
+		{"get", "GET", "/api/v1/worlds/alpha", nil, http.StatusOK},
+		{"get missing", "GET", "/api/v1/worlds/beta", nil, http.StatusNotFound},
+		{"delete default", "DELETE", "/api/v1/worlds/" + defaultWorldName, nil, http.StatusBadRequest},
+		{"delete missing", "DELETE", "/api/v1/worlds/beta", nil, http.StatusNotFound},
+		{"scoped route of missing world", "GET", "/worlds/beta/api/v1/actors", nil, http.StatusNotFound},
+		{"unknown route of world", "GET", "/worlds/alpha/api/v1/nothing", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do(tt.method, tt.path, tt.body, nil); status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+
+	info := worldInfo{}
+	ts.do("GET", "/api/v1/worlds/alpha", nil, &info)
+	// settings omitted on create come from server config
+	if want := ts.worlds.defaultSettings(); info.Settings.MaxActors != 2 || info.Settings.RPCQueueSize != want.RPCQueueSize {
+		t.Errorf("settings = %+v, want MaxActors 2 and the rest from config", info.Settings)
+	}
+	list := struct{ Worlds []worldInfo }{}
+	ts.do("GET", "/api/v1/worlds", nil, &list)
+	names := []string{}
+	for _, w := range list.Worlds {
+		names = append(names, w.Name)
+	}
+	if want := []string{"alpha", defaultWorldName}; !reflect.DeepEqual(names, want) {
+		t.Errorf("worlds = %v, want %v", names, want)
+	}
+}
+
+func TestWorldScopedRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	if status := ts.do("POST", "/api/v1/worlds", map[string]interface{}{"Name": "alpha"}, nil); status != http.StatusCreated {
+		t.Fatalf("create world status = %v", status)
+	}
+	created := meshsim.ActorInfo{}
+	if status := ts.do("POST", "/worlds/alpha/api/v1/actors", map[string]interface{}{}, &created); status != http.StatusCreated {
+		t.Fatalf("create actor status = %v", status)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/worlds/alpha/api/v1/actors/" + created.ID, http.StatusOK},
+		{"/api/v1/actors/" + created.ID, http.StatusNotFound},
+		{"/worlds/" + defaultWorldName + "/api/v1/actors/" + created.ID, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if status := ts.do("GET", tt.path, nil, nil); status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+		})
+	}
+
+	alpha := ts.worlds.get("alpha")
+	if status := ts.do("DELETE", "/api/v1/worlds/alpha", nil, nil); status != http.StatusNoContent {
+		t.Fatalf("delete world status = %v", status)
+	}
+	if status := ts.do("GET", "/worlds/alpha/api/v1/actors", nil, nil); status != http.StatusNotFound {
+		t.Errorf("actors of deleted world status = %v, want %v", status, http.StatusNotFound)
+	}
+	if _, err := alpha.sim.Step(1); err == nil {
+		t.Errorf("simulation of deleted world is not stopped")
+	}
+}
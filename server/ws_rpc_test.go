@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mesh-simulator/meshclient"
+	"mesh-simulator/meshpeer"
+)
+
+// TestRemotePeerRoundTrip runs meshclient against the server's /ws_rpc route
+func TestRemotePeerRoundTrip(t *testing.T) {
+	ts := newTestServer(t)
+	ts.world.sim.Pause()
+	c := meshclient.New(ts.http.URL)
+
+	coord := [2]float64{53.9, 27.55}
+	p1, err := c.ConnectPeer(meshclient.PeerOptions{Coord: &coord})
+	if err != nil {
+		t.Fatalf("ConnectPeer: %v", err)
+	}
+	defer p1.Close()
+	p2, err := c.ConnectPeer(meshclient.PeerOptions{Coord: &coord})
+	if err != nil {
+		t.Fatalf("ConnectPeer: %v", err)
+	}
+	defer p2.Close()
+
+	if p1.GetMyID() == "" || p1.GetMyID() == p2.GetMyID() {
+		t.Fatalf("bad peer IDs %q and %q", p1.GetMyID(), p2.GetMyID())
+	}
+	if p1.Session() == "" || p1.Session() == p2.Session() {
+		t.Errorf("bad sessions %q and %q", p1.Session(), p2.Session())
+	}
+	// getPosition is answered during ConnectPeer
+	if pos := p1.GetPosition(); pos != coord {
+		t.Errorf("position = %v, want %v", pos, coord)
+	}
+
+	var rpcErr *meshclient.RPCError
+	if err := p1.Call("noSuchMethod", nil, nil); !errors.As(err, &rpcErr) {
+		t.Errorf("unknown method error = %v, want RPCError", err)
+	}
+
+	appeared := make(chan meshpeer.NetworkID, 10)
+	received := make(chan string, 10)
+	p2.RegisterPeerAppearedHandler(func(id meshpeer.NetworkID) {
+		appeared <- id
+	})
+	p2.RegisterMessageHandler(func(id meshpeer.NetworkID, data meshpeer.NetworkMessage) {
+		received <- string(id) + ":" + string(data)
+	})
+
+	step := func() {
+		if _, err := ts.world.sim.Step(1); err != nil {
+			t.Fatalf("Step: %v", err)
+		}
+	}
+	deadline := time.After(5 * time.Second)
+	for found := false; !found; {
+		step()
+		select {
+		case id := <-appeared:
+			found = id == p1.GetMyID()
+		case <-deadline:
+			t.Fatal("p2 did not find p1")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// the second message is not valid UTF-8, it must arrive intact
+	for _, data := range []string{"hello", "\xff\xfe\x00bin\xc3"} {
+		p1.SendMessage(p2.GetMyID(), meshpeer.NetworkMessage(data))
+		for got := false; !got; {
+			step()
+			select {
+			case msg := <-received:
+				if want := string(p1.GetMyID()) + ":" + data; msg != want {
+					t.Fatalf("received %q, want %q", msg, want)
+				}
+				got = true
+			case <-deadline:
+				t.Fatalf("p2 did not receive message %q of p1", data)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+}